@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mereith/nav/config"
+	"github.com/mereith/nav/database"
+)
+
+// 执行命令行子命令，没有子命令时返回 false 继续启动服务
+func runCommand(cfg *config.Config, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "config":
+		runConfigCommand(cfg, args[1:])
+	case "migrate":
+		runMigrateCommand(args[1:])
+	case "backup":
+		runBackupCommand(args[1:])
+	case "migrate-data":
+		runMigrateDataCommand(cfg, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n", args[0])
+		os.Exit(2)
+	}
+	return true
+}
+
+// nav config print：输出合并配置文件、环境变量和命令行参数之后生效的配置
+func runConfigCommand(cfg *config.Config, args []string) {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "用法: nav config print")
+		os.Exit(2)
+	}
+	printed, err := cfg.Print()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "输出配置失败: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(printed)
+}
+
+// nav migrate status：查看数据库结构迁移的执行情况
+func runMigrateCommand(args []string) {
+	if len(args) != 1 || args[0] != "status" {
+		fmt.Fprintln(os.Stderr, "用法: nav migrate status")
+		os.Exit(2)
+	}
+	database.OpenDB()
+	statuses, err := database.GetMigrationStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取迁移记录失败: %s\n", err)
+		os.Exit(1)
+	}
+	current, err := database.SchemaVersion()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取结构版本失败: %s\n", err)
+		os.Exit(1)
+	}
+	latest := database.LatestSchemaVersion()
+	fmt.Printf("当前结构版本: %d，程序支持的最新版本: %d\n\n", current, latest)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state := "pending"
+		appliedAt := "-"
+		if status.AppliedAt > 0 {
+			state = "applied"
+			appliedAt = time.Unix(status.AppliedAt, 0).Format("2006-01-02 15:04:05")
+		}
+		if status.Version > latest {
+			state = "unknown"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	w.Flush()
+}
+
+// nav backup <file>：生成数据库快照，服务运行时也可以安全执行
+func runBackupCommand(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "用法: nav backup <file>")
+		os.Exit(2)
+	}
+	if _, err := os.Stat(args[0]); err == nil {
+		fmt.Fprintf(os.Stderr, "%s 已存在\n", args[0])
+		os.Exit(1)
+	}
+	database.OpenDB()
+	if err := database.Snapshot(args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "备份失败: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已备份到 %s\n", args[0])
+}
+
+// nav migrate-data <nav.db>：把已有的 SQLite 数据库复制到配置的 PostgreSQL 数据库
+func runMigrateDataCommand(cfg *config.Config, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "用法: nav -database postgres://... migrate-data <nav.db>")
+		os.Exit(2)
+	}
+	if database.DialectOf(cfg.Database) != database.Postgres {
+		fmt.Fprintln(os.Stderr, "请通过 -database 或 NAV_DATABASE 指定目标 PostgreSQL 连接串")
+		os.Exit(2)
+	}
+	if _, err := os.Stat(args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "读取源数据库失败: %s\n", err)
+		os.Exit(1)
+	}
+	// 两边都先升级到当前程序的最新结构，源库升级前照常自动备份；先连目标库，连不上时不改动源库
+	dst, dstVersion := openAndMigrate(cfg.DataDir, cfg.Database)
+	src, srcVersion := openAndMigrate(cfg.DataDir, args[0])
+	if srcVersion != dstVersion {
+		fmt.Fprintf(os.Stderr, "源数据库结构版本 %d 与目标数据库 %d 不一致\n", srcVersion, dstVersion)
+		os.Exit(1)
+	}
+	copied, err := database.CopyData(src, dst)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "复制数据失败: %s\n", err)
+		os.Exit(1)
+	}
+	tables := make([]string, 0, len(copied))
+	for table := range copied {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tROWS")
+	for _, table := range tables {
+		fmt.Fprintf(w, "%s\t%d\n", table, copied[table])
+	}
+	w.Flush()
+	fmt.Println("\n数据复制完成")
+}
+
+func openAndMigrate(dataDir string, dsn string) (*database.Handle, int) {
+	database.Configure(dataDir, dsn)
+	db, err := database.Open(dsn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打开数据库失败: %s\n", err)
+		os.Exit(1)
+	}
+	database.DB = db
+	if err = database.Migrate(); err != nil {
+		fmt.Fprintf(os.Stderr, "升级数据库结构失败: %s\n", err)
+		os.Exit(1)
+	}
+	version, err := database.SchemaVersion()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取结构版本失败: %s\n", err)
+		os.Exit(1)
+	}
+	return database.DB, version
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/utils"
+)
+
+// 备份和恢复互斥，恢复期间不允许生成快照
+var backupMutex sync.Mutex
+
+// 恢复时要关闭并替换 DB。请求和后台任务在使用数据库期间持有读锁，替换时持有写锁，
+// 等进行中的请求结束后再关闭连接，替换完成前新的请求会等待
+var swapMutex sync.RWMutex
+
+// 使用数据库前获取读锁，返回的函数用于释放。同一个 goroutine 中不能重复获取，否则会与等待中的恢复死锁
+func Acquire() (release func()) {
+	swapMutex.RLock()
+	return swapMutex.RUnlock
+}
+
+// 获取写锁，独占数据库直到释放，用于替换 DB 及重新加载依赖它的状态
+func Exclusive() (release func()) {
+	swapMutex.Lock()
+	return swapMutex.Unlock
+}
+
+var errPostgresBackup = errors.New("PostgreSQL 请使用 pg_dump / pg_restore 备份和恢复")
+
+// 使用 VACUUM INTO 在线生成一致的数据库快照，不影响正在运行的 WAL 数据库。目标文件不能已经存在
+func Snapshot(path string) error {
+	if DB.Dialect == Postgres {
+		return errPostgresBackup
+	}
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+	utils.PathExistsOrCreate(filepath.Dir(path))
+	_, err := DB.Exec(`VACUUM INTO ?;`, path)
+	return err
+}
+
+// 在数据目录中生成一个临时快照，返回文件路径，调用方负责删除
+func TempSnapshot() (string, error) {
+	dir, err := os.MkdirTemp(dataDir, ".snapshot-")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "nav.db")
+	if err = Snapshot(path); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return path, nil
+}
+
+// 在数据库所在目录创建用于接收上传备份的临时文件，保证恢复时可以原子地重命名
+func CreateRestoreFile() (*os.File, error) {
+	return os.CreateTemp(filepath.Dir(dbPath()), ".restore-*.db")
+}
+
+// 校验一个 SQLite 备份文件：完整性检查通过、包含 nav 的表，且结构版本不高于当前程序，返回其结构版本
+func ValidateBackup(path string) (int, error) {
+	db, err := sql.Open("sqlite", path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	var result string
+	if err = db.QueryRow(`PRAGMA integrity_check;`).Scan(&result); err != nil {
+		return 0, fmt.Errorf("不是有效的数据库文件: %w", err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("数据库完整性检查失败: %s", result)
+	}
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('nav_user', 'nav_table', 'nav_setting');`).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count < 3 {
+		return 0, errors.New("不是 nav 的数据库文件")
+	}
+	// 引入版本化迁移之前的数据库没有 schema_migrations，按 0 处理
+	var version sql.NullInt64
+	err = db.QueryRow(`SELECT MAX(version) FROM schema_migrations;`).Scan(&version)
+	if err != nil && !strings.Contains(err.Error(), "no such table") {
+		return 0, err
+	}
+	latest := migrations[len(migrations)-1].version
+	if int(version.Int64) > latest {
+		return 0, fmt.Errorf("备份的结构版本 %d 高于当前程序支持的版本 %d", version.Int64, latest)
+	}
+	return int(version.Int64), nil
+}
+
+// backups 目录中一个尚不存在的文件路径，VACUUM INTO 不能覆盖已有文件，同一秒内多次备份时加上序号
+func backupPath(prefix string) string {
+	dir := filepath.Join(dataDir, "backups")
+	utils.PathExistsOrCreate(dir)
+	name := prefix + time.Now().Format("20060102150405")
+	path := filepath.Join(dir, name+".db")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.db", name, i))
+	}
+}
+
+// 用校验过的备份文件替换当前数据库：先把当前数据库备份到 backups 目录，关闭连接后原子地重命名替换，
+// 再重新打开并执行迁移。调用方需要持有 Exclusive，保证替换期间没有其他请求使用 DB
+func Restore(path string) error {
+	if DB.Dialect == Postgres {
+		return errPostgresBackup
+	}
+	if _, err := ValidateBackup(path); err != nil {
+		return err
+	}
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	current := backupPath("nav-before-restore-")
+	if _, err := DB.Exec(`VACUUM INTO ?;`, current); err != nil {
+		return fmt.Errorf("备份当前数据库失败: %w", err)
+	}
+	logger.LogInfo("恢复前已备份当前数据库到 %s", current)
+
+	// 关闭最后一个连接时 SQLite 会合并并删除 WAL，避免旧的 WAL 被应用到新文件上
+	if err := DB.Close(); err != nil {
+		return err
+	}
+	target := dbPath()
+	err := os.Rename(path, target)
+	if err == nil {
+		os.Remove(target + "-wal")
+		os.Remove(target + "-shm")
+	}
+	db, openErr := Open(dbFile)
+	if openErr != nil {
+		return openErr
+	}
+	DB = db
+	if err != nil {
+		return fmt.Errorf("替换数据库文件失败: %w", err)
+	}
+	return Migrate()
+}
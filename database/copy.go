@@ -0,0 +1,163 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 不需要复制的表：迁移记录由目标库自己的迁移生成
+var skipCopyTables = []string{"schema_migrations", "sqlite_sequence"}
+
+// 把 SQLite 数据库中的全部业务数据复制到 PostgreSQL，两边的结构版本需要一致。
+// 只复制两边都存在的表和列，目标库中已有数据时拒绝执行，整个复制在一个事务中完成
+func CopyData(src *Handle, dst *Handle) (map[string]int, error) {
+	if src.Dialect != SQLite || dst.Dialect != Postgres {
+		return nil, fmt.Errorf("只支持从 SQLite 复制到 PostgreSQL")
+	}
+	tables, err := sqliteTables(src)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := dst.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	copied := make(map[string]int)
+	for _, table := range tables {
+		columns, serial, err := postgresColumns(tx, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		var count int
+		if err = tx.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s;`, table)).Scan(&count); err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("目标数据库的 %s 表已有数据，请使用空数据库", table)
+		}
+		n, err := copyTable(src, tx, table, columns)
+		if err != nil {
+			return nil, fmt.Errorf("复制 %s 失败: %w", table, err)
+		}
+		copied[table] = n
+		if serial {
+			if err = tx.SyncSequence(table); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return copied, tx.Commit()
+}
+
+func sqliteTables(src *Handle) ([]string, error) {
+	rows, err := src.Query(`SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		skip := strings.HasPrefix(name, "sqlite_")
+		for _, table := range skipCopyTables {
+			skip = skip || name == table
+		}
+		if !skip {
+			tables = append(tables, name)
+		}
+	}
+	return tables, rows.Err()
+}
+
+// 返回目标表的列名（小写）以及 id 是否为自增列，表不存在时返回空
+func postgresColumns(tx *Tx, table string) (map[string]bool, bool, error) {
+	rows, err := tx.Query(`
+		SELECT column_name, COALESCE(column_default, '')
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ?;
+		`, table)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	columns := make(map[string]bool)
+	serial := false
+	for rows.Next() {
+		var name, def string
+		if err = rows.Scan(&name, &def); err != nil {
+			return nil, false, err
+		}
+		columns[name] = true
+		if name == "id" && strings.HasPrefix(def, "nextval(") {
+			serial = true
+		}
+	}
+	return columns, serial, rows.Err()
+}
+
+// SQLite 中驼峰列名在 PostgreSQL 中未加引号创建，实际为小写
+func copyTable(src *Handle, tx *Tx, table string, targetColumns map[string]bool) (int, error) {
+	rows, err := src.Query(fmt.Sprintf(`SELECT name FROM pragma_table_info('%s');`, table))
+	if err != nil {
+		return 0, err
+	}
+	var srcColumns, dstColumns []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if targetColumns[strings.ToLower(name)] {
+			srcColumns = append(srcColumns, `"`+name+`"`)
+			dstColumns = append(dstColumns, `"`+strings.ToLower(name)+`"`)
+		}
+	}
+	rows.Close()
+	if len(srcColumns) == 0 {
+		return 0, nil
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s);`,
+		table, strings.Join(dstColumns, ","), strings.TrimSuffix(strings.Repeat("?,", len(dstColumns)), ",")))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	rows, err = src.Query(fmt.Sprintf(`SELECT %s FROM %s;`, strings.Join(srcColumns, ","), table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	count := 0
+	values := make([]interface{}, len(srcColumns))
+	pointers := make([]interface{}, len(srcColumns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			return count, err
+		}
+		// pq 会把 []byte 按 bytea 编码，写入 TEXT 列前转成字符串
+		for i, value := range values {
+			if b, ok := value.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if _, err = stmt.Exec(values...); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 数据库类型
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// 根据 dsn 判断数据库类型，postgres:// 或 postgresql:// 开头的使用 PostgreSQL，其余视为 SQLite 文件
+func DialectOf(dsn string) Dialect {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return Postgres
+	}
+	return SQLite
+}
+
+// 把 ? 占位符改写为当前数据库的写法，PostgreSQL 使用 $1、$2，字符串和带引号的标识符中的 ? 保持不变
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// 数据库连接。SQL 统一使用 ? 作为占位符，执行前按数据库类型改写
+type Handle struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+func (h *Handle) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return h.DB.Exec(h.Dialect.Rebind(query), args...)
+}
+
+func (h *Handle) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return h.DB.Query(h.Dialect.Rebind(query), args...)
+}
+
+func (h *Handle) QueryRow(query string, args ...interface{}) *sql.Row {
+	return h.DB.QueryRow(h.Dialect.Rebind(query), args...)
+}
+
+func (h *Handle) Prepare(query string) (*sql.Stmt, error) {
+	return h.DB.Prepare(h.Dialect.Rebind(query))
+}
+
+func (h *Handle) Begin() (*Tx, error) {
+	tx, err := h.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, Dialect: h.Dialect}, nil
+}
+
+// 执行 INSERT 并返回自增 id
+func (h *Handle) InsertId(query string, args ...interface{}) (int64, error) {
+	return insertId(h, h.Dialect, query, args...)
+}
+
+// 事务，占位符的处理与 Handle 相同
+type Tx struct {
+	*sql.Tx
+	Dialect Dialect
+}
+
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.Dialect.Rebind(query), args...)
+}
+
+func (tx *Tx) Prepare(query string) (*sql.Stmt, error) {
+	return tx.Tx.Prepare(tx.Dialect.Rebind(query))
+}
+
+func (tx *Tx) InsertId(query string, args ...interface{}) (int64, error) {
+	return insertId(tx, tx.Dialect, query, args...)
+}
+
+// 显式写入 id 之后（导入、迁移数据）同步自增序列，避免之后新增的记录 id 冲突。SQLite 不需要
+func (tx *Tx) SyncSequence(table string) error {
+	if tx.Dialect != Postgres {
+		return nil
+	}
+	_, err := tx.Tx.Exec(fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL) FROM %s;`, table, table))
+	return err
+}
+
+type execQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// PostgreSQL 驱动不支持 LastInsertId，改用 RETURNING id
+func insertId(q execQuerier, dialect Dialect, query string, args ...interface{}) (int64, error) {
+	if dialect == Postgres {
+		var id int64
+		query = strings.TrimRight(strings.TrimSpace(query), ";")
+		err := q.QueryRow(query+" RETURNING id;", args...).Scan(&id)
+		return id, err
+	}
+	res, err := q.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
@@ -3,162 +3,84 @@ package database
 import (
 	"database/sql"
 	"path/filepath"
+	"strings"
 
+	_ "github.com/lib/pq"
 	_ "modernc.org/sqlite"
 
 	"github.com/mereith/nav/logger"
 	"github.com/mereith/nav/utils"
 )
 
-var DB *sql.DB
+var DB *Handle
 
-func columnExists(tableName string, columnName string) bool {
-	query := `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name=?`
-	var count int
-	err := DB.QueryRow(query, tableName, columnName).Scan(&count)
-	if err != nil {
-		return false
-	}
-	return count > 0
-}
+// 数据目录，保存数据库和备份
+var dataDir = "./data"
 
-func InitDB() {
-	var err error
-	utils.PathExistsOrCreate("./data")
-	// 创建数据库
-	dir := "./data"
-	dbPath := filepath.Join(dir, "nav.db")
-	// 添加连接参数
-	dbPath = dbPath + "?_journal=WAL&_timeout=5000&_busy_timeout=5000&_txlock=immediate"
-	DB, err = sql.Open("sqlite", dbPath)
-	utils.CheckErr(err)
-	// user 表
-	sql_create_table := `
-		CREATE TABLE IF NOT EXISTS nav_user (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT,
-			password TEXT
-		);
-		`
-	_, err = DB.Exec(sql_create_table)
-	utils.CheckErr(err)
-	// setting 表
-	sql_create_table = `
-	CREATE TABLE IF NOT EXISTS nav_setting (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		favicon TEXT,
-		title TEXT,
-		govRecord TEXT,
-		logo192 TEXT,
-		logo512 TEXT,
-		hideAdmin BOOLEAN,
-		hideGithub BOOLEAN,
-		jumpTargetBlank BOOLEAN
-	);
-	`
-	_, err = DB.Exec(sql_create_table)
-	utils.CheckErr(err)
-	// 检查并添加列
-	if !columnExists("nav_setting", "logo192") {
-		DB.Exec(`ALTER TABLE nav_setting ADD COLUMN logo192 TEXT;`)
-	}
-	if !columnExists("nav_setting", "logo512") {
-		DB.Exec(`ALTER TABLE nav_setting ADD COLUMN logo512 TEXT;`)
-	}
-	if !columnExists("nav_setting", "govRecord") {
-		DB.Exec(`ALTER TABLE nav_setting ADD COLUMN govRecord TEXT;`)
-	}
-	if !columnExists("nav_setting", "jumpTargetBlank") {
-		DB.Exec(`ALTER TABLE nav_setting ADD COLUMN jumpTargetBlank BOOLEAN;`)
-	}
-	// 设置表表结构升级-20230628
-	if !columnExists("nav_setting", "hideAdmin") {
-		DB.Exec(`ALTER TABLE nav_setting ADD COLUMN hideAdmin BOOLEAN;`)
-	}
-	// 设置表表结构升级-20230627
-	if !columnExists("nav_setting", "hideGithub") {
-		DB.Exec(`ALTER TABLE nav_setting ADD COLUMN hideGithub BOOLEAN;`)
-	}
+// 数据库文件路径，可以带上 ?key=value 形式的连接参数；也可以是 postgres:// 开头的 PostgreSQL 连接串
+var dbFile = filepath.Join(dataDir, "nav.db")
 
-	// 设置表表结构升级-20240122-CustomCode
-	if !columnExists("nav_setting", "customJS") {
-		DB.Exec(`ALTER TABLE nav_setting ADD COLUMN customJS TEXT;`)
-	}
-	if !columnExists("nav_setting", "customCSS") {
-		DB.Exec(`ALTER TABLE nav_setting ADD COLUMN customCSS TEXT;`)
-	}
+// 默认的连接参数，dsn 中已经带有参数时不再添加
+const defaultDSNParams = "?_journal=WAL&_timeout=5000&_busy_timeout=5000&_txlock=immediate"
 
-	// 设置表表结构升级-202401xx-GuestPassword
-	if !columnExists("nav_setting", "guestPassword") {
-		DB.Exec(`ALTER TABLE nav_setting ADD COLUMN guestPassword TEXT;`)
-	}
+// 指定数据目录和数据库，需要在 OpenDB 之前调用
+func Configure(dir string, dsn string) {
+	dataDir = dir
+	dbFile = dsn
+}
 
-	// 默认 tools 用的 表
-	sql_create_table = `
-		CREATE TABLE IF NOT EXISTS nav_table (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT,
-			url TEXT,
-			logo TEXT,
-			catelog TEXT,
-			desc TEXT
-		);
-		`
-	_, err = DB.Exec(sql_create_table)
-	utils.CheckErr(err)
+func DataDir() string {
+	return dataDir
+}
 
-	// tools数据表结构升级-20230327
-	if !columnExists("nav_table", "sort") {
-		DB.Exec(`ALTER TABLE nav_table ADD COLUMN sort INTEGER;`)
-	}
+// 去掉连接参数后的数据库文件路径
+func dbPath() string {
+	path, _, _ := strings.Cut(dbFile, "?")
+	return path
+}
 
-	// tools数据表结构升级-20230627
-	if !columnExists("nav_table", "hide") {
-		DB.Exec(`ALTER TABLE nav_table ADD COLUMN hide BOOLEAN;`)
+// 按 dsn 的类型打开数据库
+func Open(dsn string) (*Handle, error) {
+	dialect := DialectOf(dsn)
+	if dialect == Postgres {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return nil, err
+		}
+		return &Handle{DB: db, Dialect: dialect}, db.Ping()
+	}
+	path, _, _ := strings.Cut(dsn, "?")
+	utils.PathExistsOrCreate(filepath.Dir(path))
+	if !strings.Contains(dsn, "?") {
+		dsn += defaultDSNParams
+	}
+	// 外键约束需要在每个连接上单独开启
+	if !strings.Contains(dsn, "foreign_keys") {
+		dsn += "&_pragma=foreign_keys(1)"
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
 	}
+	return &Handle{DB: db, Dialect: dialect}, nil
+}
 
-	// 分类表
-	sql_create_table = `
-		CREATE TABLE IF NOT EXISTS nav_catelog (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT
-		);
-			`
-	_, err = DB.Exec(sql_create_table)
-	utils.CheckErr(err)
-
-	// 分类表表结构升级-20230327
-	if !columnExists("nav_catelog", "sort") {
-		DB.Exec(`ALTER TABLE nav_catelog ADD COLUMN sort INTEGER NOT NULL DEFAULT 0;`)
+// 打开数据库连接，不做任何结构变更
+func OpenDB() {
+	var err error
+	utils.PathExistsOrCreate(dataDir)
+	DB, err = Open(dbFile)
+	if err != nil {
+		panic(err)
 	}
+}
 
-	// 分类表表结构升级-20241219-【隐藏分类】
-	if !columnExists("nav_catelog", "hide") {
-		DB.Exec(`ALTER TABLE nav_catelog ADD COLUMN hide BOOLEAN;`)
+func InitDB() {
+	OpenDB()
+	// 结构变更全部通过版本化迁移完成，失败时拒绝启动，避免在不完整的结构上运行
+	if err := Migrate(); err != nil {
+		panic(err)
 	}
-	migration_2024_12_13() // 只涉及 nav_catelog 表，所以可以放在这里
-
-	// api token 表
-	sql_create_table = `
-		CREATE TABLE IF NOT EXISTS nav_api_token (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT,
-			value TEXT,
-			disabled INTEGER
-		);
-		`
-	_, err = DB.Exec(sql_create_table)
-	utils.CheckErr(err)
-	// img 表
-	sql_create_table = `
-		CREATE TABLE IF NOT EXISTS nav_img (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			url TEXT,
-			value TEXT
-		);
-		`
-	_, err = DB.Exec(sql_create_table)
-	utils.CheckErr(err)
 	// 如果不存在，就初始化用户
 	sql_get_user := `
 		SELECT * FROM nav_user;
@@ -166,16 +88,15 @@ func InitDB() {
 	rows, err := DB.Query(sql_get_user)
 	utils.CheckErr(err)
 	if !rows.Next() {
-		sql_add_user := `
-			INSERT INTO nav_user (id, name, password)
-			VALUES (?, ?, ?);
-			`
-		stmt, err := DB.Prepare(sql_add_user)
+		rows.Close()
+		hash, err := utils.HashPassword("admin")
 		utils.CheckErr(err)
-		res, err := stmt.Exec(utils.GenerateId(), "admin", "admin")
-		utils.CheckErr(err)
-		_, err = res.LastInsertId()
+		tx, err := DB.Begin()
 		utils.CheckErr(err)
+		_, err = tx.Exec(`INSERT INTO nav_user (id, name, password, role) VALUES (?, ?, ?, 'owner');`, utils.GenerateId(), "admin", hash)
+		utils.CheckTxErr(err, tx.Tx)
+		utils.CheckTxErr(tx.SyncSequence("nav_user"), tx.Tx)
+		utils.CheckErr(tx.Commit())
 	}
 	rows.Close()
 	// 如果不存在设置，就初始化
@@ -191,9 +112,7 @@ func InitDB() {
 			`
 		stmt, err := DB.Prepare(sql_add_setting)
 		utils.CheckErr(err)
-		res, err := stmt.Exec("favicon.ico", "Van Nav", "", "logo192.png", "logo512.png", false, false, true)
-		utils.CheckErr(err)
-		_, err = res.LastInsertId()
+		_, err = stmt.Exec("favicon.ico", "Van Nav", "", "logo192.png", "logo512.png", false, false, true)
 		utils.CheckErr(err)
 	}
 	rows.Close()
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mereith/nav/logger"
+)
+
+// 一次结构迁移，version 只能递增，已发布的迁移不能修改
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// 迁移状态，AppliedAt 为 0 表示尚未执行
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt int64
+}
+
+// 当前数据库类型使用的迁移列表。PostgreSQL 从当前结构的基线开始，之后的迁移两边的版本号保持一致
+func schemaMigrations() []migration {
+	if DB != nil && DB.Dialect == Postgres {
+		return postgresMigrations
+	}
+	return migrations
+}
+
+// 当前程序支持的最新结构版本
+func LatestSchemaVersion() int {
+	list := schemaMigrations()
+	return list[len(list)-1].version
+}
+
+func ensureMigrationTable() error {
+	_, err := DB.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at BIGINT NOT NULL
+		);
+		`)
+	return err
+}
+
+// 已执行的迁移，version -> 执行时间
+func appliedMigrations() (map[int]int64, error) {
+	applied := make(map[int]int64)
+	rows, err := DB.Query(`SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// 数据库当前的结构版本，即已执行的最大迁移版本
+func SchemaVersion() (int, error) {
+	if err := ensureMigrationTable(); err != nil {
+		return 0, err
+	}
+	var version sql.NullInt64
+	err := DB.QueryRow(`SELECT MAX(version) FROM schema_migrations;`).Scan(&version)
+	return int(version.Int64), err
+}
+
+// 列出所有迁移的执行情况，包括数据库中存在但当前程序不认识的版本
+func GetMigrationStatus() ([]MigrationStatus, error) {
+	if err := ensureMigrationTable(); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	list := schemaMigrations()
+	result := make([]MigrationStatus, 0, len(list))
+	for _, m := range list {
+		result = append(result, MigrationStatus{Version: m.version, Name: m.name, AppliedAt: applied[m.version]})
+	}
+	latest := LatestSchemaVersion()
+	rows, err := DB.Query(`SELECT version, name, applied_at FROM schema_migrations WHERE version > ? ORDER BY version;`, latest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status MigrationStatus
+		if err = rows.Scan(&status.Version, &status.Name, &status.AppliedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, status)
+	}
+	return result, rows.Err()
+}
+
+// 数据库中还没有任何业务表，说明是全新安装
+func isEmptyDB() (bool, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name LIKE 'nav_%';`
+	if DB.Dialect == Postgres {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name LIKE 'nav_%';`
+	}
+	var count int
+	err := DB.QueryRow(query).Scan(&count)
+	return count == 0, err
+}
+
+// 执行迁移前备份数据库，返回备份文件路径
+func backupBeforeMigrate(version int) (string, error) {
+	path := backupPath(fmt.Sprintf("nav-v%d-", version))
+	_, err := DB.Exec(`VACUUM INTO ?;`, path)
+	return path, err
+}
+
+// 依次执行尚未执行的迁移，每个迁移在单独的事务中完成
+func Migrate() error {
+	empty, err := isEmptyDB()
+	if err != nil {
+		return err
+	}
+	if err = ensureMigrationTable(); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return err
+	}
+	current, err := SchemaVersion()
+	if err != nil {
+		return err
+	}
+	latest := LatestSchemaVersion()
+	if current > latest {
+		return fmt.Errorf("数据库结构版本 %d 高于当前程序支持的版本 %d，请使用更新版本的程序", current, latest)
+	}
+	pending := make([]migration, 0)
+	for _, m := range schemaMigrations() {
+		if _, ok := applied[m.version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if !empty && DB.Dialect == Postgres {
+		logger.LogInfo("数据库结构版本 %d -> %d，PostgreSQL 不会自动备份，请提前使用 pg_dump 备份", current, latest)
+	} else if !empty {
+		path, err := backupBeforeMigrate(current)
+		if err != nil {
+			return fmt.Errorf("迁移前备份数据库失败: %w", err)
+		}
+		logger.LogInfo("数据库结构版本 %d -> %d，已备份到 %s", current, latest, path)
+	}
+	for _, m := range pending {
+		if err = runMigration(m); err != nil {
+			return fmt.Errorf("执行迁移 %d_%s 失败: %w", m.version, m.name, err)
+		}
+		logger.LogInfo("已执行迁移 %d_%s", m.version, m.name)
+	}
+	return nil
+}
+
+func runMigration(m migration) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = m.up(tx.Tx); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?);`, m.version, m.name, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func hasColumn(tx *sql.Tx, table string, column string) (bool, error) {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?;`, table, column).Scan(&count)
+	return count > 0, err
+}
+
+// 列不存在时添加，已存在时跳过，保证迁移在老数据库上可以重复执行
+func addColumn(tx *sql.Tx, table string, column string, definition string) error {
+	exists, err := hasColumn(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s;`, table, column, definition))
+	return err
+}
+
+// 依次执行多条语句
+func execAll(tx *sql.Tx, statements ...string) error {
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,57 +1,705 @@
 package database
 
-func migration_2024_12_13() {
-	// 1. 首先更新现有的 NULL 值为 0
-	sql_update_null_sort := `
-        UPDATE nav_catelog 
-        SET sort = 0 
-        WHERE sort IS NULL;
-    `
+import (
+	"database/sql"
+	"strings"
 
-	_, err := DB.Exec(sql_update_null_sort)
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+// 所有结构迁移，按版本号顺序执行。每个迁移都要能在已经手动升级过的老数据库上重复执行
+var migrations = []migration{
+	{1, "init", migration_init},
+	{2, "setting_columns", migration_setting_columns},
+	{3, "tool_sort_hide", migration_tool_sort_hide},
+	{4, "catelog_sort_hide", migration_catelog_sort_hide},
+	{5, "catelog_sort_not_null", migration_catelog_sort_not_null},
+	{6, "user_role", migration_user_role},
+	{7, "api_token_scopes", migration_api_token_scopes},
+	{8, "secret_session", migration_secret_session},
+	{9, "hash_user_passwords", migration_hash_user_passwords},
+	{10, "hash_api_tokens", migration_hash_api_tokens},
+	{11, "login_attempt", migration_login_attempt},
+	{12, "totp", migration_totp},
+	{13, "user_external_identity", migration_user_external_identity},
+	{14, "hash_guest_password", migration_hash_guest_password},
+	{15, "guest_link", migration_guest_link},
+	{16, "audit", migration_audit},
+	{17, "state", migration_state},
+	{18, "trash", migration_trash},
+	{19, "tool_revision", migration_tool_revision},
+	{20, "tool_catelog_id", migration_tool_catelog_id},
+	{21, "catelog_parent", migration_catelog_parent},
+	{22, "tag", migration_tag},
+	{23, "tool_fts", migration_tool_fts},
+	{24, "tool_pinyin", migration_tool_pinyin},
+}
+
+// 最初版本的表结构
+func migration_init(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_user (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT,
+			password TEXT
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_setting (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			favicon TEXT,
+			title TEXT,
+			govRecord TEXT,
+			logo192 TEXT,
+			logo512 TEXT,
+			hideAdmin BOOLEAN,
+			hideGithub BOOLEAN,
+			jumpTargetBlank BOOLEAN
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_table (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT,
+			url TEXT,
+			logo TEXT,
+			catelog TEXT,
+			desc TEXT
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_catelog (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_api_token (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT,
+			value TEXT,
+			disabled INTEGER
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_img (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url TEXT,
+			value TEXT
+		);
+		`)
+}
+
+// 设置表陆续增加的列：备案号、PWA 图标、跳转方式、隐藏入口、自定义代码、访客密码
+func migration_setting_columns(tx *sql.Tx) error {
+	columns := [][2]string{
+		{"logo192", "TEXT"},
+		{"logo512", "TEXT"},
+		{"govRecord", "TEXT"},
+		{"jumpTargetBlank", "BOOLEAN"},
+		{"hideAdmin", "BOOLEAN"},
+		{"hideGithub", "BOOLEAN"},
+		{"customJS", "TEXT"},
+		{"customCSS", "TEXT"},
+		{"guestPassword", "TEXT"},
+	}
+	for _, column := range columns {
+		if err := addColumn(tx, "nav_setting", column[0], column[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tools数据表结构升级-20230327、20230627
+func migration_tool_sort_hide(tx *sql.Tx) error {
+	if err := addColumn(tx, "nav_table", "sort", "INTEGER"); err != nil {
+		return err
+	}
+	return addColumn(tx, "nav_table", "hide", "BOOLEAN")
+}
+
+// 分类表表结构升级-20230327、20241219-【隐藏分类】
+func migration_catelog_sort_hide(tx *sql.Tx) error {
+	if err := addColumn(tx, "nav_catelog", "sort", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	return addColumn(tx, "nav_catelog", "hide", "BOOLEAN")
+}
+
+// 早期版本的 nav_catelog.sort 允许为 NULL，重建表改为 NOT NULL DEFAULT 0
+func migration_catelog_sort_not_null(tx *sql.Tx) error {
+	var notNull int
+	err := tx.QueryRow(`SELECT "notnull" FROM pragma_table_info('nav_catelog') WHERE name = 'sort';`).Scan(&notNull)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if notNull == 1 {
+		return nil
+	}
+	return execAll(tx,
+		`UPDATE nav_catelog SET sort = 0 WHERE sort IS NULL;`,
+		// 之前的版本中途失败时可能留下这张表
+		`DROP TABLE IF EXISTS nav_catelog_new;`,
+		`
+		CREATE TABLE nav_catelog_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT,
+			sort INTEGER NOT NULL DEFAULT 0,
+			hide BOOLEAN
+		);
+		`,
+		`INSERT INTO nav_catelog_new (id, name, sort, hide) SELECT id, name, sort, hide FROM nav_catelog;`,
+		`DROP TABLE nav_catelog;`,
+		`ALTER TABLE nav_catelog_new RENAME TO nav_catelog;`,
+	)
+}
+
+// 用户表结构升级-【多用户角色】，已有用户默认为 owner
+func migration_user_role(tx *sql.Tx) error {
+	return addColumn(tx, "nav_user", "role", "TEXT NOT NULL DEFAULT 'owner'")
+}
+
+// api token 表结构升级-【权限范围、过期时间、最近使用记录】
+func migration_api_token_scopes(tx *sql.Tx) error {
+	columns := [][2]string{
+		{"scopes", "TEXT"},
+		{"expires_at", "INTEGER"},
+		{"created_at", "INTEGER"},
+		{"last_used_at", "INTEGER"},
+		{"last_used_ip", "TEXT"},
+	}
+	for _, column := range columns {
+		if err := addColumn(tx, "nav_api_token", column[0], column[1]); err != nil {
+			return err
+		}
 	}
+	// 旧的 token 保留原有权限
+	_, err := tx.Exec(`UPDATE nav_api_token SET scopes = ? WHERE scopes IS NULL;`, strings.Join(types.TokenScopes, ","))
+	return err
+}
 
-	// 2. 创建新表
-	sql_create_new_table := `
-        CREATE TABLE nav_catelog_new (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            name TEXT,
-            sort INTEGER NOT NULL DEFAULT 0,
-						hide BOOLEAN
-        );
-    `
+// 密钥表保存 JWT 签名密钥等需要跨重启保留的随机密钥；会话表的 id 为 JWT 的 jti
+func migration_secret_session(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_secret (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			expires_at INTEGER
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_session (
+			id TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			ip TEXT,
+			user_agent TEXT,
+			created_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL,
+			revoked_at INTEGER
+		);
+		`)
+}
 
-	_, err = DB.Exec(sql_create_new_table)
+// 把 nav_user 中的明文密码迁移为 bcrypt 哈希
+func migration_hash_user_passwords(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, password FROM nav_user;`)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	plain := map[int]string{}
+	for rows.Next() {
+		var id int
+		var password sql.NullString
+		if err = rows.Scan(&id, &password); err != nil {
+			rows.Close()
+			return err
+		}
+		if !utils.IsPasswordHashed(password.String) {
+			plain[id] = password.String
+		}
 	}
+	rows.Close()
 
-	// 3. 复制数据
-	sql_copy_data := `
-        INSERT INTO nav_catelog_new (id, name, sort, hide)
-        SELECT id, name, sort, hide FROM nav_catelog;
-    `
+	for id, password := range plain {
+		hash, err := utils.HashPassword(password)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(`UPDATE nav_user SET password = ? WHERE id = ?;`, hash, id); err != nil {
+			return err
+		}
+	}
+	if len(plain) > 0 {
+		logger.LogInfo("已将 %d 个用户的明文密码迁移为哈希", len(plain))
+	}
+	return nil
+}
 
-	_, err = DB.Exec(sql_copy_data)
+// 把旧版明文保存的 JWT 形式 API Token 迁移为哈希，迁移后旧 token 仍可继续使用
+func migration_hash_api_tokens(tx *sql.Tx) error {
+	if err := addColumn(tx, "nav_api_token", "token_hash", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumn(tx, "nav_api_token", "prefix", "TEXT"); err != nil {
+		return err
+	}
+	rows, err := tx.Query(`SELECT id, value FROM nav_api_token WHERE token_hash IS NULL AND value IS NOT NULL AND value != '';`)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	legacy := map[int]string{}
+	for rows.Next() {
+		var id int
+		var value string
+		if err = rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		legacy[id] = value
+	}
+	rows.Close()
+
+	for id, value := range legacy {
+		_, err = tx.Exec(`UPDATE nav_api_token SET token_hash = ?, prefix = ?, value = '' WHERE id = ?;`,
+			utils.HashApiToken(value), utils.LegacyApiTokenPrefix(value), id)
+		if err != nil {
+			return err
+		}
 	}
+	if len(legacy) > 0 {
+		logger.LogInfo("已将 %d 个 API Token 迁移为哈希存储", len(legacy))
+	}
+	return nil
+}
+
+// 登录失败计数表，key 形如 login:ip:<ip>、login:user:<name>
+func migration_login_attempt(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_login_attempt (
+			key TEXT PRIMARY KEY,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_failure_at INTEGER NOT NULL,
+			locked_until INTEGER NOT NULL DEFAULT 0
+		);
+		`)
+}
 
-	// 4. 删除旧表
-	sql_drop_old_table := `DROP TABLE nav_catelog;`
+// 用户表结构升级-【两步验证】，totp_last_step 记录最后一次使用的时间步，防止验证码重放；恢复码只保存哈希
+func migration_totp(tx *sql.Tx) error {
+	columns := [][2]string{
+		{"totp_secret", "TEXT NOT NULL DEFAULT ''"},
+		{"totp_enabled", "INTEGER NOT NULL DEFAULT 0"},
+		{"totp_last_step", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, column := range columns {
+		if err := addColumn(tx, "nav_user", column[0], column[1]); err != nil {
+			return err
+		}
+	}
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_recovery_code (
+			user_id INTEGER NOT NULL,
+			code_hash TEXT NOT NULL,
+			used_at INTEGER,
+			PRIMARY KEY (user_id, code_hash)
+		);
+		`)
+}
+
+// 外部身份（OIDC 的 issuer + sub，反向代理的用户名）与本地用户的关联，外部登录只按这一对查找用户
+func migration_user_external_identity(tx *sql.Tx) error {
+	if err := addColumn(tx, "nav_user", "external_issuer", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumn(tx, "nav_user", "external_subject", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS nav_user_external_identity ON nav_user (external_issuer, external_subject) WHERE external_subject != '';`)
+	return err
+}
 
-	_, err = DB.Exec(sql_drop_old_table)
+// 把明文保存的访客密码迁移为 bcrypt 哈希
+func migration_hash_guest_password(tx *sql.Tx) error {
+	var id int
+	var password sql.NullString
+	err := tx.QueryRow(`SELECT id, guestPassword FROM nav_setting ORDER BY id ASC LIMIT 1;`).Scan(&id, &password)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if password.String == "" || utils.IsPasswordHashed(password.String) {
+		return nil
+	}
+	hash, err := utils.HashPassword(password.String)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if _, err = tx.Exec(`UPDATE nav_setting SET guestPassword = ? WHERE id = ?;`, hash, id); err != nil {
+		return err
+	}
+	logger.LogInfo("已将访客密码迁移为哈希")
+	return nil
+}
+
+// 访客邀请链接表，只保存 token 的哈希
+func migration_guest_link(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_guest_link (
+			id TEXT PRIMARY KEY,
+			name TEXT,
+			prefix TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			catelog_ids TEXT NOT NULL DEFAULT '',
+			expires_at INTEGER NOT NULL,
+			max_uses INTEGER NOT NULL DEFAULT 0,
+			uses INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL
+		);
+		`)
+}
+
+// 审计日志表，只允许追加，过期记录由保留策略删除
+func migration_audit(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			created_at INTEGER NOT NULL,
+			actor_type TEXT NOT NULL,
+			actor_id INTEGER NOT NULL DEFAULT 0,
+			actor TEXT NOT NULL,
+			action TEXT NOT NULL,
+			target_type TEXT NOT NULL DEFAULT '',
+			target_id TEXT NOT NULL DEFAULT '',
+			changes TEXT NOT NULL DEFAULT '{}',
+			ip TEXT NOT NULL DEFAULT ''
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS nav_audit_created_at ON nav_audit (created_at);`,
+		`CREATE INDEX IF NOT EXISTS nav_audit_target ON nav_audit (target_type, target_id);`,
+		`
+		CREATE TRIGGER IF NOT EXISTS nav_audit_append_only BEFORE UPDATE ON nav_audit
+		BEGIN
+			SELECT RAISE(ABORT, 'nav_audit is append-only');
+		END;
+		`)
+}
+
+// 需要跨重启保留的运行状态（例如自动备份的最近结果），按名称保存一段 JSON
+func migration_state(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_state (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+		`)
+}
+
+// 工具和分类改为软删除，deleted_at 不为空表示在回收站中
+func migration_trash(tx *sql.Tx) error {
+	if err := addColumn(tx, "nav_table", "deleted_at", "INTEGER"); err != nil {
+		return err
 	}
+	return addColumn(tx, "nav_catelog", "deleted_at", "INTEGER")
+}
 
-	// 5. 重命名新表
-	sql_rename_table := `ALTER TABLE nav_catelog_new RENAME TO nav_catelog;`
+// 工具的历史版本，每次修改 nav_table 的一行后保存修改之后的完整内容。
+// 已有的工具先保存一份当前内容作为初始版本，之后的修改才能回滚
+func migration_tool_revision(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_tool_revision (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			tool_id INTEGER NOT NULL,
+			created_at INTEGER NOT NULL,
+			actor_type TEXT NOT NULL,
+			actor_id INTEGER NOT NULL DEFAULT 0,
+			actor TEXT NOT NULL,
+			action TEXT NOT NULL,
+			name TEXT,
+			url TEXT,
+			logo TEXT,
+			catelog TEXT,
+			desc TEXT,
+			sort INTEGER,
+			hide BOOLEAN,
+			deleted_at INTEGER
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS nav_tool_revision_tool ON nav_tool_revision (tool_id, id);`,
+		`
+		INSERT INTO nav_tool_revision (tool_id, created_at, actor_type, actor, action, name, url, logo, catelog, "desc", sort, hide, deleted_at)
+		SELECT id, CAST(strftime('%s', 'now') AS INTEGER), 'system', 'system', 'init', name, url, logo, catelog, "desc", sort, hide, deleted_at
+		FROM nav_table;
+		`)
+}
 
-	_, err = DB.Exec(sql_rename_table)
+// 工具改为通过 catelog_id 外键引用分类，替换原来按名称关联的 catelog 列。
+// 先为没有对应分类的名称创建分类：未删除的工具需要未删除的分类，回收站中的工具有同名分类即可。
+// 回收站中的工具优先关联与它同时删除的分类，其次是未删除的同名分类
+func migration_tool_catelog_id(tx *sql.Tx) error {
+	return execAll(tx, `
+		INSERT INTO nav_catelog (name, sort, hide)
+		SELECT DISTINCT catelog, 0, 0 FROM nav_table t
+		WHERE catelog IS NOT NULL AND catelog != '' AND NOT EXISTS (
+			SELECT 1 FROM nav_catelog c WHERE c.name = t.catelog AND (c.deleted_at IS NULL OR t.deleted_at IS NOT NULL)
+		);
+		`,
+		`DROP TABLE IF EXISTS nav_table_new;`,
+		`
+		CREATE TABLE nav_table_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT,
+			url TEXT,
+			logo TEXT,
+			catelog_id INTEGER REFERENCES nav_catelog (id) DEFERRABLE INITIALLY DEFERRED,
+			desc TEXT,
+			sort INTEGER,
+			hide BOOLEAN,
+			deleted_at INTEGER
+		);
+		`, `
+		INSERT INTO nav_table_new (id, name, url, logo, catelog_id, "desc", sort, hide, deleted_at)
+		SELECT id, name, url, logo, COALESCE(
+			(SELECT MIN(c.id) FROM nav_catelog c WHERE c.name = t.catelog AND c.deleted_at = t.deleted_at),
+			(SELECT MIN(c.id) FROM nav_catelog c WHERE c.name = t.catelog AND c.deleted_at IS NULL),
+			(SELECT MIN(c.id) FROM nav_catelog c WHERE c.name = t.catelog)
+		), "desc", sort, hide, deleted_at
+		FROM nav_table t;
+		`,
+		// 保留自增序列，避免彻底删除过的工具 id 被重新使用
+		`DELETE FROM sqlite_sequence WHERE name = 'nav_table_new';`,
+		`INSERT INTO sqlite_sequence (name, seq) SELECT 'nav_table_new', seq FROM sqlite_sequence WHERE name = 'nav_table';`,
+		`DROP TABLE nav_table;`,
+		`ALTER TABLE nav_table_new RENAME TO nav_table;`,
+		`CREATE INDEX IF NOT EXISTS nav_table_catelog_id ON nav_table (catelog_id);`,
+		`ALTER TABLE nav_tool_revision ADD COLUMN catelog_id INTEGER;`,
+		`
+		UPDATE nav_tool_revision SET catelog_id = COALESCE(
+			(SELECT MIN(c.id) FROM nav_catelog c WHERE c.name = nav_tool_revision.catelog AND c.deleted_at IS NULL),
+			(SELECT MIN(c.id) FROM nav_catelog c WHERE c.name = nav_tool_revision.catelog)
+		);
+		`)
+}
+
+// 分类可以嵌套，parent_id 为空表示顶级分类，已有的分类都是顶级分类
+func migration_catelog_parent(tx *sql.Tx) error {
+	if err := addColumn(tx, "nav_catelog", "parent_id", "INTEGER REFERENCES nav_catelog (id) DEFERRABLE INITIALLY DEFERRED"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS nav_catelog_parent_id ON nav_catelog (parent_id);`)
+	return err
+}
+
+// 工具的标签，与工具多对多关联，标签名称唯一
+func migration_tag(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_tag (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_tool_tag (
+			tool_id INTEGER NOT NULL REFERENCES nav_table (id) DEFERRABLE INITIALLY DEFERRED,
+			tag_id INTEGER NOT NULL REFERENCES nav_tag (id) DEFERRABLE INITIALLY DEFERRED,
+			PRIMARY KEY (tool_id, tag_id)
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS nav_tool_tag_tag_id ON nav_tool_tag (tag_id);`,
+	)
+}
+
+// 刷新一个工具在全文索引中的内容，id 为触发器中的表达式
+func ftsRefresh(id string) string {
+	return `
+		DELETE FROM nav_tool_fts WHERE rowid = ` + id + `;
+		INSERT INTO nav_tool_fts (rowid, name, "desc", url, catelog, tags)
+		SELECT id, name, "desc", url, catelog, tags FROM nav_tool_fts_source WHERE id = ` + id + `;`
+}
+
+// 工具的全文索引，覆盖名称、描述、网址、分类名称和标签。使用 trigram 分词，支持中文和网址中的任意片段，
+// 但少于 3 个字符的关键词无法匹配。索引由触发器与 nav_table、nav_catelog、nav_tag、nav_tool_tag 保持同步
+func migration_tool_fts(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE VIRTUAL TABLE IF NOT EXISTS nav_tool_fts USING fts5(name, "desc", url, catelog, tags, tokenize = 'trigram');
+		`, `
+		CREATE VIEW IF NOT EXISTS nav_tool_fts_source AS
+		SELECT t.id, t.name, t."desc", t.url, c.name AS catelog, (
+			SELECT group_concat(g.name, ' ') FROM nav_tool_tag tt JOIN nav_tag g ON g.id = tt.tag_id WHERE tt.tool_id = t.id
+		) AS tags
+		FROM nav_table t LEFT JOIN nav_catelog c ON c.id = t.catelog_id;
+		`, `
+		CREATE TRIGGER IF NOT EXISTS nav_tool_fts_insert AFTER INSERT ON nav_table BEGIN`+ftsRefresh("new.id")+`
+		END;
+		`, `
+		CREATE TRIGGER IF NOT EXISTS nav_tool_fts_update AFTER UPDATE OF name, "desc", url, catelog_id ON nav_table BEGIN`+ftsRefresh("new.id")+`
+		END;
+		`, `
+		CREATE TRIGGER IF NOT EXISTS nav_tool_fts_delete AFTER DELETE ON nav_table BEGIN
+			DELETE FROM nav_tool_fts WHERE rowid = old.id;
+		END;
+		`, `
+		CREATE TRIGGER IF NOT EXISTS nav_tool_fts_catelog AFTER UPDATE OF name ON nav_catelog BEGIN
+			DELETE FROM nav_tool_fts WHERE rowid IN (SELECT id FROM nav_table WHERE catelog_id = new.id);
+			INSERT INTO nav_tool_fts (rowid, name, "desc", url, catelog, tags)
+			SELECT id, name, "desc", url, catelog, tags FROM nav_tool_fts_source WHERE id IN (SELECT id FROM nav_table WHERE catelog_id = new.id);
+		END;
+		`, `
+		CREATE TRIGGER IF NOT EXISTS nav_tool_fts_tag AFTER UPDATE OF name ON nav_tag BEGIN
+			DELETE FROM nav_tool_fts WHERE rowid IN (SELECT tool_id FROM nav_tool_tag WHERE tag_id = new.id);
+			INSERT INTO nav_tool_fts (rowid, name, "desc", url, catelog, tags)
+			SELECT id, name, "desc", url, catelog, tags FROM nav_tool_fts_source WHERE id IN (SELECT tool_id FROM nav_tool_tag WHERE tag_id = new.id);
+		END;
+		`, `
+		CREATE TRIGGER IF NOT EXISTS nav_tool_fts_tool_tag_insert AFTER INSERT ON nav_tool_tag BEGIN`+ftsRefresh("new.tool_id")+`
+		END;
+		`, `
+		CREATE TRIGGER IF NOT EXISTS nav_tool_fts_tool_tag_delete AFTER DELETE ON nav_tool_tag BEGIN`+ftsRefresh("old.tool_id")+`
+		END;
+		`, `
+		DELETE FROM nav_tool_fts;
+		`, `
+		INSERT INTO nav_tool_fts (rowid, name, "desc", url, catelog, tags)
+		SELECT id, name, "desc", url, catelog, tags FROM nav_tool_fts_source;
+		`)
+}
+
+// 第 22 版起全文索引包含的列，name_pinyin 和 desc_pinyin 为全拼和拼音首字母以空格连接
+const sql_fts_columns = `name, "desc", url, catelog, tags, name_pinyin, desc_pinyin`
+
+// 同 ftsRefresh，用于包含拼音列的全文索引
+func ftsPinyinRefresh(id string) string {
+	return `
+		DELETE FROM nav_tool_fts WHERE rowid = ` + id + `;
+		INSERT INTO nav_tool_fts (rowid, ` + sql_fts_columns + `)
+		SELECT id, ` + sql_fts_columns + ` FROM nav_tool_fts_source WHERE id = ` + id + `;`
+}
+
+// 同 ftsPinyinRefresh，刷新 ids 子查询中的全部工具
+func ftsPinyinRefreshIn(ids string) string {
+	return `
+		DELETE FROM nav_tool_fts WHERE rowid IN (` + ids + `);
+		INSERT INTO nav_tool_fts (rowid, ` + sql_fts_columns + `)
+		SELECT id, ` + sql_fts_columns + ` FROM nav_tool_fts_source WHERE id IN (` + ids + `);`
+}
+
+// 工具名称和描述的全拼与拼音首字母，由程序计算（见 utils.Pinyin）后随工具一起写入，已有的工具在这里补齐。
+// 全文索引增加两列拼音，需要删除重建，触发器随之重建，并在拼音变化时刷新索引
+func migration_tool_pinyin(tx *sql.Tx) error {
+	err := execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_tool_pinyin (
+			tool_id INTEGER PRIMARY KEY REFERENCES nav_table (id) DEFERRABLE INITIALLY DEFERRED,
+			name_full TEXT NOT NULL DEFAULT '',
+			name_initials TEXT NOT NULL DEFAULT '',
+			desc_full TEXT NOT NULL DEFAULT '',
+			desc_initials TEXT NOT NULL DEFAULT ''
+		);
+		`,
+		`DROP TRIGGER IF EXISTS nav_tool_fts_insert;`,
+		`DROP TRIGGER IF EXISTS nav_tool_fts_update;`,
+		`DROP TRIGGER IF EXISTS nav_tool_fts_delete;`,
+		`DROP TRIGGER IF EXISTS nav_tool_fts_catelog;`,
+		`DROP TRIGGER IF EXISTS nav_tool_fts_tag;`,
+		`DROP TRIGGER IF EXISTS nav_tool_fts_tool_tag_insert;`,
+		`DROP TRIGGER IF EXISTS nav_tool_fts_tool_tag_delete;`,
+		`DROP VIEW IF EXISTS nav_tool_fts_source;`,
+		`DROP TABLE IF EXISTS nav_tool_fts;`,
+	)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if err = fillToolPinyin(tx, SQLite); err != nil {
+		return err
+	}
+	return execAll(tx, `
+		CREATE VIRTUAL TABLE nav_tool_fts USING fts5(`+sql_fts_columns+`, tokenize = 'trigram');
+		`, `
+		CREATE VIEW nav_tool_fts_source AS
+		SELECT t.id, t.name, t."desc", t.url, c.name AS catelog, (
+			SELECT group_concat(g.name, ' ') FROM nav_tool_tag tt JOIN nav_tag g ON g.id = tt.tag_id WHERE tt.tool_id = t.id
+		) AS tags,
+		p.name_full || ' ' || p.name_initials AS name_pinyin,
+		p.desc_full || ' ' || p.desc_initials AS desc_pinyin
+		FROM nav_table t
+		LEFT JOIN nav_catelog c ON c.id = t.catelog_id
+		LEFT JOIN nav_tool_pinyin p ON p.tool_id = t.id;
+		`, `
+		CREATE TRIGGER nav_tool_fts_insert AFTER INSERT ON nav_table BEGIN`+ftsPinyinRefresh("new.id")+`
+		END;
+		`, `
+		CREATE TRIGGER nav_tool_fts_update AFTER UPDATE OF name, "desc", url, catelog_id ON nav_table BEGIN`+ftsPinyinRefresh("new.id")+`
+		END;
+		`, `
+		CREATE TRIGGER nav_tool_fts_delete AFTER DELETE ON nav_table BEGIN
+			DELETE FROM nav_tool_fts WHERE rowid = old.id;
+		END;
+		`, `
+		CREATE TRIGGER nav_tool_fts_catelog AFTER UPDATE OF name ON nav_catelog BEGIN`+
+		ftsPinyinRefreshIn("SELECT id FROM nav_table WHERE catelog_id = new.id")+`
+		END;
+		`, `
+		CREATE TRIGGER nav_tool_fts_tag AFTER UPDATE OF name ON nav_tag BEGIN`+
+		ftsPinyinRefreshIn("SELECT tool_id FROM nav_tool_tag WHERE tag_id = new.id")+`
+		END;
+		`, `
+		CREATE TRIGGER nav_tool_fts_tool_tag_insert AFTER INSERT ON nav_tool_tag BEGIN`+ftsPinyinRefresh("new.tool_id")+`
+		END;
+		`, `
+		CREATE TRIGGER nav_tool_fts_tool_tag_delete AFTER DELETE ON nav_tool_tag BEGIN`+ftsPinyinRefresh("old.tool_id")+`
+		END;
+		`, `
+		CREATE TRIGGER nav_tool_fts_pinyin_insert AFTER INSERT ON nav_tool_pinyin BEGIN`+ftsPinyinRefresh("new.tool_id")+`
+		END;
+		`, `
+		CREATE TRIGGER nav_tool_fts_pinyin_update AFTER UPDATE ON nav_tool_pinyin BEGIN`+ftsPinyinRefresh("new.tool_id")+`
+		END;
+		`, `
+		CREATE TRIGGER nav_tool_fts_pinyin_delete AFTER DELETE ON nav_tool_pinyin BEGIN`+ftsPinyinRefresh("old.tool_id")+`
+		END;
+		`, `
+		INSERT INTO nav_tool_fts (rowid, `+sql_fts_columns+`)
+		SELECT id, `+sql_fts_columns+` FROM nav_tool_fts_source;
+		`)
+}
+
+// 为所有工具计算拼音索引，已有的覆盖
+func fillToolPinyin(tx *sql.Tx, dialect Dialect) error {
+	rows, err := tx.Query(`SELECT id, name, "desc" FROM nav_table;`)
+	if err != nil {
+		return err
+	}
+	type tool struct {
+		id         int
+		name, desc sql.NullString
+	}
+	tools := make([]tool, 0)
+	for rows.Next() {
+		var t tool
+		if err = rows.Scan(&t.id, &t.name, &t.desc); err != nil {
+			rows.Close()
+			return err
+		}
+		tools = append(tools, t)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(dialect.Rebind(`
+		INSERT INTO nav_tool_pinyin (tool_id, name_full, name_initials, desc_full, desc_initials)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (tool_id) DO UPDATE SET name_full = excluded.name_full, name_initials = excluded.name_initials,
+			desc_full = excluded.desc_full, desc_initials = excluded.desc_initials;
+		`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, t := range tools {
+		nameFull, nameInitials := utils.Pinyin(t.name.String)
+		descFull, descInitials := utils.Pinyin(t.desc.String)
+		if _, err = stmt.Exec(t.id, nameFull, nameInitials, descFull, descInitials); err != nil {
+			return err
+		}
 	}
+	return nil
 }
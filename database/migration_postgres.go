@@ -0,0 +1,282 @@
+package database
+
+import "database/sql"
+
+// PostgreSQL 没有历史包袱，直接从 SQLite 第 16 版的表结构开始；之后新增的迁移需要同时追加到两个列表
+var postgresMigrations = []migration{
+	{16, "init", migration_postgres_init},
+	{17, "state", migration_postgres_state},
+	{18, "trash", migration_postgres_trash},
+	{19, "tool_revision", migration_postgres_tool_revision},
+	{20, "tool_catelog_id", migration_postgres_tool_catelog_id},
+	{21, "catelog_parent", migration_postgres_catelog_parent},
+	{22, "tag", migration_postgres_tag},
+	{23, "tool_fts", migration_postgres_tool_fts},
+	{24, "tool_pinyin", migration_postgres_tool_pinyin},
+}
+
+// 与 SQLite 第 16 版等价的表结构。时间统一为 BIGINT 秒级时间戳，SQLite 中声明为 BOOLEAN 的列保持 BOOLEAN
+func migration_postgres_init(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_user (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT,
+			password TEXT,
+			role TEXT NOT NULL DEFAULT 'owner',
+			totp_secret TEXT NOT NULL DEFAULT '',
+			totp_enabled INTEGER NOT NULL DEFAULT 0,
+			totp_last_step BIGINT NOT NULL DEFAULT 0,
+			external_issuer TEXT NOT NULL DEFAULT '',
+			external_subject TEXT NOT NULL DEFAULT ''
+		);
+		`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS nav_user_external_identity ON nav_user (external_issuer, external_subject) WHERE external_subject != '';`,
+		`
+		CREATE TABLE IF NOT EXISTS nav_setting (
+			id BIGSERIAL PRIMARY KEY,
+			favicon TEXT,
+			title TEXT,
+			govRecord TEXT,
+			logo192 TEXT,
+			logo512 TEXT,
+			hideAdmin BOOLEAN,
+			hideGithub BOOLEAN,
+			jumpTargetBlank BOOLEAN,
+			customJS TEXT,
+			customCSS TEXT,
+			guestPassword TEXT
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_table (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT,
+			url TEXT,
+			logo TEXT,
+			catelog TEXT,
+			"desc" TEXT,
+			sort INTEGER,
+			hide BOOLEAN
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_catelog (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT,
+			sort INTEGER NOT NULL DEFAULT 0,
+			hide BOOLEAN
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_api_token (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT,
+			value TEXT,
+			disabled INTEGER,
+			scopes TEXT,
+			expires_at BIGINT,
+			created_at BIGINT,
+			last_used_at BIGINT,
+			last_used_ip TEXT,
+			token_hash TEXT,
+			prefix TEXT
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_img (
+			id BIGSERIAL PRIMARY KEY,
+			url TEXT,
+			value TEXT
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_secret (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			created_at BIGINT NOT NULL,
+			expires_at BIGINT
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_session (
+			id TEXT PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			ip TEXT,
+			user_agent TEXT,
+			created_at BIGINT NOT NULL,
+			expires_at BIGINT NOT NULL,
+			revoked_at BIGINT
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_login_attempt (
+			key TEXT PRIMARY KEY,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_failure_at BIGINT NOT NULL,
+			locked_until BIGINT NOT NULL DEFAULT 0
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_recovery_code (
+			user_id BIGINT NOT NULL,
+			code_hash TEXT NOT NULL,
+			used_at BIGINT,
+			PRIMARY KEY (user_id, code_hash)
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_guest_link (
+			id TEXT PRIMARY KEY,
+			name TEXT,
+			prefix TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			catelog_ids TEXT NOT NULL DEFAULT '',
+			expires_at BIGINT NOT NULL,
+			max_uses INTEGER NOT NULL DEFAULT 0,
+			uses INTEGER NOT NULL DEFAULT 0,
+			created_at BIGINT NOT NULL
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_audit (
+			id BIGSERIAL PRIMARY KEY,
+			created_at BIGINT NOT NULL,
+			actor_type TEXT NOT NULL,
+			actor_id BIGINT NOT NULL DEFAULT 0,
+			actor TEXT NOT NULL,
+			action TEXT NOT NULL,
+			target_type TEXT NOT NULL DEFAULT '',
+			target_id TEXT NOT NULL DEFAULT '',
+			changes TEXT NOT NULL DEFAULT '{}',
+			ip TEXT NOT NULL DEFAULT ''
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS nav_audit_created_at ON nav_audit (created_at);`,
+		`CREATE INDEX IF NOT EXISTS nav_audit_target ON nav_audit (target_type, target_id);`,
+		`
+		CREATE OR REPLACE FUNCTION nav_audit_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'nav_audit is append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+		`, `
+		DROP TRIGGER IF EXISTS nav_audit_append_only ON nav_audit;
+		`, `
+		CREATE TRIGGER nav_audit_append_only BEFORE UPDATE ON nav_audit
+		FOR EACH ROW EXECUTE FUNCTION nav_audit_append_only();
+		`)
+}
+
+// 运行状态，见 migration_state
+func migration_postgres_state(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_state (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at BIGINT NOT NULL
+		);
+		`)
+}
+
+// 回收站，见 migration_trash
+func migration_postgres_trash(tx *sql.Tx) error {
+	return execAll(tx,
+		`ALTER TABLE nav_table ADD COLUMN IF NOT EXISTS deleted_at BIGINT;`,
+		`ALTER TABLE nav_catelog ADD COLUMN IF NOT EXISTS deleted_at BIGINT;`,
+	)
+}
+
+// 工具的历史版本，见 migration_tool_revision
+func migration_postgres_tool_revision(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_tool_revision (
+			id BIGSERIAL PRIMARY KEY,
+			tool_id BIGINT NOT NULL,
+			created_at BIGINT NOT NULL,
+			actor_type TEXT NOT NULL,
+			actor_id BIGINT NOT NULL DEFAULT 0,
+			actor TEXT NOT NULL,
+			action TEXT NOT NULL,
+			name TEXT,
+			url TEXT,
+			logo TEXT,
+			catelog TEXT,
+			"desc" TEXT,
+			sort INTEGER,
+			hide BOOLEAN,
+			deleted_at BIGINT
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS nav_tool_revision_tool ON nav_tool_revision (tool_id, id);`,
+		`
+		INSERT INTO nav_tool_revision (tool_id, created_at, actor_type, actor, action, name, url, logo, catelog, "desc", sort, hide, deleted_at)
+		SELECT id, EXTRACT(EPOCH FROM now())::BIGINT, 'system', 'system', 'init', name, url, logo, catelog, "desc", sort, hide, deleted_at
+		FROM nav_table;
+		`)
+}
+
+// 工具通过 catelog_id 外键引用分类，见 migration_tool_catelog_id
+func migration_postgres_tool_catelog_id(tx *sql.Tx) error {
+	return execAll(tx, `
+		INSERT INTO nav_catelog (name, sort, hide)
+		SELECT DISTINCT catelog, 0, false FROM nav_table t
+		WHERE catelog IS NOT NULL AND catelog != '' AND NOT EXISTS (
+			SELECT 1 FROM nav_catelog c WHERE c.name = t.catelog AND (c.deleted_at IS NULL OR t.deleted_at IS NOT NULL)
+		);
+		`,
+		`ALTER TABLE nav_table ADD COLUMN IF NOT EXISTS catelog_id BIGINT REFERENCES nav_catelog (id) DEFERRABLE INITIALLY DEFERRED;`,
+		`
+		UPDATE nav_table t SET catelog_id = COALESCE(
+			(SELECT MIN(c.id) FROM nav_catelog c WHERE c.name = t.catelog AND c.deleted_at = t.deleted_at),
+			(SELECT MIN(c.id) FROM nav_catelog c WHERE c.name = t.catelog AND c.deleted_at IS NULL),
+			(SELECT MIN(c.id) FROM nav_catelog c WHERE c.name = t.catelog)
+		);
+		`,
+		`ALTER TABLE nav_table DROP COLUMN IF EXISTS catelog;`,
+		`CREATE INDEX IF NOT EXISTS nav_table_catelog_id ON nav_table (catelog_id);`,
+		`ALTER TABLE nav_tool_revision ADD COLUMN IF NOT EXISTS catelog_id BIGINT;`,
+		`
+		UPDATE nav_tool_revision r SET catelog_id = COALESCE(
+			(SELECT MIN(c.id) FROM nav_catelog c WHERE c.name = r.catelog AND c.deleted_at IS NULL),
+			(SELECT MIN(c.id) FROM nav_catelog c WHERE c.name = r.catelog)
+		);
+		`)
+}
+
+// 分类嵌套，见 migration_catelog_parent
+func migration_postgres_catelog_parent(tx *sql.Tx) error {
+	return execAll(tx,
+		`ALTER TABLE nav_catelog ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES nav_catelog (id) DEFERRABLE INITIALLY DEFERRED;`,
+		`CREATE INDEX IF NOT EXISTS nav_catelog_parent_id ON nav_catelog (parent_id);`,
+	)
+}
+
+// 工具标签，见 migration_tag
+func migration_postgres_tag(tx *sql.Tx) error {
+	return execAll(tx, `
+		CREATE TABLE IF NOT EXISTS nav_tag (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE
+		);
+		`, `
+		CREATE TABLE IF NOT EXISTS nav_tool_tag (
+			tool_id BIGINT NOT NULL REFERENCES nav_table (id) DEFERRABLE INITIALLY DEFERRED,
+			tag_id BIGINT NOT NULL REFERENCES nav_tag (id) DEFERRABLE INITIALLY DEFERRED,
+			PRIMARY KEY (tool_id, tag_id)
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS nav_tool_tag_tag_id ON nav_tool_tag (tag_id);`,
+	)
+}
+
+// PostgreSQL 不使用 FTS5，搜索退化为 LIKE 匹配，这里只占用版本号，保持两边的结构版本一致
+func migration_postgres_tool_fts(tx *sql.Tx) error {
+	return nil
+}
+
+// 工具名称和描述的拼音索引。PostgreSQL 没有全文索引，只用于 LIKE 匹配
+func migration_postgres_tool_pinyin(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS nav_tool_pinyin (
+			tool_id BIGINT PRIMARY KEY REFERENCES nav_table (id) DEFERRABLE INITIALLY DEFERRED,
+			name_full TEXT NOT NULL DEFAULT '',
+			name_initials TEXT NOT NULL DEFAULT '',
+			desc_full TEXT NOT NULL DEFAULT '',
+			desc_initials TEXT NOT NULL DEFAULT ''
+		);
+		`)
+	if err != nil {
+		return err
+	}
+	return fillToolPinyin(tx, Postgres)
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mereith/nav/utils"
+)
+
+// 在临时目录中打开一个空的 SQLite 数据库作为 DB，测试结束后关闭并恢复原来的配置
+func openTestDB(t *testing.T) {
+	t.Helper()
+	prevDB, prevDir, prevFile := DB, dataDir, dbFile
+	dir := t.TempDir()
+	Configure(dir, filepath.Join(dir, "nav.db"))
+	OpenDB()
+	t.Cleanup(func() {
+		DB.Close()
+		DB, dataDir, dbFile = prevDB, prevDir, prevFile
+	})
+}
+
+func mustExec(t *testing.T, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := DB.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+func TestMigrationHashUserPasswords(t *testing.T) {
+	openTestDB(t)
+	hashed, err := utils.HashPassword("hashed-before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, `CREATE TABLE nav_user (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, password TEXT);`)
+	mustExec(t, `INSERT INTO nav_user (name, password) VALUES ('plain', 'admin'), ('hashed', ?), ('empty', '');`, hashed)
+
+	// 重复执行时已经是哈希的密码保持不变
+	for i := 0; i < 2; i++ {
+		tx, err := DB.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = migration_hash_user_passwords(tx.Tx); err != nil {
+			t.Fatal(err)
+		}
+		if err = tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{"plain", "admin"},
+		{"hashed", "hashed-before"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		var stored string
+		if err := DB.QueryRow(`SELECT password FROM nav_user WHERE name = ?;`, tt.name).Scan(&stored); err != nil {
+			t.Fatal(err)
+		}
+		if !utils.IsPasswordHashed(stored) {
+			t.Errorf("%s: 密码没有迁移为哈希: %q", tt.name, stored)
+		}
+		if !utils.VerifyPassword(stored, tt.password) {
+			t.Errorf("%s: 迁移后原密码无法通过校验", tt.name)
+		}
+		if utils.VerifyPassword(stored, tt.password+"x") {
+			t.Errorf("%s: 迁移后错误的密码通过了校验", tt.name)
+		}
+		if tt.name == "hashed" && stored != hashed {
+			t.Errorf("已经是哈希的密码被重新计算")
+		}
+	}
+}
+
+// 最早版本（没有 schema_migrations，也没有之后补充的列）的数据库，升级后数据应完整保留
+func TestMigrateFromV0(t *testing.T) {
+	openTestDB(t)
+	mustExec(t, `CREATE TABLE nav_user (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, password TEXT);`)
+	mustExec(t, `
+		CREATE TABLE nav_setting (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, favicon TEXT, title TEXT, govRecord TEXT,
+			logo192 TEXT, logo512 TEXT, hideAdmin BOOLEAN, hideGithub BOOLEAN, jumpTargetBlank BOOLEAN
+		);`)
+	mustExec(t, `CREATE TABLE nav_table (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, url TEXT, logo TEXT, catelog TEXT, desc TEXT);`)
+	mustExec(t, `CREATE TABLE nav_catelog (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT);`)
+	mustExec(t, `CREATE TABLE nav_api_token (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, value TEXT, disabled INTEGER);`)
+	mustExec(t, `CREATE TABLE nav_img (id INTEGER PRIMARY KEY AUTOINCREMENT, url TEXT, value TEXT);`)
+	mustExec(t, `INSERT INTO nav_user (name, password) VALUES ('admin', 'secret');`)
+	mustExec(t, `INSERT INTO nav_setting (favicon, title, govRecord, logo192, logo512, hideAdmin, hideGithub, jumpTargetBlank)
+		VALUES ('favicon.ico', '我的导航', '', 'logo192.png', 'logo512.png', 0, 0, 1);`)
+	mustExec(t, `INSERT INTO nav_catelog (name) VALUES ('常用工具'), ('开发');`)
+	// 最后一个工具的分类在分类表中不存在
+	mustExec(t, `INSERT INTO nav_table (name, url, logo, catelog, desc) VALUES
+		('工号系统', 'https://hr.example.com', '', '常用工具', '查询员工工号'),
+		('GitHub', 'https://github.com', '', '开发', 'Code hosting'),
+		('遗留工具', 'https://old.example.com', '', '已删除的分类', '');`)
+	mustExec(t, `INSERT INTO nav_api_token (name, value, disabled) VALUES ('ci', 'legacy-token', 0);`)
+
+	if err := Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	version, err := SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Errorf("SchemaVersion = %d, want %d", version, LatestSchemaVersion())
+	}
+	backups, err := filepath.Glob(filepath.Join(DataDir(), "backups", "nav-v0-*.db"))
+	if err != nil || len(backups) != 1 {
+		t.Errorf("迁移前没有备份: %v %v", backups, err)
+	}
+
+	var password, role string
+	if err := DB.QueryRow(`SELECT password, role FROM nav_user WHERE name = 'admin';`).Scan(&password, &role); err != nil {
+		t.Fatal(err)
+	}
+	if !utils.IsPasswordHashed(password) || !utils.VerifyPassword(password, "secret") {
+		t.Errorf("密码没有正确迁移: %q", password)
+	}
+	if role != "owner" {
+		t.Errorf("role = %q, want owner", role)
+	}
+
+	var title string
+	if err := DB.QueryRow(`SELECT title FROM nav_setting;`).Scan(&title); err != nil || title != "我的导航" {
+		t.Errorf("title = %q, %v", title, err)
+	}
+
+	var value, tokenHash string
+	if err := DB.QueryRow(`SELECT value, token_hash FROM nav_api_token WHERE name = 'ci';`).Scan(&value, &tokenHash); err != nil {
+		t.Fatal(err)
+	}
+	if value != "" || tokenHash != utils.HashApiToken("legacy-token") {
+		t.Errorf("API Token 没有迁移为哈希: value=%q hash=%q", value, tokenHash)
+	}
+
+	tools := []struct {
+		name    string
+		catelog string
+	}{
+		{"工号系统", "常用工具"},
+		{"GitHub", "开发"},
+		{"遗留工具", "已删除的分类"},
+	}
+	for _, tt := range tools {
+		var catelog string
+		err := DB.QueryRow(`SELECT c.name FROM nav_table t JOIN nav_catelog c ON c.id = t.catelog_id WHERE t.name = ?;`, tt.name).Scan(&catelog)
+		if err != nil || catelog != tt.catelog {
+			t.Errorf("%s: 分类 = %q, %v, want %q", tt.name, catelog, err, tt.catelog)
+		}
+	}
+
+	var nameFull, nameInitials string
+	err = DB.QueryRow(`SELECT p.name_full, p.name_initials FROM nav_tool_pinyin p JOIN nav_table t ON t.id = p.tool_id WHERE t.name = '工号系统';`).Scan(&nameFull, &nameInitials)
+	if err != nil || nameFull != "gonghaoxitong" || nameInitials != "ghxt" {
+		t.Errorf("拼音索引 = %q %q, %v", nameFull, nameInitials, err)
+	}
+	matches := []struct {
+		query string
+		want  string
+	}{
+		{`"员工工号"`, "工号系统"},
+		{`"gonghao"`, "工号系统"},
+		{`"hosting"`, "GitHub"},
+		{`"常用工具"`, "工号系统"},
+	}
+	for _, tt := range matches {
+		var name string
+		err := DB.QueryRow(`SELECT t.name FROM nav_tool_fts f JOIN nav_table t ON t.id = f.rowid WHERE nav_tool_fts MATCH ?;`, tt.query).Scan(&name)
+		if err != nil || name != tt.want {
+			t.Errorf("全文索引 %s = %q, %v, want %q", tt.query, name, err, tt.want)
+		}
+	}
+
+	rows, err := DB.Query(`PRAGMA foreign_key_check;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows.Next() {
+		t.Error("迁移后存在违反外键约束的数据")
+	}
+	rows.Close()
+	var integrity string
+	if err := DB.QueryRow(`PRAGMA integrity_check;`).Scan(&integrity); err != nil || integrity != "ok" {
+		t.Errorf("integrity_check = %q, %v", integrity, err)
+	}
+
+	// 已是最新版本时再次执行不做任何事
+	if err := Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := filepath.Glob(filepath.Join(DataDir(), "backups", "*.db")); len(again) != 1 {
+		t.Errorf("没有待执行的迁移时也做了备份: %v", again)
+	}
+}
@@ -1,15 +0,0 @@
-package database
-
-func HasApiToken(token string) bool {
-	sql := `SELECT value FROM nav_api_token WHERE value = ? and disabled = 0`
-	rows, err := DB.Query(sql, token)
-	if err != nil {
-		return false
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		return true
-	}
-	return false
-}
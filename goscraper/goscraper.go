@@ -330,8 +330,6 @@ func (scraper *Scraper) parseDocument(doc *Document) error {
 		}
 
 	}
-
-	return nil
 }
 
 func avoidByte(b byte) bool {
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/types"
+)
+
+// 当前请求的操作者，API Token 以 token 名称记录
+func actorOf(c *gin.Context) types.Actor {
+	actor := types.Actor{
+		Type: "user",
+		Id:   c.GetInt("uid"),
+		Name: c.GetString("username"),
+	}
+	if tokenId, ok := c.Get("tokenId"); ok {
+		actor.Type = "token"
+		actor.Id, _ = tokenId.(int)
+		actor.Name = c.GetString("tokenName")
+	}
+	return actor
+}
+
+// 记录当前请求的操作者对目标做的修改
+func audit(c *gin.Context, action string, targetType string, targetId interface{}, before, after interface{}) {
+	actor := actorOf(c)
+	entry := types.Audit{
+		ActorType:  actor.Type,
+		ActorId:    actor.Id,
+		Actor:      actor.Name,
+		Action:     action,
+		TargetType: targetType,
+		Ip:         c.ClientIP(),
+	}
+	if targetId != nil {
+		entry.TargetId = fmt.Sprint(targetId)
+	}
+	service.RecordAudit(entry, before, after)
+}
+
+// 修改用户时只记录密码是否变化
+func auditUserState(user types.User, password string) gin.H {
+	state := gin.H{
+		"name":        user.Name,
+		"role":        user.Role,
+		"totpEnabled": user.TotpEnabled,
+	}
+	if user.ExternalSubject != "" {
+		state["externalIssuer"] = user.ExternalIssuer
+		state["externalSubject"] = user.ExternalSubject
+	}
+	if password != "" {
+		state["password"] = true
+	}
+	return state
+}
+
+func GetAuditHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 20
+	}
+	since, _ := strconv.ParseInt(c.Query("since"), 10, 64)
+	until, _ := strconv.ParseInt(c.Query("until"), 10, 64)
+
+	items, total := service.GetAuditPage(types.AuditQueryDto{
+		Page:       page,
+		PageSize:   pageSize,
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("targetType"),
+		TargetId:   c.Query("targetId"),
+		Since:      since,
+		Until:      until,
+	})
+	c.JSON(200, gin.H{
+		"success": true,
+		"data": gin.H{
+			"items": items,
+			"total": total,
+			"page":  page,
+			"size":  pageSize,
+		},
+	})
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/middleware"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/utils"
+)
+
+// 备份和恢复要传输整个数据库文件，不能受服务器默认的读写超时限制
+const backupTransferTimeout = 30 * time.Minute
+
+// 延长当前连接的读写超时，read 为 false 时只延长写超时
+func extendTransferDeadline(c *gin.Context, read bool) {
+	controller := http.NewResponseController(c.Writer)
+	deadline := time.Now().Add(backupTransferTimeout)
+	if read {
+		utils.CheckErr(controller.SetReadDeadline(deadline))
+	}
+	utils.CheckErr(controller.SetWriteDeadline(deadline))
+}
+
+// 下载当前数据库的一致快照
+func BackupHandler(c *gin.Context) {
+	extendTransferDeadline(c, false)
+	path, err := database.TempSnapshot()
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": "生成备份失败: " + err.Error(),
+		})
+		return
+	}
+	defer os.RemoveAll(filepath.Dir(path))
+	audit(c, "database.backup", "database", nil, nil, nil)
+	c.FileAttachment(path, "nav-"+time.Now().Format("20060102150405")+".db")
+}
+
+// 上传备份文件并替换当前数据库，表单字段为 file
+func RestoreHandler(c *gin.Context) {
+	extendTransferDeadline(c, true)
+	upload, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "请上传备份文件",
+		})
+		return
+	}
+	src, err := upload.Open()
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	defer src.Close()
+	dst, err := database.CreateRestoreFile()
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	// 恢复成功后文件已被重命名，删除只对失败的情况生效
+	defer os.Remove(dst.Name())
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": "保存上传文件失败: " + err.Error(),
+		})
+		return
+	}
+	version, err := database.ValidateBackup(dst.Name())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	// 恢复需要独占数据库，先释放本次请求持有的读锁，完成后重新获取
+	middleware.ReleaseDatabase(c)
+	err = service.RestoreDatabase(dst.Name())
+	release := database.Acquire()
+	defer release()
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": "恢复失败: " + err.Error(),
+		})
+		return
+	}
+	// 审计记录写入恢复后的数据库
+	audit(c, "database.restore", "database", nil, nil, gin.H{"file": upload.Filename, "version": version})
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "恢复成功，请重新登录",
+	})
+}
+
+// 自动备份状态和备份目录中的文件
+func GetBackupStatusHandler(c *gin.Context) {
+	files, err := service.ListBackups()
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"data": gin.H{
+			"status": service.GetBackupStatus(),
+			"files":  files,
+		},
+	})
+}
+
+// 立即执行一次自动备份
+func RunBackupHandler(c *gin.Context) {
+	name, err := service.RunBackup()
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": "备份失败: " + err.Error(),
+		})
+		return
+	}
+	audit(c, "database.backup", "database", nil, nil, gin.H{"file": name})
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "备份成功",
+		"data":    gin.H{"file": name},
+	})
+}
+
+// 健康检查：数据库不可用时返回 503，同时给出自动备份的结果
+func HealthHandler(c *gin.Context) {
+	status := service.GetBackupStatus()
+	backup := gin.H{
+		"enabled":       status.Enabled,
+		"lastSuccessAt": status.LastSuccessAt,
+		"lastError":     status.LastError,
+	}
+	if err := database.DB.Ping(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success":      false,
+			"errorMessage": "数据库不可用",
+			"data":         gin.H{"database": "error", "backup": backup},
+		})
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"data":    gin.H{"database": "ok", "backup": backup},
+	})
+}
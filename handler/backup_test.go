@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/config"
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/middleware"
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/service"
+)
+
+// 在临时目录中初始化数据库，并用默认的读写超时启动只有备份和恢复接口的服务
+func startBackupServer(t *testing.T) (*httptest.Server, time.Duration) {
+	t.Helper()
+	dir := t.TempDir()
+	database.Configure(dir, filepath.Join(dir, "nav.db"))
+	database.InitDB()
+	repository.Init(database.DB)
+	service.InitGuestSecret()
+	t.Cleanup(func() {
+		database.DB.Close()
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.DatabaseGate())
+	router.GET("/backup", BackupHandler)
+	router.POST("/restore", RestoreHandler)
+	server := httptest.NewUnstartedServer(router)
+	cfg := config.Default()
+	server.Config.ReadTimeout = cfg.ReadTimeout
+	server.Config.WriteTimeout = cfg.WriteTimeout
+	server.Start()
+	t.Cleanup(server.Close)
+	// 传输过程中停顿的时间超过默认的超时
+	return server, max(cfg.ReadTimeout, cfg.WriteTimeout) + 500*time.Millisecond
+}
+
+// 下载时客户端长时间不读取，超过默认写超时后仍能拿到完整的备份
+func TestBackupHandlerSlowClient(t *testing.T) {
+	server, pause := startBackupServer(t)
+	// 数据库需要比连接的缓冲区大，服务端才会在写入时阻塞
+	if _, err := database.DB.Exec(`CREATE TABLE pad (data BLOB); INSERT INTO pad (data) VALUES (zeroblob(16 * 1024 * 1024));`); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(server.URL + "/backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	time.Sleep(pause)
+	path := filepath.Join(t.TempDir(), "download.db")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := io.Copy(out, resp.Body)
+	out.Close()
+	if err != nil {
+		t.Fatalf("下载在 %d 字节处中断: %v", n, err)
+	}
+	if _, err := database.ValidateBackup(path); err != nil {
+		t.Errorf("下载的备份无效: %v", err)
+	}
+}
+
+// 发送到一半时停顿 pause 的请求体
+type slowReader struct {
+	data  []byte
+	pause time.Duration
+	read  int
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if r.read >= len(r.data) {
+		return 0, io.EOF
+	}
+	half := len(r.data) / 2
+	if r.read == half && r.pause > 0 {
+		time.Sleep(r.pause)
+		r.pause = 0
+	}
+	end := len(r.data)
+	if r.read < half {
+		end = half
+	}
+	n := copy(p, r.data[r.read:end])
+	r.read += n
+	return n, nil
+}
+
+// 上传时中途停顿，超过默认读超时后恢复仍然成功
+func TestRestoreHandlerSlowUpload(t *testing.T) {
+	server, pause := startBackupServer(t)
+	snapshot, err := database.TempSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(filepath.Dir(snapshot))
+	content, err := os.ReadFile(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "nav.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	form.Close()
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/restore", &slowReader{data: body.Bytes(), pause: pause})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.ContentLength = int64(body.Len())
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !result.Success {
+		t.Errorf("恢复失败: %d %s", resp.StatusCode, result.ErrorMessage)
+	}
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+// 访客会话 cookie，HttpOnly，前端无法读取
+const guestCookieName = "nav_guest"
+
+func setGuestCookie(c *gin.Context, session types.GuestSession) error {
+	value, err := service.SignGuestSession(session)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     guestCookieName,
+		Value:    value,
+		Path:     basePath + "/",
+		Expires:  time.Unix(session.ExpiresAt, 0),
+		MaxAge:   int(time.Until(time.Unix(session.ExpiresAt, 0)).Seconds()),
+		HttpOnly: true,
+		Secure:   isHttps(c),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// 获取当前请求中有效的访客会话，通过邀请链接获得的会话同时返回允许访问的分类（为空表示不限制）
+func getGuestSession(c *gin.Context) ([]int, bool) {
+	value, err := c.Cookie(guestCookieName)
+	if err != nil || value == "" {
+		return nil, false
+	}
+	session, ok := service.ParseGuestSession(value)
+	if !ok {
+		return nil, false
+	}
+	if session.LinkId == "" {
+		return nil, true
+	}
+	// 链接被删除或过期后，由它签发的会话也随之失效
+	link, ok := service.GetActiveGuestLink(session.LinkId)
+	if !ok {
+		return nil, false
+	}
+	return link.CatelogIds, true
+}
+
+// 校验访客密码，通过后签发访客会话
+func VerifyGuestHandler(c *gin.Context) {
+	var input struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "errorMessage": "参数错误"})
+		return
+	}
+
+	// 没有设置访客密码时不需要验证
+	if !service.HasGuestPassword() {
+		c.JSON(200, gin.H{"success": true})
+		return
+	}
+
+	guardKey := service.LoginIpKey("guest", c.ClientIP())
+	if lockedFor := service.LoginLockedFor(guardKey); lockedFor > 0 {
+		loginLockedResponse(c, lockedFor)
+		return
+	}
+	if !service.VerifyGuestPassword(input.Password) {
+		service.RecordLoginFailure(guardKey)
+		c.JSON(200, gin.H{"success": false, "errorMessage": "密码错误"})
+		return
+	}
+	service.ResetLoginFailures(guardKey)
+	if err := setGuestCookie(c, service.NewGuestSession()); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "errorMessage": "创建访客会话失败"})
+		return
+	}
+	c.JSON(200, gin.H{"success": true})
+}
+
+// 更换访客会话密钥，所有访客需要重新输入密码
+func RotateGuestSecretHandler(c *gin.Context) {
+	if utils.DemoMode {
+		c.JSON(200, gin.H{
+			"success":      false,
+			"errorMessage": "演示模式不允许轮换密钥",
+		})
+		return
+	}
+	if err := service.RotateGuestSecret(); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "guest.rotate", "secret", "guest", nil, nil)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "已撤销所有访客会话",
+	})
+}
+
+// 访客邀请链接入口，校验通过后签发访客会话并跳转到首页
+func GuestLinkHandler(c *gin.Context) {
+	link, err := service.UseGuestLink(c.Param("token"))
+	if err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+	session := service.NewGuestSession()
+	session.LinkId = link.Id
+	// 会话不能比链接本身活得更久
+	if session.ExpiresAt > link.ExpiresAt {
+		session.ExpiresAt = link.ExpiresAt
+	}
+	if err := setGuestCookie(c, session); err != nil {
+		utils.CheckErr(err)
+		c.String(http.StatusInternalServerError, "创建访客会话失败")
+		return
+	}
+	c.Redirect(http.StatusFound, basePath+"/")
+}
+
+func GetGuestLinksHandler(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"success": true,
+		"data":    service.GetGuestLinks(),
+	})
+}
+
+func AddGuestLinkHandler(c *gin.Context) {
+	var data types.AddGuestLinkDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	if utils.DemoMode {
+		c.JSON(200, gin.H{
+			"success":      false,
+			"errorMessage": "演示模式不允许创建访客链接",
+		})
+		return
+	}
+	token, link, err := service.AddGuestLink(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "guestLink.create", "guestLink", link.Id, nil, link)
+	// 完整链接只在创建时返回这一次
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "创建访客链接成功",
+		"data": gin.H{
+			"link": link,
+			"path": basePath + "/g/" + token,
+		},
+	})
+}
+
+func DeleteGuestLinkHandler(c *gin.Context) {
+	before, _ := service.GetActiveGuestLink(c.Param("id"))
+	count, err := service.DeleteGuestLink(c.Param("id"))
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "访客链接不存在",
+		})
+		return
+	}
+	audit(c, "guestLink.delete", "guestLink", before.Id, before, nil)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "删除访客链接成功",
+	})
+}
@@ -1,23 +1,28 @@
 package handler
 
 import (
-	"crypto/sha256"
 	"encoding/base64"
-	"encoding/hex"
 	"net/http"
-	"net/url"
 	urlPkg "net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mereith/nav/database"
 	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/middleware"
 	"github.com/mereith/nav/service"
 	"github.com/mereith/nav/types"
 	"github.com/mereith/nav/utils"
 )
 
+// 部署在子路径下时的路径前缀，例如 /nav，根路径部署时为空
+var basePath string
+
+func SetBasePath(path string) {
+	basePath = path
+}
+
 func ExportToolsHandler(c *gin.Context) {
 	tools := service.GetAllTool()
 	c.JSON(200, gin.H{
@@ -39,7 +44,15 @@ func ImportToolsHandler(c *gin.Context) {
 		return
 	}
 	// 导入所有工具
-	service.ImportTools(tools)
+	if err := service.ImportTools(tools, actorOf(c)); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "tool.import", "tool", nil, nil, gin.H{"count": len(tools)})
 	c.JSON(200, gin.H{
 		"success": true,
 		"message": "导入工具成功",
@@ -49,17 +62,18 @@ func ImportToolsHandler(c *gin.Context) {
 func DeleteApiTokenHandler(c *gin.Context) {
 	// 删除 Token
 	id := c.Param("id")
-	sql_delete_api_token := `
-		UPDATE nav_api_token
-		SET disabled = 1
-		WHERE id = ?;
-		`
-	stmt, err := database.DB.Prepare(sql_delete_api_token)
-	utils.CheckErr(err)
-	res, err := stmt.Exec(id)
-	utils.CheckErr(err)
-	_, err = res.RowsAffected()
+	numberId, _ := strconv.Atoi(id)
+	before, ok := service.GetApiTokenById(numberId)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "API Token 不存在",
+		})
+		return
+	}
+	err := service.DeleteApiToken(numberId)
 	utils.CheckErr(err)
+	audit(c, "token.delete", "token", id, before, nil)
 	c.JSON(200, gin.H{
 		"success": true,
 		"message": "删除 API Token 成功",
@@ -77,30 +91,57 @@ func AddApiTokenHandler(c *gin.Context) {
 		})
 		return
 	}
+	scopes, err := service.NormalizeTokenScopes(token.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	if token.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "有效天数不能为负数",
+		})
+		return
+	}
+	var expiresAt int64
+	if token.ExpiresInDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, token.ExpiresInDays).Unix()
+	}
 	newId := utils.GenerateId()
-	var signedJwt string
-	signedJwt, err = utils.SignJWTForAPI(token.Name, newId)
+	value, prefix, hash, err := utils.GenerateApiToken()
 	if err != nil {
 		utils.CheckErr(err)
-		c.JSON(http.StatusBadRequest, gin.H{
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"success":      false,
 			"errorMessage": err.Error(),
 		})
 		return
 	}
-	service.AddApiTokenInDB(types.Token{
-		Name:     token.Name,
-		Value:    signedJwt,
-		Id:       newId,
-		Disabled: 0,
-	})
-	// 签名 jwt
+	newToken := types.Token{
+		Name:      token.Name,
+		Prefix:    prefix,
+		TokenHash: hash,
+		Id:        newId,
+		Disabled:  0,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().Unix(),
+	}
+	service.AddApiTokenInDB(newToken)
+	audit(c, "token.create", "token", newId, nil, newToken)
+	// 完整的 token 只在创建时返回这一次
 	c.JSON(200, gin.H{
 		"success": true,
 		"data": gin.H{
-			"id":    newId,
-			"Value": signedJwt,
-			"Name":  token.Name,
+			"id":        newId,
+			"Value":     value,
+			"Name":      token.Name,
+			"prefix":    prefix,
+			"scopes":    scopes,
+			"expiresAt": expiresAt,
 		},
 		"message": "添加 Token 成功",
 	})
@@ -118,7 +159,6 @@ func UpdateSettingHandler(c *gin.Context) {
 	}
 	if utils.DemoMode {
 		old := service.GetSetting()
-		print(old.GuestPassword, data.GuestPassword)
 		if old.GuestPassword != data.GuestPassword {
 			c.JSON(200, gin.H{
 				"success":      false,
@@ -127,7 +167,13 @@ func UpdateSettingHandler(c *gin.Context) {
 			return
 		}
 	}
-	logger.LogInfo("更新配置: %+v", data)
+	// 访客密码是明文，不能写入日志
+	logged := data
+	if logged.GuestPassword != "" {
+		logged.GuestPassword = "********"
+	}
+	logger.LogInfo("更新配置: %+v", logged)
+	before := service.GetSetting()
 	err := service.UpdateSetting(data)
 	if err != nil {
 		utils.CheckErr(err)
@@ -137,6 +183,12 @@ func UpdateSettingHandler(c *gin.Context) {
 		})
 		return
 	}
+	after := service.GetSetting()
+	// 读出的访客密码已脱敏，设置了新密码时也要体现为变化
+	if data.GuestPassword != "********" && data.GuestPassword != "" {
+		after.GuestPassword = "changed"
+	}
+	audit(c, "setting.update", "setting", before.Id, before, after)
 	c.JSON(200, gin.H{
 		"success": true,
 		"message": "更新配置成功",
@@ -160,99 +212,83 @@ func UpdateUserHandler(c *gin.Context) {
 		})
 		return
 	}
-	service.UpdateUser(data)
+	// 只能修改自己的账号，修改其他用户请使用 /api/admin/users/:id
+	data.Id = int64(c.GetInt("uid"))
+	before, _ := service.GetUserById(int(data.Id))
+	if err := service.UpdateUser(data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	after, _ := service.GetUserById(int(data.Id))
+	audit(c, "account.update", "user", data.Id, auditUserState(before, ""), auditUserState(after, data.Password))
 	c.JSON(200, gin.H{
 		"success": true,
 		"message": "更新用户成功",
 	})
 }
 
-func sha256Hash(s string) string {
-	h := sha256.New()
-	h.Write([]byte(s))
-	return hex.EncodeToString(h.Sum(nil))
+// 当前请求可以看到的工具和分类：未登录时过滤掉隐藏的工具和分类，邀请链接限制了分类时只保留这些分类。
+// 设置了访客密码时，需要有效的访客会话或已登录才能查看，否则 locked 为 true
+func visibleData(c *gin.Context) (tools []types.Tool, catelogs []types.Catelog, locked bool) {
+	isLogin := middleware.IsLogin(c)
+	allowedCates, isGuest := getGuestSession(c)
+	if service.HasGuestPassword() && !isLogin && !isGuest {
+		return []types.Tool{}, []types.Catelog{}, true
+	}
+	tools = service.GetAllTool()
+	// 获取全部数据
+	catelogs = service.GetAllCatelog()
+	if !isLogin {
+		// 过滤掉隐藏工具
+		tools = utils.FilterHideTools(tools, catelogs)
+		// 过滤掉隐藏分类
+		catelogs = utils.FilterHideCates(catelogs)
+		// 邀请链接限制了分类时只展示这些分类
+		if isGuest && len(allowedCates) > 0 {
+			tools, catelogs = utils.FilterAllowedCates(tools, catelogs, allowedCates)
+		}
+	}
+	return tools, catelogs, false
 }
 
 func GetAllHandler(c *gin.Context) {
 	setting := service.GetSetting()
 
-	// Check Guest Password
-	realPwd := service.GetRealGuestPassword()
-	isLocked := false
-	if realPwd != "" {
-		// Check cookie
-		cookie, err := c.Cookie("guest_authorized")
-		// Verify if cookie matches SHA256(realPwd)
-		if err != nil || cookie != sha256Hash(realPwd) {
-			isLocked = true
-		}
-	}
-
+	tools, catelogs, isLocked := visibleData(c)
 	if isLocked {
 		c.JSON(200, gin.H{
 			"success": true,
 			"data": gin.H{
-				"tools":    []types.Tool{},
-				"catelogs": []types.Catelog{},
-				"setting":  setting,
-				"locked":   true,
+				"tools":       []types.Tool{},
+				"catelogs":    []types.Catelog{},
+				"catelogTree": []types.CatelogNode{},
+				"setting":     setting,
+				"locked":      true,
 			},
 		})
 		return
 	}
-
-	tools := service.GetAllTool()
-	// 获取全部数据
-	catelogs := service.GetAllCatelog()
-	if !utils.IsLogin(c) {
-		// 过滤掉隐藏工具
-		tools = utils.FilterHideTools(tools, catelogs)
-	}
-	if !utils.IsLogin(c) {
-		// 过滤掉隐藏分类
-		catelogs = utils.FilterHideCates(catelogs)
+	// 按标签过滤，?tags=prod,oncall&match=all
+	if tags, matchAll := tagFilter(c); len(tags) > 0 {
+		tools = utils.FilterTags(tools, tags, matchAll)
 	}
 
+	// catelogs 按树的顺序平铺，catelogTree 为嵌套的分类树
 	c.JSON(200, gin.H{
 		"success": true,
 		"data": gin.H{
-			"tools":    tools,
-			"catelogs": catelogs,
-			"setting":  setting,
-			"locked":   false,
+			"tools":       tools,
+			"catelogs":    catelogs,
+			"catelogTree": utils.BuildCatelogTree(catelogs),
+			"setting":     setting,
+			"locked":      false,
 		},
 	})
 }
 
-func VerifyGuestHandler(c *gin.Context) {
-	var input struct {
-		Password string `json:"password"`
-	}
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "errorMessage": "参数错误"})
-		return
-	}
-
-	realPwd := service.GetRealGuestPassword()
-	if realPwd == "" {
-		// No password set, always success
-		// Set a dummy hash or clear it, strictly speaking no lock means no cookie needed,
-		// but to be consistent let's just return success without cookie or empty cookie.
-		// Actually if no password is set, the frontend/GetAllHandler won't check it.
-		c.JSON(200, gin.H{"success": true})
-		return
-	}
-
-	if input.Password == realPwd {
-		// Set SHA256 hash of the password as cookie
-		cookieVal := sha256Hash(realPwd)
-		c.SetCookie("guest_authorized", cookieVal, 3600*24*30, "/", "", false, false)
-		c.JSON(200, gin.H{"success": true})
-	} else {
-		c.JSON(200, gin.H{"success": false, "errorMessage": "密码错误"})
-	}
-}
-
 func GetLogoImgHandler(c *gin.Context) {
 	url := c.Query("url")
 	// Robust fix for unencoded input URLs: extract everything after "url="
@@ -308,24 +344,25 @@ func GetAdminAllDataHandler(c *gin.Context) {
 	tools := service.GetAllTool()
 	catelogs := service.GetAllCatelog()
 	setting := service.GetSetting()
-	tokens := service.GetApiTokens()
-	userId, ok := c.Get("uid")
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success":      false,
-			"errorMessage": "不存在该用户！",
-		})
-		return
+	// 只有 owner 才能看到 API Token 列表
+	tokens := []types.Token{}
+	if middleware.HasScope(c, types.ScopeTokensManage) {
+		tokens = service.GetApiTokens()
 	}
+	// 使用 API Token 访问时没有对应的用户，id 为空
+	userId, _ := c.Get("uid")
 	c.JSON(200, gin.H{
 		"success": true,
 		"data": gin.H{
 			"tools":    tools,
 			"catelogs": catelogs,
+			"tags":     service.GetAllTags(),
 			"setting":  setting,
 			"user": gin.H{
-				"name": c.GetString("username"),
-				"id":   userId,
+				"name":   c.GetString("username"),
+				"id":     userId,
+				"role":   c.GetString("role"),
+				"scopes": c.GetStringSlice("scopes"),
 			},
 			"tokens": tokens,
 		},
@@ -342,24 +379,65 @@ func LoginHandler(c *gin.Context) {
 		})
 		return
 	}
+	// 同时按来源 IP 和用户名计数，分别防止单点爆破和分布式撞库
+	guardKeys := []string{service.LoginIpKey("login", c.ClientIP()), service.LoginUserKey(data.Name)}
+	if lockedFor := service.LoginLockedFor(guardKeys...); lockedFor > 0 {
+		loginLockedResponse(c, lockedFor)
+		return
+	}
 	user := service.GetUser(data.Name)
 	if user.Name == "" {
+		utils.VerifyDummyPassword(data.Password)
+	}
+	// 不区分用户名不存在和密码错误，避免枚举用户名
+	if user.Name == "" || !utils.VerifyPassword(user.Password, data.Password) {
+		service.RecordLoginFailure(guardKeys...)
+		logger.LogInfo("登录失败: 用户 %s, IP %s", data.Name, c.ClientIP())
 		c.JSON(200, gin.H{
 			"success":      false,
-			"errorMessage": "用户名不存在",
+			"errorMessage": "用户名或密码错误",
 		})
 		return
 	}
-	if user.Password != data.Password {
-		c.JSON(200, gin.H{
+	// 开启两步验证的账号还需要验证码，密码正确但未填写验证码时提示前端进入第二步
+	if user.TotpEnabled {
+		if data.Code == "" {
+			c.JSON(200, gin.H{
+				"success":      false,
+				"errorMessage": "请输入两步验证码",
+				"data": gin.H{
+					"totpRequired": true,
+				},
+			})
+			return
+		}
+		if !service.VerifySecondFactor(user.Id, data.Code) {
+			service.RecordLoginFailure(guardKeys...)
+			logger.LogInfo("两步验证失败: 用户 %s, IP %s", data.Name, c.ClientIP())
+			c.JSON(200, gin.H{
+				"success":      false,
+				"errorMessage": "验证码错误",
+				"data": gin.H{
+					"totpRequired": true,
+				},
+			})
+			return
+		}
+	}
+	service.ResetLoginFailures(guardKeys...)
+	if !utils.IsPasswordHashed(user.Password) {
+		service.UpgradeUserPassword(user.Id, data.Password)
+	}
+	// 创建会话并生成 token
+	token, err := service.CreateSession(user, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"success":      false,
-			"errorMessage": "密码错误",
+			"errorMessage": "创建会话失败",
 		})
 		return
 	}
-	// 生成 token
-	token, err := utils.SignJWT(user)
-	utils.CheckErr(err)
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -372,8 +450,27 @@ func LoginHandler(c *gin.Context) {
 
 }
 
-// 退出登录
+// 失败次数过多被临时锁定
+func loginLockedResponse(c *gin.Context, lockedFor time.Duration) {
+	seconds := int(lockedFor.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"success":      false,
+		"errorMessage": "尝试次数过多，请 " + strconv.Itoa(seconds) + " 秒后再试",
+	})
+}
+
+// 退出登录，撤销当前会话
 func LogoutHandler(c *gin.Context) {
+	if middleware.IsLogin(c) {
+		if jti := c.GetString("jti"); jti != "" {
+			_, err := service.RevokeSession(jti)
+			utils.CheckErr(err)
+		}
+	}
 	c.JSON(200, gin.H{
 		"success": true,
 		"message": "登出成功",
@@ -393,7 +490,7 @@ func AddToolHandler(c *gin.Context) {
 	}
 
 	logger.LogInfo("%s 获取 logo: %s", data.Name, data.Logo)
-	id, err := service.AddTool(data)
+	id, err := service.AddTool(data, actorOf(c))
 	if err != nil {
 		utils.CheckErr(err)
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -402,6 +499,8 @@ func AddToolHandler(c *gin.Context) {
 		})
 		return
 	}
+	after, _ := service.GetToolById(int(id))
+	audit(c, "tool.create", "tool", id, nil, after)
 	if data.Logo == "" {
 		go service.LazyFetchLogo(data.Url, id)
 	}
@@ -414,32 +513,36 @@ func AddToolHandler(c *gin.Context) {
 func DeleteToolHandler(c *gin.Context) {
 	// 删除工具
 	id := c.Param("id")
-	sql_delete_tool := `
-		DELETE FROM nav_table WHERE id = ?;
-		`
-	stmt, err := database.DB.Prepare(sql_delete_tool)
-	utils.CheckErr(err)
-	res, err := stmt.Exec(id)
-	utils.CheckErr(err)
-	_, err = res.RowsAffected()
-	utils.CheckErr(err)
-	// 删除工具的 logo，如果有
 	numberId, err := strconv.Atoi(id)
-	utils.CheckErr(err)
-	url1 := service.GetToolLogoUrlById(numberId)
-	urlEncoded := url.QueryEscape(url1)
-	sql_delete_tool_img := `
-		DELETE FROM nav_img WHERE url = ?;
-		`
-	stmt, err = database.DB.Prepare(sql_delete_tool_img)
-	utils.CheckErr(err)
-	res, err = stmt.Exec(urlEncoded)
-	utils.CheckErr(err)
-	_, err = res.RowsAffected()
-	utils.CheckErr(err)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "无效的工具 id",
+		})
+		return
+	}
+	before, ok := service.GetToolById(numberId)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "工具不存在",
+		})
+		return
+	}
+	// 移入回收站，彻底删除时再清理 logo 缓存
+	err = service.DeleteTools([]int{numberId}, actorOf(c))
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "tool.delete", "tool", numberId, before, nil)
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "删除成功",
+		"message": "已移入回收站",
 	})
 }
 
@@ -454,7 +557,24 @@ func UpdateToolHandler(c *gin.Context) {
 		})
 		return
 	}
-	service.UpdateTool(data)
+	before, ok := service.GetToolById(data.Id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "工具不存在",
+		})
+		return
+	}
+	if err := service.UpdateTool(data, actorOf(c)); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	after, _ := service.GetToolById(data.Id)
+	audit(c, "tool.update", "tool", data.Id, before, after)
 	if data.Logo == "" {
 		logger.LogInfo("%s 获取 logo: %s", data.Name, data.Logo)
 		go service.LazyFetchLogo(data.Url, int64(data.Id))
@@ -477,7 +597,26 @@ func AddCatelogHandler(c *gin.Context) {
 		})
 		return
 	}
-	service.AddCatelog(data)
+	if data.ParentId != 0 {
+		if _, ok := service.GetCatelogById(data.ParentId); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success":      false,
+				"errorMessage": "上级分类不存在",
+			})
+			return
+		}
+	}
+	id, err := service.AddCatelog(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	if after, ok := service.GetCatelogById(int(id)); ok {
+		audit(c, "catelog.create", "catelog", after.Id, nil, after)
+	}
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -485,21 +624,61 @@ func AddCatelogHandler(c *gin.Context) {
 	})
 }
 
+// 删除分类。分类下还有工具时需要指定 mode：move 表示把工具移动到 target 指定的分类，cascade 表示工具一起移入回收站
 func DeleteCatelogHandler(c *gin.Context) {
-	// 删除分类
 	id := c.Param("id")
-	sql_delete_catelog := `
-		DELETE FROM nav_catelog WHERE id = ?;
-		`
-	stmt, err := database.DB.Prepare(sql_delete_catelog)
-	utils.CheckErr(err)
-	res, err := stmt.Exec(id)
-	utils.CheckErr(err)
-	_, err = res.RowsAffected()
-	utils.CheckErr(err)
+	numberId, _ := strconv.Atoi(id)
+	before, ok := service.GetCatelogById(numberId)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "分类不存在",
+		})
+		return
+	}
+	moveTo := 0
+	message := "分类及其中的工具已移入回收站"
+	switch c.Query("mode") {
+	case "move":
+		moveTo, _ = strconv.Atoi(c.Query("target"))
+		if _, ok = service.GetCatelogById(moveTo); !ok || moveTo == numberId {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success":      false,
+				"errorMessage": "请选择另一个分类来接收其中的工具",
+			})
+			return
+		}
+		message = "其中的工具已移动，分类已移入回收站"
+	case "cascade":
+	case "":
+		if service.CountCatelogTools(numberId) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success":      false,
+				"errorMessage": "分类下还有工具，请选择移动到其他分类或一起删除",
+			})
+			return
+		}
+		message = "分类已移入回收站"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "mode 只能是 move 或 cascade",
+		})
+		return
+	}
+	err := service.DeleteCatelog(numberId, moveTo, actorOf(c))
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "catelog.delete", "catelog", numberId, before, gin.H{"mode": c.Query("mode"), "target": moveTo})
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "删除分类成功",
+		"message": message,
 	})
 }
 
@@ -514,7 +693,17 @@ func UpdateCatelogHandler(c *gin.Context) {
 		})
 		return
 	}
+	before, ok := service.GetCatelogById(data.Id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "分类不存在",
+		})
+		return
+	}
 	service.UpdateCatelog(data)
+	after, _ := service.GetCatelogById(data.Id)
+	audit(c, "catelog.update", "catelog", data.Id, before, after)
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -522,6 +711,49 @@ func UpdateCatelogHandler(c *gin.Context) {
 	})
 }
 
+// 修改分类的上级分类，不能移动到它自己或它的子分类下
+func MoveCatelogHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var data types.MoveCatelogDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	before, ok := service.GetCatelogById(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "分类不存在",
+		})
+		return
+	}
+	if err := service.CheckCatelogParents(map[int]int{id: data.ParentId}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	if err := service.MoveCatelog(id, data.ParentId); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	after, _ := service.GetCatelogById(id)
+	audit(c, "catelog.move", "catelog", id, before, after)
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "移动分类成功",
+	})
+}
+
 func ManifastHanlder(c *gin.Context) {
 
 	setting := service.GetSetting()
@@ -557,9 +789,9 @@ func ManifastHanlder(c *gin.Context) {
 		"short_name":       title,
 		"name":             title,
 		"icons":            icons,
-		"start_url":        "/",
+		"start_url":        "./",
 		"display":          "standalone",
-		"scope":            "/",
+		"scope":            "./",
 		"theme_color":      "#000000",
 		"background_color": "#ffffff",
 	})
@@ -576,7 +808,7 @@ func UpdateToolsSortHandler(c *gin.Context) {
 		return
 	}
 
-	err := service.UpdateToolsSort(updates)
+	err := service.UpdateToolsSort(updates, actorOf(c))
 	if err != nil {
 		utils.CheckErr(err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -585,6 +817,7 @@ func UpdateToolsSortHandler(c *gin.Context) {
 		})
 		return
 	}
+	audit(c, "tool.sort", "tool", nil, nil, updates)
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -602,6 +835,19 @@ func UpdateCatelogsSortHandler(c *gin.Context) {
 		})
 		return
 	}
+	moves := make(map[int]int)
+	for _, update := range updates {
+		if update.ParentId != nil {
+			moves[update.Id] = *update.ParentId
+		}
+	}
+	if err := service.CheckCatelogParents(moves); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
 
 	err := service.UpdateCatelogsSort(updates)
 	if err != nil {
@@ -612,6 +858,7 @@ func UpdateCatelogsSortHandler(c *gin.Context) {
 		})
 		return
 	}
+	audit(c, "catelog.sort", "catelog", nil, nil, updates)
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -629,91 +876,33 @@ func BatchDeleteToolHandler(c *gin.Context) {
 		return
 	}
 
-	// 1. Collect Logo URLs first (Read operation)
-	var logoUrls []string
+	// 先取出要删除的工具用于审计
+	var deleted []types.Tool
 	for _, id := range ids {
-		url := service.GetToolLogoUrlById(id)
-		if url != "" {
-			logoUrls = append(logoUrls, url)
-		}
-	}
-
-	// 2. Start Transaction (Write operation)
-	tx, err := database.DB.Begin()
-	if err != nil {
-		utils.CheckErr(err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success":      false,
-			"errorMessage": "数据库忙，请稍后重试",
-		})
-		return
-	}
-
-	// Prepare statements
-	sql_delete_tool := "DELETE FROM nav_table WHERE id = ?;"
-	stmtTool, err := tx.Prepare(sql_delete_tool)
-	if err != nil {
-		tx.Rollback()
-		utils.CheckErr(err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success":      false,
-			"errorMessage": "准备删除语句失败",
-		})
-		return
-	}
-	defer stmtTool.Close()
-
-	sql_delete_img := "DELETE FROM nav_img WHERE url = ?;"
-	stmtImg, err := tx.Prepare(sql_delete_img)
-	if err != nil {
-		tx.Rollback()
-		utils.CheckErr(err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success":      false,
-			"errorMessage": "准备图片删除语句失败",
-		})
-		return
-	}
-	defer stmtImg.Close()
-
-	// Execute Deletes
-	for _, id := range ids {
-		_, err := stmtTool.Exec(id)
-		if err != nil {
-			tx.Rollback()
-			utils.CheckErr(err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success":      false,
-				"errorMessage": "删除工具失败: " + strconv.Itoa(id),
-			})
-			return
-		}
-	}
-
-	for _, url := range logoUrls {
-		if url == "" {
+		tool, ok := service.GetToolById(id)
+		if !ok {
 			continue
 		}
-		urlEncoded := urlPkg.QueryEscape(url)
-		_, err := stmtImg.Exec(urlEncoded)
-		if err != nil {
-			logger.LogError("Failed to delete image: %s, error: %s", url, err)
-		}
+		deleted = append(deleted, tool)
 	}
 
-	err = tx.Commit()
-	if err != nil {
+	// 在同一个事务里移入回收站
+	if err := service.DeleteTools(ids, actorOf(c)); err != nil {
 		utils.CheckErr(err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success":      false,
-			"errorMessage": "提交事务失败",
+			"errorMessage": "删除工具失败: " + err.Error(),
 		})
 		return
 	}
+	// 每个工具单独记录，方便按工具查询
+	for _, tool := range deleted {
+		audit(c, "tool.delete", "tool", tool.Id, tool, nil)
+	}
 
 	c.JSON(200, gin.H{
 		"success": true,
-		"message": "批量删除成功",
+		"message": "已移入回收站",
 	})
 }
 
@@ -722,6 +911,7 @@ func GetToolsPageHandler(c *gin.Context) {
 	pageSizeStr := c.DefaultQuery("size", "20")
 	keyword := c.Query("q")
 	catelog := c.Query("catelog")
+	tags, matchAll := tagFilter(c)
 
 	page, _ := strconv.Atoi(pageStr)
 	pageSize, _ := strconv.Atoi(pageSizeStr)
@@ -732,7 +922,14 @@ func GetToolsPageHandler(c *gin.Context) {
 		pageSize = 20
 	}
 
-	tools, total := service.GetToolsPage(page, pageSize, keyword, catelog)
+	tools, total := service.GetToolsPage(types.ToolQueryDto{
+		Page:     page,
+		PageSize: pageSize,
+		Keyword:  keyword,
+		Catelog:  catelog,
+		Tags:     tags,
+		MatchAll: matchAll,
+	})
 
 	c.JSON(200, gin.H{
 		"success": true,
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/utils"
+)
+
+// 保存登录流程中 state、nonce、verifier 的 cookie，只在回调路径下发送
+const oidcCookieName = "nav_oidc"
+
+func oidcCookiePath() string {
+	return basePath + "/api/oidc"
+}
+
+// 登录失败的具体原因只写入日志，不放进跳转地址，避免 IdP 和内部的错误信息留在浏览器地址栏和历史记录中
+const oidcErrorMessage = "单点登录失败"
+
+// 前端据此决定是否展示单点登录按钮
+func OIDCConfigHandler(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"success": true,
+		"data": gin.H{
+			"enabled": service.OIDCEnabled(),
+		},
+	})
+}
+
+// 跳转到 IdP 登录页
+func OIDCLoginHandler(c *gin.Context) {
+	state, nonce, verifier := service.NewOIDCLoginState()
+	authURL, err := service.OIDCAuthURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		utils.CheckErr(err)
+		oidcRedirectError(c, oidcErrorMessage)
+		return
+	}
+	// 回调是从 IdP 跳转回来的顶级导航，SameSite=Lax 时 cookie 仍会被带上
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    state + "." + nonce + "." + verifier,
+		Path:     oidcCookiePath(),
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   isHttps(c),
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// IdP 回调，校验通过后创建会话，把 token 放在 hash 中交给登录页
+func OIDCCallbackHandler(c *gin.Context) {
+	cookie, err := c.Cookie(oidcCookieName)
+	// 无论成功与否，这次流程的 cookie 都只能用一次
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcCookieName,
+		Path:     oidcCookiePath(),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHttps(c),
+		SameSite: http.SameSiteLaxMode,
+	})
+	if errMsg := c.Query("error"); errMsg != "" {
+		oidcRedirectError(c, errMsg+" "+c.Query("error_description"))
+		return
+	}
+	parts := strings.Split(cookie, ".")
+	if err != nil || len(parts) != 3 || parts[0] == "" || parts[0] != c.Query("state") {
+		oidcRedirectError(c, "登录状态已失效，请重新登录")
+		return
+	}
+	user, err := service.OIDCExchange(c.Request.Context(), c.Query("code"), parts[1], parts[2])
+	if err != nil {
+		utils.CheckErr(err)
+		oidcRedirectError(c, oidcErrorMessage)
+		return
+	}
+	token, err := service.CreateSession(user, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		logger.LogError("创建会话失败: %v", err)
+		oidcRedirectError(c, "创建会话失败")
+		return
+	}
+	logger.LogInfo("OIDC 登录成功: 用户 %s, IP %s", user.Name, c.ClientIP())
+	// 放在 hash 里，不会出现在服务端和代理的访问日志中
+	c.Redirect(http.StatusFound, basePath+"/login#token="+url.QueryEscape(token))
+}
+
+func oidcRedirectError(c *gin.Context, message string) {
+	c.Redirect(http.StatusFound, basePath+"/login#error="+url.QueryEscape(message))
+}
+
+func isHttps(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/types"
+)
+
+// 搜索工具，q 按空白拆分，每个词都需要匹配，结果按相关度排序并带有高亮的片段。
+// 只返回当前请求可以看到的工具，规则与 GetAllHandler 相同；limit 默认 20，最多 100
+func SearchHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	tools, _, locked := visibleData(c)
+	items := make([]types.ToolSearchResult, 0)
+	if !locked {
+		visible := make(map[int]bool)
+		for _, tool := range tools {
+			visible[tool.Id] = true
+		}
+		for _, result := range service.SearchTools(c.Query("q")) {
+			if visible[result.Tool.Id] {
+				items = append(items, result)
+			}
+			if len(items) == limit {
+				break
+			}
+		}
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"data": gin.H{
+			"items":  items,
+			"locked": locked,
+		},
+	})
+}
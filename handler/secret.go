@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/utils"
+)
+
+// 轮换 JWT 签名密钥，默认给旧密钥 24 小时宽限期
+func RotateJWTSecretHandler(c *gin.Context) {
+	var data struct {
+		GraceHours *int `json:"graceHours"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&data); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success":      false,
+				"errorMessage": err.Error(),
+			})
+			return
+		}
+	}
+	graceHours := 24
+	if data.GraceHours != nil {
+		graceHours = *data.GraceHours
+	}
+	if graceHours < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "宽限期不能为负数",
+		})
+		return
+	}
+	if utils.DemoMode {
+		c.JSON(200, gin.H{
+			"success":      false,
+			"errorMessage": "演示模式不允许轮换密钥",
+		})
+		return
+	}
+	err := service.RotateJWTSecret(time.Duration(graceHours) * time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "jwt.rotate", "secret", "jwt", nil, gin.H{"graceHours": graceHours})
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "密钥轮换成功",
+	})
+}
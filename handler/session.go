@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/utils"
+)
+
+// 获取所有有效的登录会话
+func GetSessionsHandler(c *gin.Context) {
+	sessions := service.GetActiveSessions(c.GetString("jti"))
+	c.JSON(200, gin.H{
+		"success": true,
+		"data":    sessions,
+	})
+}
+
+// 撤销指定会话
+func RevokeSessionHandler(c *gin.Context) {
+	id := c.Param("id")
+	count, err := service.RevokeSession(id)
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "会话不存在或已失效",
+		})
+		return
+	}
+	audit(c, "session.revoke", "session", id, nil, nil)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "撤销会话成功",
+	})
+}
+
+// 撤销所有会话，exceptCurrent=true 时保留当前会话
+func RevokeAllSessionsHandler(c *gin.Context) {
+	except := ""
+	if c.Query("exceptCurrent") == "true" {
+		except = c.GetString("jti")
+	}
+	count, err := service.RevokeAllSessions(except)
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "session.revokeAll", "session", nil, nil, gin.H{"count": count, "exceptCurrent": except != ""})
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "撤销会话成功",
+		"data": gin.H{
+			"count": count,
+		},
+	})
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/types"
+)
+
+// 按标签过滤的查询参数：tags 为逗号分隔的标签名称，match=all 时要求包含全部标签，默认包含任意一个即可
+func tagFilter(c *gin.Context) ([]string, bool) {
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(c.Query("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, c.Query("match") == "all"
+}
+
+func GetTagsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"success": true,
+		"data":    service.GetAllTags(),
+	})
+}
+
+func AddTagHandler(c *gin.Context) {
+	var data types.TagDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	id, err := service.AddTag(data.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	after, _ := service.GetTagById(id)
+	audit(c, "tag.create", "tag", id, nil, after)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "添加标签成功",
+		"data":    after,
+	})
+}
+
+// 标签改名
+func UpdateTagHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var data types.TagDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	before, ok := service.GetTagById(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "标签不存在",
+		})
+		return
+	}
+	if err := service.UpdateTag(id, data.Name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	after, _ := service.GetTagById(id)
+	audit(c, "tag.update", "tag", id, before, after)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "更新标签成功",
+	})
+}
+
+// 删除标签，工具本身不受影响
+func DeleteTagHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	before, ok := service.GetTagById(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "标签不存在",
+		})
+		return
+	}
+	if err := service.DeleteTag(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "tag.delete", "tag", id, before, nil)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "删除标签成功",
+	})
+}
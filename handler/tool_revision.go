@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/utils"
+)
+
+// 工具的历史版本，最新的在前。回收站中的工具也可以查看
+func GetToolRevisionsHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	revisions := service.GetToolRevisions(id)
+	if len(revisions) == 0 {
+		if _, ok := service.GetToolById(id); !ok {
+			if _, ok = service.GetTrashedTool(id); !ok {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success":      false,
+					"errorMessage": "工具不存在",
+				})
+				return
+			}
+		}
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"data":    revisions,
+	})
+}
+
+// 比较同一个工具的两个历史版本，from 和 to 为版本 id，返回从 from 到 to 变化的字段
+func DiffToolRevisionsHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	fromId, fromErr := strconv.ParseInt(c.Query("from"), 10, 64)
+	toId, toErr := strconv.ParseInt(c.Query("to"), 10, 64)
+	if fromErr != nil || toErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "请指定要比较的两个版本",
+		})
+		return
+	}
+	from, fromOk := service.GetToolRevision(id, fromId)
+	to, toOk := service.GetToolRevision(id, toId)
+	if !fromOk || !toOk {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "历史版本不存在",
+		})
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"data": gin.H{
+			"from":    from,
+			"to":      to,
+			"changes": service.AuditDiff(from.Tool, to.Tool),
+		},
+	})
+}
+
+// 把工具回滚到某个历史版本，回滚本身也会保存为新的版本
+func RollbackToolHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	revisionId, _ := strconv.ParseInt(c.Param("rev"), 10, 64)
+	before, ok := service.GetToolById(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "工具不存在",
+		})
+		return
+	}
+	if _, ok = service.GetToolRevision(id, revisionId); !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "历史版本不存在",
+		})
+		return
+	}
+	if err := service.RollbackTool(id, revisionId, actorOf(c)); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	after, _ := service.GetToolById(id)
+	audit(c, "tool.rollback", "tool", id, before, after)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "已恢复到该版本",
+	})
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+// 获取当前用户的两步验证状态
+func GetTotpStatusHandler(c *gin.Context) {
+	status, err := service.GetTotpStatus(c.GetInt("uid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// 生成两步验证密钥，返回 otpauth 链接和二维码
+func SetupTotpHandler(c *gin.Context) {
+	if utils.DemoMode {
+		c.JSON(200, gin.H{
+			"success":      false,
+			"errorMessage": "演示模式不允许开启两步验证",
+		})
+		return
+	}
+	user, ok := service.GetUserById(c.GetInt("uid"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "用户不存在",
+		})
+		return
+	}
+	setup, err := service.BeginTotpSetup(user)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "totp.setup", "user", user.Id, nil, nil)
+	c.JSON(200, gin.H{
+		"success": true,
+		"data":    setup,
+	})
+}
+
+// 输入验证器上的验证码确认开启，恢复码只返回这一次
+func EnableTotpHandler(c *gin.Context) {
+	var data types.TotpCodeDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	codes, err := service.EnableTotp(c.GetInt("uid"), data.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "totp.enable", "user", c.GetInt("uid"), gin.H{"totpEnabled": false}, gin.H{"totpEnabled": true})
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "已开启两步验证",
+		"data": gin.H{
+			"recoveryCodes": codes,
+		},
+	})
+}
+
+// 重新生成恢复码，需要先通过一次第二因素校验
+func RegenerateRecoveryCodesHandler(c *gin.Context) {
+	var data types.TotpCodeDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	uid := c.GetInt("uid")
+	if !service.VerifySecondFactor(uid, data.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "验证码错误",
+		})
+		return
+	}
+	codes, err := service.RegenerateRecoveryCodes(uid)
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "totp.recoveryCodes", "user", uid, nil, nil)
+	c.JSON(200, gin.H{
+		"success": true,
+		"data": gin.H{
+			"recoveryCodes": codes,
+		},
+	})
+}
+
+// 关闭自己的两步验证，需要同时提供密码和验证码
+func DisableTotpHandler(c *gin.Context) {
+	var data types.DisableTotpDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	uid := c.GetInt("uid")
+	user, ok := service.GetUserById(uid)
+	if !ok || !utils.VerifyPassword(user.Password, data.Password) || !service.VerifySecondFactor(uid, data.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "密码或验证码错误",
+		})
+		return
+	}
+	if err := service.DisableTotp(uid); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "totp.disable", "user", uid, gin.H{"totpEnabled": true}, gin.H{"totpEnabled": false})
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "已关闭两步验证",
+	})
+}
+
+// 管理员为丢失验证器的用户关闭两步验证
+func AdminDisableTotpHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "无效的用户 id",
+		})
+		return
+	}
+	if err := service.DisableTotp(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "totp.disable", "user", id, gin.H{"totpEnabled": true}, gin.H{"totpEnabled": false})
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "已关闭该用户的两步验证",
+	})
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/utils"
+)
+
+// 回收站中的工具和分类，最近删除的在前
+func GetTrashHandler(c *gin.Context) {
+	tools, catelogs := service.GetTrash()
+	c.JSON(200, gin.H{
+		"success": true,
+		"data": gin.H{
+			"tools":    tools,
+			"catelogs": catelogs,
+		},
+	})
+}
+
+func RestoreToolHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	tool, ok := service.GetTrashedTool(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "回收站中没有这个工具",
+		})
+		return
+	}
+	if err := service.RestoreTool(id, actorOf(c)); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "tool.restore", "tool", id, nil, tool)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "恢复成功",
+	})
+}
+
+func PurgeToolHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	tool, ok := service.GetTrashedTool(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "回收站中没有这个工具",
+		})
+		return
+	}
+	if err := service.PurgeTool(id); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "tool.purge", "tool", id, tool, nil)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "已彻底删除",
+	})
+}
+
+func RestoreCatelogHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	catelog, ok := service.GetTrashedCatelog(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "回收站中没有这个分类",
+		})
+		return
+	}
+	if err := service.RestoreCatelog(id, actorOf(c)); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "catelog.restore", "catelog", id, nil, catelog)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "恢复成功",
+	})
+}
+
+func PurgeCatelogHandler(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	catelog, ok := service.GetTrashedCatelog(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":      false,
+			"errorMessage": "回收站中没有这个分类",
+		})
+		return
+	}
+	if err := service.PurgeCatelog(id); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "catelog.purge", "catelog", id, catelog, nil)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "已彻底删除",
+	})
+}
+
+func EmptyTrashHandler(c *gin.Context) {
+	count, err := service.EmptyTrash()
+	// 部分分类无法删除时，其余内容已经删除，同样需要记录
+	if count > 0 || err == nil {
+		audit(c, "trash.empty", "trash", nil, nil, gin.H{"count": count})
+	}
+	if err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+			"data":         gin.H{"count": count},
+		})
+		return
+	}
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "已清空回收站",
+		"data":    gin.H{"count": count},
+	})
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+func GetUsersHandler(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"success": true,
+		"data":    service.GetUsers(),
+	})
+}
+
+func AddUserHandler(c *gin.Context) {
+	var data types.AddUserDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	if utils.DemoMode {
+		c.JSON(200, gin.H{
+			"success":      false,
+			"errorMessage": "演示模式不允许添加用户",
+		})
+		return
+	}
+	id, err := service.AddUser(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	after, _ := service.GetUserById(int(id))
+	audit(c, "user.create", "user", id, nil, auditUserState(after, data.Password))
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "添加用户成功",
+		"data": gin.H{
+			"id": id,
+		},
+	})
+}
+
+func AdminUpdateUserHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "无效的用户 id",
+		})
+		return
+	}
+	var data types.AdminUpdateUserDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	if utils.DemoMode {
+		c.JSON(200, gin.H{
+			"success":      false,
+			"errorMessage": "演示模式不允许修改用户",
+		})
+		return
+	}
+	before, _ := service.GetUserById(id)
+	if err := service.AdminUpdateUser(id, data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	after, _ := service.GetUserById(id)
+	audit(c, "user.update", "user", id, auditUserState(before, ""), auditUserState(after, data.Password))
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "更新用户成功",
+	})
+}
+
+// 关联或解除外部身份，关联后该身份通过 OIDC 或反向代理认证登录时使用这个用户
+func SetUserExternalIdentityHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "无效的用户 id",
+		})
+		return
+	}
+	var data types.UserExternalIdentityDto
+	if err := c.ShouldBindJSON(&data); err != nil {
+		utils.CheckErr(err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	if utils.DemoMode {
+		c.JSON(200, gin.H{
+			"success":      false,
+			"errorMessage": "演示模式不允许修改用户",
+		})
+		return
+	}
+	before, _ := service.GetUserById(id)
+	if err := service.SetUserExternalIdentity(id, data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	after, _ := service.GetUserById(id)
+	audit(c, "user.external", "user", id, auditUserState(before, ""), auditUserState(after, ""))
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "更新外部身份成功",
+	})
+}
+
+func DeleteUserHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": "无效的用户 id",
+		})
+		return
+	}
+	if utils.DemoMode {
+		c.JSON(200, gin.H{
+			"success":      false,
+			"errorMessage": "演示模式不允许删除用户",
+		})
+		return
+	}
+	before, _ := service.GetUserById(id)
+	if err := service.DeleteUser(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":      false,
+			"errorMessage": err.Error(),
+		})
+		return
+	}
+	audit(c, "user.delete", "user", id, auditUserState(before, ""), nil)
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "删除用户成功",
+	})
+}
@@ -68,3 +68,16 @@ func LogInfo(format string, args ...interface{}) {
 func LogError(format string, args ...interface{}) {
 	Logger.Error(format, args...)
 }
+
+// 根据名称设置日志级别，支持 info 和 error
+func SetLevel(name string) error {
+	switch name {
+	case "info":
+		Logger.level = InfoLevel
+	case "error":
+		Logger.level = ErrorLevel
+	default:
+		return fmt.Errorf("未知的日志级别: %s", name)
+	}
+	return nil
+}
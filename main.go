@@ -2,19 +2,20 @@ package main
 
 import (
 	"embed"
-	"flag"
-	"fmt"
 	"net/http"
 	"os"
 	"path"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/mereith/nav/config"
 	"github.com/mereith/nav/database"
 	"github.com/mereith/nav/handler"
 	"github.com/mereith/nav/logger"
 	"github.com/mereith/nav/middleware"
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/types"
 	"github.com/mereith/nav/utils"
 
 	"github.com/gin-contrib/gzip"
@@ -60,77 +61,213 @@ func BinaryFileSystem(data embed.FS, root string) *binaryFileSystem {
 	}
 }
 
-var port = flag.String("port", "6412", "指定监听端口")
-var demo = flag.Bool("demo", false, "demo模式")
-
 func main() {
-	flag.Parse()
-	utils.DemoMode = *demo
-	// 优先从环境变量获取
-	if envDemo := os.Getenv("NAV_DEMO"); envDemo != "" {
-		if val, err := strconv.ParseBool(envDemo); err == nil {
-			utils.DemoMode = val
-		}
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		panic(err)
+	}
+	if err = logger.SetLevel(cfg.LogLevel); err != nil {
+		panic(err)
 	}
+	database.Configure(cfg.DataDir, cfg.Database)
+	if runCommand(cfg, args) {
+		return
+	}
+	utils.DemoMode = cfg.Demo
 	logger.LogInfo("demo ? :%t", utils.DemoMode)
 	database.InitDB()
+	repository.Init(database.DB)
+	service.InitJWTSecret(cfg.JWTSecret)
+	service.InitGuestSecret()
+	service.SetLoginGuardConfig(service.LoginGuardConfig{
+		MaxAttempts: cfg.Login.MaxAttempts,
+		Lockout:     cfg.Login.Lockout,
+		MaxLockout:  cfg.Login.LockoutMax,
+	})
+	service.SetAuditRetention(time.Duration(cfg.Audit.RetentionDays) * 24 * time.Hour)
+	service.StartAuditPruner()
+	service.SetTrashRetention(time.Duration(cfg.Trash.RetentionDays) * 24 * time.Hour)
+	service.StartTrashPruner()
+	err = service.SetBackupConfig(service.BackupConfig{
+		Schedule: cfg.Backup.Schedule,
+		Compress: cfg.Backup.Compress,
+		Keep:     cfg.Backup.Keep,
+		MaxAge:   time.Duration(cfg.Backup.MaxAgeDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		panic(err)
+	}
+	service.StartBackupScheduler()
+	roleMapping, err := service.ParseRoleMapping(cfg.OIDC.RoleMapping)
+	if err != nil {
+		panic(err)
+	}
+	err = service.SetOIDCConfig(service.OIDCConfig{
+		Issuer:       cfg.OIDC.Issuer,
+		ClientId:     cfg.OIDC.ClientId,
+		ClientSecret: cfg.OIDC.ClientSecret,
+		RedirectURL:  cfg.OIDC.RedirectURL,
+		Scopes:       cfg.OIDC.Scopes,
+		UserClaim:    cfg.OIDC.UserClaim,
+		GroupsClaim:  cfg.OIDC.GroupsClaim,
+		RoleMapping:  roleMapping,
+		DefaultRole:  cfg.OIDC.DefaultRole,
+	})
+	if err != nil {
+		panic(err)
+	}
+	logger.LogInfo("OIDC ? :%t", service.OIDCEnabled())
+	proxies, err := service.ParseCIDRs(strings.Join(cfg.TrustedProxies, ","))
+	if err != nil {
+		panic(err)
+	}
+	proxyRoleMapping, err := service.ParseRoleMapping(cfg.ProxyAuth.RoleMapping)
+	if err != nil {
+		panic(err)
+	}
+	err = service.SetProxyAuthConfig(service.ProxyAuthConfig{
+		UserHeader:     cfg.ProxyAuth.UserHeader,
+		GroupsHeader:   cfg.ProxyAuth.GroupsHeader,
+		TrustedProxies: proxies,
+		RoleMapping:    proxyRoleMapping,
+		DefaultRole:    cfg.ProxyAuth.DefaultRole,
+	})
+	if err != nil {
+		panic(err)
+	}
+	logger.LogInfo("proxy auth ? :%t", service.ProxyAuthEnabled())
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.Default()
-	router.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedExtensions([]string{".png", ".jpg", ".jpeg", ".ico", ".svg"})))
+	router := gin.New()
+	router.Use(middleware.Logger(cfg.BasePath), gin.Recovery(), middleware.DatabaseGate())
+	// 配置了受信任代理时，只从这些代理的 X-Forwarded-For 中获取客户端 IP，防止伪造 IP 绕过登录限制
+	if len(proxies) > 0 {
+		proxyList := make([]string, 0, len(proxies))
+		for _, network := range proxies {
+			proxyList = append(proxyList, network.String())
+		}
+		if err := router.SetTrustedProxies(proxyList); err != nil {
+			panic(err)
+		}
+	}
+	// 备份下载和恢复上传需要调整连接的超时，gzip 包装后的 ResponseWriter 不支持
+	router.Use(gzip.Gzip(gzip.DefaultCompression,
+		gzip.WithExcludedExtensions([]string{".png", ".jpg", ".jpeg", ".ico", ".svg"}),
+		gzip.WithExcludedPaths([]string{cfg.BasePath + "/api/admin/backup", cfg.BasePath + "/api/admin/restore"}),
+	))
 	//router.Use(gzip.Gzip(gzip.DefaultCompression))
+	handler.SetBasePath(cfg.BasePath)
+	// 所有路由都挂在路径前缀下，方便部署在反向代理的子路径中
+	root := router.Group(cfg.BasePath)
 	// 嵌入文件夹
-	router.GET("/manifest.json", handler.ManifastHanlder)
-	router.Use(Serve("/", BinaryFileSystem(fs, "ui/build")))
-	api := router.Group("/api")
+	root.GET("/manifest.json", handler.ManifastHanlder)
+	// 访客邀请链接
+	root.GET("/g/:token", handler.GuestLinkHandler)
+	router.Use(Serve(cfg.BasePath, BinaryFileSystem(fs, "ui/build")))
+	api := root.Group("/api")
 	{
 		// 获取数据的路由
 		api.GET("/", handler.GetAllHandler)
+		api.GET("/search", handler.SearchHandler)
+		// OIDC 单点登录
+		api.GET("/oidc/config", handler.OIDCConfigHandler)
+		api.GET("/oidc/login", handler.OIDCLoginHandler)
+		api.GET("/oidc/callback", handler.OIDCCallbackHandler)
 		// 获取用户信息
 		api.POST("/guest/verify", handler.VerifyGuestHandler)
 
 		api.POST("/login", handler.LoginHandler)
 		api.GET("/logout", handler.LogoutHandler)
 		api.GET("/img", handler.GetLogoImgHandler)
+		api.GET("/health", handler.HealthHandler)
 		// 管理员用的
 		admin := api.Group("/admin")
 		admin.Use(middleware.JWTMiddleware())
 		{
-			admin.POST("/apiToken", handler.AddApiTokenHandler)
-			admin.DELETE("/apiToken/:id", handler.DeleteApiTokenHandler)
-			admin.GET("/all", handler.GetAdminAllDataHandler)
-			admin.GET("/tools", handler.GetToolsPageHandler)
+			account := middleware.RequireScope(types.ScopeAccount)
+			tokensManage := middleware.RequireScope(types.ScopeTokensManage)
+			usersManage := middleware.RequireScope(types.ScopeUsersManage)
+			toolsRead := middleware.RequireScope(types.ScopeToolsRead)
+			toolsWrite := middleware.RequireScope(types.ScopeToolsWrite)
+			catelogsWrite := middleware.RequireScope(types.ScopeCatelogsWrite)
+
+			admin.POST("/apiToken", tokensManage, handler.AddApiTokenHandler)
+			admin.DELETE("/apiToken/:id", tokensManage, handler.DeleteApiTokenHandler)
+			admin.GET("/all", toolsRead, handler.GetAdminAllDataHandler)
+			admin.GET("/tools", toolsRead, handler.GetToolsPageHandler)
+			admin.GET("/tags", toolsRead, handler.GetTagsHandler)
+			admin.POST("/tag", toolsWrite, handler.AddTagHandler)
+			admin.PUT("/tag/:id", toolsWrite, handler.UpdateTagHandler)
+			admin.DELETE("/tag/:id", toolsWrite, handler.DeleteTagHandler)
+
+			admin.GET("/exportTools", toolsRead, handler.ExportToolsHandler)
+
+			admin.POST("/importTools", middleware.RequireScope(types.ScopeImport), handler.ImportToolsHandler)
+
+			admin.PUT("/user", account, handler.UpdateUserHandler)
+			// 两步验证
+			admin.GET("/totp", account, handler.GetTotpStatusHandler)
+			admin.POST("/totp/setup", account, handler.SetupTotpHandler)
+			admin.POST("/totp/enable", account, handler.EnableTotpHandler)
+			admin.POST("/totp/disable", account, handler.DisableTotpHandler)
+			admin.POST("/totp/recoveryCodes", account, handler.RegenerateRecoveryCodesHandler)
+			admin.POST("/jwt/rotate", usersManage, handler.RotateJWTSecretHandler)
+			settingsWrite := middleware.RequireScope(types.ScopeSettingsWrite)
+			admin.POST("/guest/rotate", settingsWrite, handler.RotateGuestSecretHandler)
+			admin.GET("/guestLinks", settingsWrite, handler.GetGuestLinksHandler)
+			admin.POST("/guestLinks", settingsWrite, handler.AddGuestLinkHandler)
+			admin.DELETE("/guestLinks/:id", settingsWrite, handler.DeleteGuestLinkHandler)
 
-			admin.GET("/exportTools", handler.ExportToolsHandler)
+			admin.GET("/users", usersManage, handler.GetUsersHandler)
+			admin.POST("/users", usersManage, handler.AddUserHandler)
+			admin.PUT("/users/:id", usersManage, handler.AdminUpdateUserHandler)
+			admin.DELETE("/users/:id", usersManage, handler.DeleteUserHandler)
+			admin.DELETE("/users/:id/totp", usersManage, handler.AdminDisableTotpHandler)
+			admin.PUT("/users/:id/external", usersManage, handler.SetUserExternalIdentityHandler)
 
-			admin.POST("/importTools", handler.ImportToolsHandler)
+			admin.GET("/sessions", usersManage, handler.GetSessionsHandler)
+			admin.DELETE("/sessions", usersManage, handler.RevokeAllSessionsHandler)
+			admin.DELETE("/session/:id", usersManage, handler.RevokeSessionHandler)
 
-			admin.PUT("/user", handler.UpdateUserHandler)
+			admin.PUT("/setting", settingsWrite, handler.UpdateSettingHandler)
+			admin.GET("/audit", middleware.RequireScope(types.ScopeAuditRead), handler.GetAuditHandler)
+			admin.GET("/backup", middleware.RequireScope(types.ScopeBackup), handler.BackupHandler)
+			admin.GET("/backup/status", middleware.RequireScope(types.ScopeBackup), handler.GetBackupStatusHandler)
+			admin.POST("/backup/run", middleware.RequireScope(types.ScopeBackup), handler.RunBackupHandler)
+			admin.POST("/restore", middleware.RequireScope(types.ScopeBackup), handler.RestoreHandler)
 
-			admin.PUT("/setting", handler.UpdateSettingHandler)
+			admin.POST("/tool", toolsWrite, handler.AddToolHandler)
+			admin.POST("/tools/batch-delete", toolsWrite, handler.BatchDeleteToolHandler)
+			admin.DELETE("/tool/:id", toolsWrite, handler.DeleteToolHandler)
+			admin.PUT("/tool/:id", toolsWrite, handler.UpdateToolHandler)
+			admin.PUT("/tools/sort", toolsWrite, handler.UpdateToolsSortHandler)
+			admin.GET("/tool/:id/revisions", toolsRead, handler.GetToolRevisionsHandler)
+			admin.GET("/tool/:id/revisions/diff", toolsRead, handler.DiffToolRevisionsHandler)
+			admin.POST("/tool/:id/revisions/:rev/restore", toolsWrite, handler.RollbackToolHandler)
 
-			admin.POST("/tool", handler.AddToolHandler)
-			admin.POST("/tools/batch-delete", handler.BatchDeleteToolHandler)
-			admin.DELETE("/tool/:id", handler.DeleteToolHandler)
-			admin.PUT("/tool/:id", handler.UpdateToolHandler)
-			admin.PUT("/tools/sort", handler.UpdateToolsSortHandler)
+			admin.POST("/catelog", catelogsWrite, handler.AddCatelogHandler)
+			admin.DELETE("/catelog/:id", catelogsWrite, handler.DeleteCatelogHandler)
+			admin.PUT("/catelog/:id", catelogsWrite, handler.UpdateCatelogHandler)
+			admin.PUT("/catelog/:id/move", catelogsWrite, handler.MoveCatelogHandler)
+			admin.PUT("/catelogs/sort", catelogsWrite, handler.UpdateCatelogsSortHandler)
 
-			admin.POST("/catelog", handler.AddCatelogHandler)
-			admin.DELETE("/catelog/:id", handler.DeleteCatelogHandler)
-			admin.PUT("/catelog/:id", handler.UpdateCatelogHandler)
-			admin.PUT("/catelogs/sort", handler.UpdateCatelogsSortHandler)
+			admin.GET("/trash", toolsRead, handler.GetTrashHandler)
+			admin.DELETE("/trash", toolsWrite, catelogsWrite, handler.EmptyTrashHandler)
+			admin.POST("/trash/tool/:id/restore", toolsWrite, handler.RestoreToolHandler)
+			admin.DELETE("/trash/tool/:id", toolsWrite, handler.PurgeToolHandler)
+			admin.POST("/trash/catelog/:id/restore", catelogsWrite, handler.RestoreCatelogHandler)
+			admin.DELETE("/trash/catelog/:id", catelogsWrite, handler.PurgeCatelogHandler)
 		}
 	}
-	logger.LogInfo("应用启动成功，网址: http://localhost:%s", *port)
-	listen := fmt.Sprintf(":%s", *port)
+	logger.LogInfo("应用启动成功，监听地址: %s%s/", cfg.Listen, cfg.BasePath)
 	srv := &http.Server{
-		Addr:         listen,
+		Addr:         cfg.Listen,
 		Handler:      router,
-		ReadTimeout:  3 * time.Second, // 可根据实际需要调整
-		WriteTimeout: 3 * time.Second, // 可根据实际需要调整
-		IdleTimeout:  3 * time.Second, // 建议设置为 10s 或更短
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
 	}
 
-	err := srv.ListenAndServe()
+	err = srv.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		logger.LogError("应用启动失败，错误: %s", err)
 	}
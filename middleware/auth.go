@@ -5,15 +5,15 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
-	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/service"
+	"github.com/mereith/nav/types"
 	"github.com/mereith/nav/utils"
 )
 
 // 定义一个 JWT 的中间件, 除了校验 jtw，还要校验之前签发的 api token 只要一样就放行。
 func JWTMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		rawToken := c.Request.Header.Get("Authorization")
-		if rawToken == "" {
+		if !authenticate(c) {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success":      false,
 				"errorMessage": "未登录",
@@ -21,36 +21,100 @@ func JWTMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		if database.HasApiToken(rawToken) {
-			c.Set("username", "apiToken")
-			c.Set("uid", 1)
-			c.Next()
-			return
-		}
+// 判断当前请求是否已登录，用于公开接口决定是否展示隐藏内容
+func IsLogin(c *gin.Context) bool {
+	return authenticate(c)
+}
 
-		// 解析 token
-		token, err := utils.ParseJWT(rawToken)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success":      false,
-				"errorMessage": "未登录",
-			})
-			c.Abort()
-			return
-		}
-		// 把名称加到上下文
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			c.Set("username", claims["name"])
-			c.Set("uid", claims["id"])
-			c.Next()
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{
+// 校验请求携带的凭证，成功时把身份信息写入上下文
+func authenticate(c *gin.Context) bool {
+	rawToken := c.Request.Header.Get("Authorization")
+	if rawToken != "" && authenticateToken(c, rawToken) {
+		return true
+	}
+	return authenticateProxy(c)
+}
+
+// 受信任的反向代理已经完成认证时，直接使用它传来的身份头
+func authenticateProxy(c *gin.Context) bool {
+	user, ok := service.ProxyAuthUser(c.Request.RemoteAddr, c.Request.Header)
+	if !ok {
+		return false
+	}
+	setUser(c, user)
+	return true
+}
+
+func setUser(c *gin.Context, user types.User) {
+	c.Set("username", user.Name)
+	c.Set("uid", user.Id)
+	c.Set("role", user.Role)
+	c.Set("scopes", types.RoleScopes[user.Role])
+}
+
+// 校验 API Token 或登录 JWT
+func authenticateToken(c *gin.Context, rawToken string) bool {
+	if apiToken, ok := service.GetActiveApiToken(rawToken); ok {
+		service.TouchApiToken(apiToken.Id, c.ClientIP())
+		// API Token 不属于任何用户，不设置 uid，操作者按 tokenId 和 tokenName 记录
+		c.Set("username", "apiToken")
+		c.Set("tokenId", apiToken.Id)
+		c.Set("tokenName", apiToken.Name)
+		c.Set("scopes", apiToken.Scopes)
+		return true
+	}
+
+	// 解析 token
+	token, err := utils.ParseJWT(rawToken)
+	if err != nil || !token.Valid {
+		return false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return false
+	}
+	// 会话被撤销（登出或后台踢出）后 token 立即失效
+	jti, _ := claims["jti"].(string)
+	if jti == "" || !service.IsSessionActive(jti) {
+		return false
+	}
+	// 每次都从数据库读取用户，角色变更或用户被删除后立即生效
+	uid, _ := claims["id"].(float64)
+	user, ok := service.GetUserById(int(uid))
+	if !ok {
+		return false
+	}
+	// 把名称加到上下文
+	setUser(c, user)
+	c.Set("jti", jti)
+	return true
+}
+
+// 要求当前请求拥有指定的权限范围，需放在 JWTMiddleware 之后
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasScope(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{
 				"success":      false,
-				"errorMessage": "未登录",
+				"errorMessage": "权限不足，需要 " + scope,
 			})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
+
+// 判断当前请求是否拥有指定的权限范围
+func HasScope(c *gin.Context, scope string) bool {
+	scopes, ok := c.Get("scopes")
+	if !ok {
+		return false
 	}
+	list, ok := scopes.([]string)
+	return ok && utils.In(scope, list)
 }
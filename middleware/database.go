@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mereith/nav/database"
+)
+
+const databaseReleaseKey = "databaseRelease"
+
+// 请求处理期间持有数据库的读锁，恢复数据库时会等进行中的请求结束，替换完成前新的请求会等待
+func DatabaseGate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var once sync.Once
+		release := database.Acquire()
+		unlock := func() {
+			once.Do(release)
+		}
+		c.Set(databaseReleaseKey, unlock)
+		defer unlock()
+		c.Next()
+	}
+}
+
+// 提前释放当前请求持有的读锁，需要独占数据库的请求（恢复备份）在此之后才能调用 database.Exclusive
+func ReleaseDatabase(c *gin.Context) {
+	if unlock, ok := c.Get(databaseReleaseKey); ok {
+		unlock.(func())()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 访问日志，格式与 gin 默认的相同，但访客邀请链接 /g/<token> 中的 token 会被隐去，
+// 否则任何能读到日志的人都可以用它获得访客会话
+func Logger(basePath string) gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			maskLogPath(basePath, param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// 隐去路径中的访客链接 token，保留查询参数
+func maskLogPath(basePath string, path string) string {
+	prefix := basePath + "/g/"
+	if !strings.HasPrefix(path, prefix) {
+		return path
+	}
+	rest := path[len(prefix):]
+	if rest == "" {
+		return path
+	}
+	if i := strings.IndexAny(rest, "/?"); i >= 0 {
+		return prefix + "***" + rest[i:]
+	}
+	return prefix + "***"
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import "testing"
+
+func TestMaskLogPath(t *testing.T) {
+	tests := []struct {
+		basePath string
+		path     string
+		want     string
+	}{
+		{"", "/g/abcdef", "/g/***"},
+		{"", "/g/abcdef?from=qr", "/g/***?from=qr"},
+		{"", "/g/abcdef/", "/g/***/"},
+		{"", "/g/", "/g/"},
+		{"", "/api/search?q=g", "/api/search?q=g"},
+		{"", "/api/g/abcdef", "/api/g/abcdef"},
+		{"/nav", "/nav/g/abcdef", "/nav/g/***"},
+		{"/nav", "/g/abcdef", "/g/abcdef"},
+	}
+	for _, tt := range tests {
+		if got := maskLogPath(tt.basePath, tt.path); got != tt.want {
+			t.Errorf("maskLogPath(%q, %q) = %q, want %q", tt.basePath, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"encoding/json"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlAuditRepository struct {
+	db *database.Handle
+}
+
+func (r *sqlAuditRepository) Add(entry types.Audit) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO nav_audit (created_at, actor_type, actor_id, actor, action, target_type, target_id, changes, ip)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+		`, entry.CreatedAt, entry.ActorType, entry.ActorId, entry.Actor, entry.Action, entry.TargetType, entry.TargetId, string(changes), entry.Ip)
+	return err
+}
+
+func (r *sqlAuditRepository) Page(query types.AuditQueryDto) ([]types.Audit, int64, error) {
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+	filters := []struct {
+		column string
+		value  string
+	}{
+		{"actor", query.Actor},
+		{"action", query.Action},
+		{"target_type", query.TargetType},
+		{"target_id", query.TargetId},
+	}
+	for _, filter := range filters {
+		if filter.value != "" {
+			whereClause += " AND " + filter.column + " = ?"
+			args = append(args, filter.value)
+		}
+	}
+	if query.Since > 0 {
+		whereClause += " AND created_at >= ?"
+		args = append(args, query.Since)
+	}
+	if query.Until > 0 {
+		whereClause += " AND created_at < ?"
+		args = append(args, query.Until)
+	}
+
+	results := make([]types.Audit, 0)
+	var total int64
+	err := r.db.QueryRow("SELECT count(*) FROM nav_audit "+whereClause, args...).Scan(&total)
+	if err != nil {
+		return results, 0, err
+	}
+
+	dataSQL := "SELECT id, created_at, actor_type, actor_id, actor, action, target_type, target_id, changes, ip FROM nav_audit " + whereClause + " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, query.PageSize, (query.Page-1)*query.PageSize)
+	rows, err := r.db.Query(dataSQL, args...)
+	if err != nil {
+		return results, 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry types.Audit
+		var changes string
+		if err = rows.Scan(&entry.Id, &entry.CreatedAt, &entry.ActorType, &entry.ActorId, &entry.Actor, &entry.Action, &entry.TargetType, &entry.TargetId, &changes, &entry.Ip); err != nil {
+			return results, total, err
+		}
+		if err = json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+			entry.Changes = make(map[string]types.AuditChange)
+		}
+		results = append(results, entry)
+	}
+	return results, total, rows.Err()
+}
+
+func (r *sqlAuditRepository) Prune(before int64) (int64, error) {
+	res, err := r.db.Exec(`DELETE FROM nav_audit WHERE created_at < ?;`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
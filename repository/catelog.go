@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlCatelogRepository struct {
+	db *database.Handle
+}
+
+const sql_select_catelog = `
+		SELECT id,name,parent_id,sort,hide,deleted_at FROM nav_catelog `
+
+func scanCatelog(row scanner) (types.Catelog, error) {
+	var catelog types.Catelog
+	var hide sql.NullBool
+	var parentId, deletedAt sql.NullInt64
+	err := row.Scan(&catelog.Id, &catelog.Name, &parentId, &catelog.Sort, &hide, &deletedAt)
+	catelog.ParentId = int(parentId.Int64)
+	catelog.Hide = hide.Bool
+	catelog.DeletedAt = deletedAt.Int64
+	return catelog, err
+}
+
+func (r *sqlCatelogRepository) List() ([]types.Catelog, error) {
+	return r.list(sql_select_catelog + `WHERE deleted_at IS NULL ORDER BY sort;`)
+}
+
+func (r *sqlCatelogRepository) ListTrashed() ([]types.Catelog, error) {
+	return r.list(sql_select_catelog + `WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC;`)
+}
+
+func (r *sqlCatelogRepository) list(query string) ([]types.Catelog, error) {
+	results := make([]types.Catelog, 0)
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		catelog, err := scanCatelog(rows)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, catelog)
+	}
+	return results, rows.Err()
+}
+
+func (r *sqlCatelogRepository) Get(id int) (types.Catelog, bool, error) {
+	catelog, err := scanCatelog(r.db.QueryRow(sql_select_catelog+`WHERE id = ? AND deleted_at IS NULL;`, id))
+	ok, err := found(err)
+	return catelog, ok, err
+}
+
+func (r *sqlCatelogRepository) GetTrashed(id int) (types.Catelog, bool, error) {
+	catelog, err := scanCatelog(r.db.QueryRow(sql_select_catelog+`WHERE id = ? AND deleted_at IS NOT NULL;`, id))
+	ok, err := found(err)
+	return catelog, ok, err
+}
+
+func (r *sqlCatelogRepository) GetByName(name string) (types.Catelog, bool, error) {
+	catelog, err := scanCatelog(r.db.QueryRow(sql_select_catelog+`WHERE name = ? AND deleted_at IS NULL;`, name))
+	ok, err := found(err)
+	return catelog, ok, err
+}
+
+func (r *sqlCatelogRepository) Add(data types.AddCatelogDto) (int64, error) {
+	return r.db.InsertId(`
+		INSERT INTO nav_catelog (name,parent_id,sort,hide)
+		VALUES (?,?,?,?);
+		`, data.Name, nullId(data.ParentId), data.Sort, data.Hide)
+}
+
+func (r *sqlCatelogRepository) Update(data types.UpdateCatelogDto) error {
+	_, err := r.db.Exec(`
+		UPDATE nav_catelog
+		SET name = ?, sort = ?, hide = ?
+		WHERE id = ? AND deleted_at IS NULL;
+		`, data.Name, data.Sort, data.Hide, data.Id)
+	return err
+}
+
+func (r *sqlCatelogRepository) UpdateSort(updates []types.UpdateCatelogsSortDto) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		for _, update := range updates {
+			var err error
+			if update.ParentId == nil {
+				_, err = tx.Exec(`UPDATE nav_catelog SET sort = ? WHERE id = ?;`, update.Sort, update.Id)
+			} else {
+				_, err = tx.Exec(`UPDATE nav_catelog SET sort = ?, parent_id = ? WHERE id = ?;`, update.Sort, nullId(*update.ParentId), update.Id)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *sqlCatelogRepository) Move(id int, parentId int) error {
+	_, err := r.db.Exec(`UPDATE nav_catelog SET parent_id = ? WHERE id = ? AND deleted_at IS NULL;`, nullId(parentId), id)
+	return err
+}
+
+func (r *sqlCatelogRepository) Trash(id int, at int64, moveTo int) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		var exists int
+		err := tx.QueryRow(`SELECT 1 FROM nav_catelog WHERE id = ? AND deleted_at IS NULL;`, id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if moveTo != 0 {
+			_, err = tx.Exec(`UPDATE nav_table SET catelog_id = ? WHERE catelog_id = ? AND deleted_at IS NULL;`, moveTo, id)
+			if err != nil {
+				return err
+			}
+		}
+		// 子分类（包括回收站中的）提升到上一级，回收站中的分类不会再作为上级分类
+		_, err = tx.Exec(`UPDATE nav_catelog SET parent_id = (SELECT parent_id FROM nav_catelog WHERE id = ?) WHERE parent_id = ?;`, id, id)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(`UPDATE nav_catelog SET deleted_at = ? WHERE id = ?;`, at, id); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE nav_table SET deleted_at = ? WHERE catelog_id = ? AND deleted_at IS NULL;`, at, id)
+		return err
+	})
+}
+
+func (r *sqlCatelogRepository) Restore(id int) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		var name string
+		var deletedAt int64
+		err := tx.QueryRow(`SELECT name, deleted_at FROM nav_catelog WHERE id = ? AND deleted_at IS NOT NULL;`, id).Scan(&name, &deletedAt)
+		if err != nil {
+			return err
+		}
+		var target int
+		err = tx.QueryRow(`SELECT id FROM nav_catelog WHERE name = ? AND deleted_at IS NULL ORDER BY id LIMIT 1;`, name).Scan(&target)
+		if err == sql.ErrNoRows {
+			if _, err = tx.Exec(`UPDATE nav_catelog SET deleted_at = NULL WHERE id = ?;`, id); err != nil {
+				return err
+			}
+			// 上级分类已不存在时恢复为顶级分类
+			_, err = tx.Exec(`
+				UPDATE nav_catelog SET parent_id = NULL
+				WHERE id = ? AND parent_id IN (SELECT id FROM nav_catelog WHERE deleted_at IS NOT NULL);
+				`, id)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`UPDATE nav_table SET deleted_at = NULL WHERE catelog_id = ? AND deleted_at = ?;`, id, deletedAt)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		// 合并到同名分类：一起删除的工具恢复到该分类，回收站中其余引用这个分类的工具也改为引用该分类
+		_, err = tx.Exec(`UPDATE nav_table SET deleted_at = NULL, catelog_id = ? WHERE catelog_id = ? AND deleted_at = ?;`, target, id, deletedAt)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(`UPDATE nav_table SET catelog_id = ? WHERE catelog_id = ?;`, target, id); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM nav_catelog WHERE id = ?;`, id)
+		return err
+	})
+}
+
+// 分类下不能还有工具，否则违反外键约束
+func (r *sqlCatelogRepository) Delete(id int) error {
+	_, err := r.db.Exec(`DELETE FROM nav_catelog WHERE id = ?;`, id)
+	return err
+}
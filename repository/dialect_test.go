@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+// 只记录收到的 SQL 和参数个数的驱动，查询不返回任何行，写入影响一行
+type recordingConnector struct {
+	mu      sync.Mutex
+	queries []recordedQuery
+}
+
+type recordedQuery struct {
+	query string
+	args  int
+}
+
+func (c *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &recordingConn{connector: c}, nil
+}
+
+func (c *recordingConnector) Driver() driver.Driver {
+	return nil
+}
+
+func (c *recordingConnector) record(query string, args int) {
+	c.mu.Lock()
+	c.queries = append(c.queries, recordedQuery{query, args})
+	c.mu.Unlock()
+}
+
+func (c *recordingConnector) take() []recordedQuery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	queries := c.queries
+	c.queries = nil
+	return queries
+}
+
+type recordingConn struct {
+	connector *recordingConnector
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{connector: c.connector, query: query}, nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return recordingTx{}, nil
+}
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error {
+	return nil
+}
+
+func (recordingTx) Rollback() error {
+	return nil
+}
+
+type recordingStmt struct {
+	connector *recordingConnector
+	query     string
+}
+
+func (s *recordingStmt) Close() error {
+	return nil
+}
+
+// 不检查参数个数，交给测试比较
+func (s *recordingStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.connector.record(s.query, len(args))
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.connector.record(s.query, len(args))
+	return recordingRows{}, nil
+}
+
+type recordingRows struct{}
+
+func (recordingRows) Columns() []string {
+	return nil
+}
+
+func (recordingRows) Close() error {
+	return nil
+}
+
+func (recordingRows) Next([]driver.Value) error {
+	return io.EOF
+}
+
+var postgresPlaceholder = regexp.MustCompile(`\$(\d+)`)
+
+// 这些仓库的 SQL 在 PostgreSQL 下要改写为 $1、$2，编号与参数一一对应
+func TestSQLStoreRebindsPlaceholders(t *testing.T) {
+	connector := &recordingConnector{}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	store := NewSQLStore(&database.Handle{DB: db, Dialect: database.Postgres})
+
+	tests := []struct {
+		name string
+		call func()
+	}{
+		{"Sessions.Add", func() { store.Sessions.Add(types.Session{Id: "s", UserId: 1}) }},
+		{"Sessions.IsActive", func() { store.Sessions.IsActive("s", 1) }},
+		{"Sessions.ListActive", func() { store.Sessions.ListActive(1) }},
+		{"Sessions.Revoke", func() { store.Sessions.Revoke("s", 1) }},
+		{"Sessions.RevokeAll", func() { store.Sessions.RevokeAll("s", 1) }},
+		{"Sessions.DeleteExpired", func() { store.Sessions.DeleteExpired(1) }},
+		{"Totp.Get", func() { store.Totp.Get(1) }},
+		{"Totp.CountRecoveryCodes", func() { store.Totp.CountRecoveryCodes(1) }},
+		{"Totp.SetSecret", func() { store.Totp.SetSecret(1, "secret") }},
+		{"Totp.Enable", func() { store.Totp.Enable(1, 2, []string{"a", "b"}) }},
+		{"Totp.ReplaceRecoveryCodes", func() { store.Totp.ReplaceRecoveryCodes(1, []string{"a"}) }},
+		{"Totp.UseStep", func() { store.Totp.UseStep(1, 2) }},
+		{"Totp.UseRecoveryCode", func() { store.Totp.UseRecoveryCode(1, "a", 2) }},
+		{"Totp.Disable", func() { store.Totp.Disable(1) }},
+		{"LoginAttempts.Get", func() { store.LoginAttempts.Get("k") }},
+		{"LoginAttempts.Save", func() { store.LoginAttempts.Save(types.LoginAttempt{Key: "k", Failures: 1}) }},
+		{"LoginAttempts.Delete", func() { store.LoginAttempts.Delete("k") }},
+		{"Audit.Add", func() { store.Audit.Add(types.Audit{Action: "tool.add"}) }},
+		{"Audit.Page", func() {
+			store.Audit.Page(types.AuditQueryDto{Actor: "admin", Action: "tool.add", TargetType: "tool", TargetId: "1", Since: 1, Until: 2, Page: 2, PageSize: 10})
+		}},
+		{"Audit.Prune", func() { store.Audit.Prune(1) }},
+		{"GuestLinks.List", func() { store.GuestLinks.List() }},
+		{"GuestLinks.Get", func() { store.GuestLinks.Get("l") }},
+		{"GuestLinks.Add", func() { store.GuestLinks.Add(types.GuestLink{Id: "l", CatelogIds: []int{1, 2}}, "hash") }},
+		{"GuestLinks.Delete", func() { store.GuestLinks.Delete("l") }},
+		{"GuestLinks.Use", func() { store.GuestLinks.Use("hash", 1) }},
+		{"Secrets.Get", func() { store.Secrets.Get("jwt") }},
+		{"Secrets.Save", func() {
+			store.Secrets.Save(types.Secret{Name: "jwt", Value: "v"}, types.Secret{Name: "jwt_previous", Value: "p", ExpiresAt: 1}, types.Secret{Name: "old"})
+		}},
+		{"State.Get", func() { store.State.Get("backup") }},
+		{"State.Save", func() { store.State.Save("backup", "{}", 1) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.call()
+			queries := connector.take()
+			if len(queries) == 0 {
+				t.Fatal("没有执行任何 SQL")
+			}
+			for _, q := range queries {
+				if strings.Contains(q.query, "?") {
+					t.Errorf("占位符没有改写: %s", q.query)
+				}
+				highest := 0
+				for _, match := range postgresPlaceholder.FindAllStringSubmatch(q.query, -1) {
+					n, _ := strconv.Atoi(match[1])
+					highest = max(highest, n)
+				}
+				if highest != q.args {
+					t.Errorf("占位符到 $%d，参数 %d 个: %s", highest, q.args, q.query)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlGuestLinkRepository struct {
+	db *database.Handle
+}
+
+const sql_select_guest_link = `
+	SELECT id, name, prefix, catelog_ids, expires_at, max_uses, uses, created_at FROM nav_guest_link `
+
+func scanGuestLink(row scanner) (types.GuestLink, error) {
+	var link types.GuestLink
+	var name sql.NullString
+	var catelogIds string
+	err := row.Scan(&link.Id, &name, &link.Prefix, &catelogIds, &link.ExpiresAt, &link.MaxUses, &link.Uses, &link.CreatedAt)
+	link.Name = name.String
+	link.CatelogIds = make([]int, 0)
+	for _, item := range strings.Split(catelogIds, ",") {
+		if id, err := strconv.Atoi(item); err == nil {
+			link.CatelogIds = append(link.CatelogIds, id)
+		}
+	}
+	return link, err
+}
+
+func (r *sqlGuestLinkRepository) List() ([]types.GuestLink, error) {
+	results := make([]types.GuestLink, 0)
+	rows, err := r.db.Query(sql_select_guest_link + `ORDER BY created_at DESC;`)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		link, err := scanGuestLink(rows)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, link)
+	}
+	return results, rows.Err()
+}
+
+func (r *sqlGuestLinkRepository) Get(id string) (types.GuestLink, bool, error) {
+	link, err := scanGuestLink(r.db.QueryRow(sql_select_guest_link+`WHERE id = ?;`, id))
+	ok, err := found(err)
+	return link, ok, err
+}
+
+func (r *sqlGuestLinkRepository) Add(link types.GuestLink, tokenHash string) error {
+	catelogIds := make([]string, 0, len(link.CatelogIds))
+	for _, id := range link.CatelogIds {
+		catelogIds = append(catelogIds, strconv.Itoa(id))
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO nav_guest_link (id, name, prefix, token_hash, catelog_ids, expires_at, max_uses, uses, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?);
+		`, link.Id, link.Name, link.Prefix, tokenHash, strings.Join(catelogIds, ","), link.ExpiresAt, link.MaxUses, link.CreatedAt)
+	return err
+}
+
+func (r *sqlGuestLinkRepository) Delete(id string) (int64, error) {
+	res, err := r.db.Exec(`DELETE FROM nav_guest_link WHERE id = ?;`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *sqlGuestLinkRepository) Use(tokenHash string, now int64) (types.GuestLink, bool, error) {
+	res, err := r.db.Exec(`
+		UPDATE nav_guest_link SET uses = uses + 1
+		WHERE token_hash = ? AND expires_at > ? AND (max_uses = 0 OR uses < max_uses);
+		`, tokenHash, now)
+	if err != nil {
+		return types.GuestLink{}, false, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return types.GuestLink{}, false, nil
+	}
+	link, err := scanGuestLink(r.db.QueryRow(sql_select_guest_link+`WHERE token_hash = ?;`, tokenHash))
+	ok, err := found(err)
+	return link, ok, err
+}
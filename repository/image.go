@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlImageRepository struct {
+	db *database.Handle
+}
+
+func (r *sqlImageRepository) Get(url string) (types.Img, bool, error) {
+	var img types.Img
+	err := r.db.QueryRow(`SELECT id,url,value FROM nav_img WHERE url = ?;`, url).Scan(&img.Id, &img.Url, &img.Value)
+	ok, err := found(err)
+	return img, ok, err
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlLoginAttemptRepository struct {
+	db *database.Handle
+}
+
+func (r *sqlLoginAttemptRepository) Get(key string) (types.LoginAttempt, bool, error) {
+	attempt := types.LoginAttempt{Key: key}
+	err := r.db.QueryRow(`SELECT failures, last_failure_at, locked_until FROM nav_login_attempt WHERE key = ?;`, key).Scan(&attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+	ok, err := found(err)
+	return attempt, ok, err
+}
+
+func (r *sqlLoginAttemptRepository) Save(attempt types.LoginAttempt) error {
+	_, err := r.db.Exec(`
+		INSERT INTO nav_login_attempt (key, failures, last_failure_at, locked_until)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET failures = excluded.failures, last_failure_at = excluded.last_failure_at, locked_until = excluded.locked_until;
+		`, attempt.Key, attempt.Failures, attempt.LastFailureAt, attempt.LockedUntil)
+	return err
+}
+
+func (r *sqlLoginAttemptRepository) Delete(key string) error {
+	_, err := r.db.Exec(`DELETE FROM nav_login_attempt WHERE key = ?;`, key)
+	return err
+}
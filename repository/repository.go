@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+// 工具
+type ToolRepository interface {
+	List() ([]types.Tool, error)
+	// 分类下的全部工具，包括回收站中的
+	ListByCatelog(catelogId int) ([]types.Tool, error)
+	// Keyword 按空白拆分，每个词都需要在名称、描述、网址、分类或标签中出现，或者与名称、描述的拼音匹配，
+	// SQLite 使用全文索引并按相关度排序
+	Page(query types.ToolQueryDto) ([]types.Tool, int64, error)
+	// 按相关度排序的全部匹配的未删除工具，规则同 Page 的 Keyword
+	Search(keyword string) ([]types.ToolSearchResult, error)
+	Get(id int) (types.Tool, bool, error)
+	// 同时写入标签和拼音索引，不存在的标签自动创建；Update 的 Tags 为 nil 时不修改标签
+	Add(data types.AddToolDto) (int64, error)
+	Update(data types.UpdateToolDto) error
+	UpdateLogo(id int64, logo string) error
+	UpdateSort(updates []types.UpdateToolsSortDto) error
+	// 按 id 写入，已存在时覆盖，回收站中的工具会被恢复，拼音索引随之更新。Tags 为 nil 时不修改标签
+	Import(tools []types.Tool) error
+	// 回收站：List、Page、Get 只返回未删除的工具
+	Trash(ids []int, at int64) error
+	ListTrashed() ([]types.Tool, error)
+	GetTrashed(id int) (types.Tool, bool, error)
+	// 恢复到分类 catelogId
+	Restore(id int, catelogId int) error
+	// 彻底删除工具、它们的历史版本、标签关联、拼音索引和缓存的 logo
+	Delete(ids []int) error
+	// 彻底删除在 before 之前移入回收站的工具，返回删除的数量
+	Purge(before int64) (int, error)
+}
+
+// 工具的历史版本，只追加，随工具一起彻底删除
+type ToolRevisionRepository interface {
+	// 把这些工具当前的内容保存为新的版本
+	Record(ids []int, actor types.Actor, action string, at int64) error
+	// 最新的版本在前
+	List(toolId int) ([]types.ToolRevision, error)
+	Get(toolId int, id int64) (types.ToolRevision, bool, error)
+}
+
+// 工具标签，名称唯一
+type TagRepository interface {
+	// 按名称排序
+	List() ([]types.Tag, error)
+	Get(id int) (types.Tag, bool, error)
+	GetByName(name string) (types.Tag, bool, error)
+	Add(name string) (int64, error)
+	Update(id int, name string) error
+	// 删除标签以及它与工具的关联
+	Delete(id int) error
+}
+
+// 分类
+type CatelogRepository interface {
+	List() ([]types.Catelog, error)
+	Get(id int) (types.Catelog, bool, error)
+	GetByName(name string) (types.Catelog, bool, error)
+	Add(data types.AddCatelogDto) (int64, error)
+	Update(data types.UpdateCatelogDto) error
+	// 更新同级分类的顺序，指定了 ParentId 时同时修改上级分类
+	UpdateSort(updates []types.UpdateCatelogsSortDto) error
+	// 修改上级分类，parentId 为 0 表示顶级分类。调用方负责检查不会形成环
+	Move(id int, parentId int) error
+	// 把分类移入回收站，List、Get、GetByName 只返回未删除的分类，子分类提升到上一级。
+	// moveTo 不为 0 时先把其中的工具移动到该分类，否则工具一起移入回收站
+	Trash(id int, at int64, moveTo int) error
+	ListTrashed() ([]types.Catelog, error)
+	GetTrashed(id int) (types.Catelog, bool, error)
+	// 恢复分类和与它一起删除的工具，已有同名分类时合并到该分类
+	Restore(id int) error
+	// 彻底删除分类，其中的工具需要先删除
+	Delete(id int) error
+}
+
+// 站点设置，只有一行
+type SettingRepository interface {
+	// GuestPassword 为数据库中保存的哈希
+	Get() (types.Setting, error)
+	GuestPasswordHash() (string, error)
+	Update(data types.Setting) error
+}
+
+// 用户
+type UserRepository interface {
+	Get(id int) (types.User, bool, error)
+	GetByName(name string) (types.User, bool, error)
+	// 按关联的外部身份查找
+	GetByExternal(issuer string, subject string) (types.User, bool, error)
+	List() ([]types.User, error)
+	// 用户名是否已被 excludeId 以外的用户占用
+	NameTaken(name string, excludeId int) (bool, error)
+	CountByRole(role string) (int, error)
+	Add(name string, passwordHash string, role string) (int64, error)
+	// 添加关联了外部身份的用户
+	AddExternal(name string, passwordHash string, role string, issuer string, subject string) (int64, error)
+	Update(id int, name string, passwordHash string, role string) error
+	UpdateName(id int, name string) error
+	UpdatePassword(id int, passwordHash string) error
+	UpdateRole(id int, role string) error
+	// subject 为空表示解除关联
+	UpdateExternal(id int, issuer string, subject string) error
+	// 删除用户，同时撤销会话、删除恢复码
+	Delete(id int) error
+}
+
+// API Token，删除只是标记为 disabled
+type TokenRepository interface {
+	ListActive() ([]types.Token, error)
+	GetActive(id int) (types.Token, bool, error)
+	GetActiveByHash(hash string) (types.Token, bool, error)
+	Add(token types.Token) error
+	Touch(id int, ip string, at int64) error
+	Disable(id int) error
+}
+
+// 图片缓存，url 为 QueryEscape 之后的值
+type ImageRepository interface {
+	Get(url string) (types.Img, bool, error)
+}
+
+// 登录会话，撤销只是标记 revoked_at
+type SessionRepository interface {
+	Add(session types.Session) error
+	// 存在、在 now 时未过期且未被撤销
+	IsActive(id string, now int64) (bool, error)
+	// 在 now 时有效的全部会话，带上用户名，最新的在前
+	ListActive(now int64) ([]types.Session, error)
+	// 返回撤销的数量
+	Revoke(id string, at int64) (int64, error)
+	// 撤销 exceptId 以外的全部会话
+	RevokeAll(exceptId string, at int64) (int64, error)
+	DeleteExpired(before int64) error
+}
+
+// 两步验证的密钥保存在用户上，恢复码只保存哈希
+type TotpRepository interface {
+	// 用户不存在时 found 为 false
+	Get(userId int) (types.Totp, bool, error)
+	CountRecoveryCodes(userId int) (int, error)
+	// 保存待确认的密钥
+	SetSecret(userId int, secret string) error
+	// 开启两步验证，同时替换恢复码
+	Enable(userId int, step int64, codeHashes []string) error
+	ReplaceRecoveryCodes(userId int, codeHashes []string) error
+	// 条件更新，只有 step 大于已使用过的时间步时才成功，并发请求中同一验证码只有一个能成功
+	UseStep(userId int, step int64) (bool, error)
+	// 恢复码只能使用一次
+	UseRecoveryCode(userId int, codeHash string, at int64) (bool, error)
+	// 清除密钥和恢复码，用户不存在时返回 false
+	Disable(userId int) (bool, error)
+}
+
+// 登录失败记录
+type LoginAttemptRepository interface {
+	Get(key string) (types.LoginAttempt, bool, error)
+	// 已存在时覆盖
+	Save(attempt types.LoginAttempt) error
+	Delete(key string) error
+}
+
+// 审计日志，只追加不修改
+type AuditRepository interface {
+	Add(entry types.Audit) error
+	// 最新的在前
+	Page(query types.AuditQueryDto) ([]types.Audit, int64, error)
+	// 删除 before 之前的记录，返回删除的数量
+	Prune(before int64) (int64, error)
+}
+
+// 访客邀请链接，只保存 token 的哈希
+type GuestLinkRepository interface {
+	// 最新的在前
+	List() ([]types.GuestLink, error)
+	Get(id string) (types.GuestLink, bool, error)
+	Add(link types.GuestLink, tokenHash string) error
+	Delete(id string) (int64, error)
+	// 条件更新，在 now 时过期或次数用尽时 found 为 false，并发访问时不会超过最大使用次数
+	Use(tokenHash string, now int64) (types.GuestLink, bool, error)
+}
+
+// 服务端密钥
+type SecretRepository interface {
+	// 不检查是否过期
+	Get(name string) (types.Secret, bool, error)
+	// 在一个事务中保存，已存在时覆盖，Value 为空时删除
+	Save(secrets ...types.Secret) error
+}
+
+// 运行状态，value 为 JSON
+type StateRepository interface {
+	Get(name string) (string, bool, error)
+	// 已存在时覆盖
+	Save(name string, value string, at int64) error
+}
+
+// 一套存储实现
+type Store struct {
+	Tools         ToolRepository
+	ToolRevisions ToolRevisionRepository
+	Tags          TagRepository
+	Catelogs      CatelogRepository
+	Settings      SettingRepository
+	Users         UserRepository
+	Tokens        TokenRepository
+	Images        ImageRepository
+	Sessions      SessionRepository
+	Totp          TotpRepository
+	LoginAttempts LoginAttemptRepository
+	Audit         AuditRepository
+	GuestLinks    GuestLinkRepository
+	Secrets       SecretRepository
+	State         StateRepository
+}
+
+var store *Store
+
+// 使用指定的存储实现
+func Use(s *Store) {
+	store = s
+}
+
+// 按数据库连接创建 SQL 实现，SQLite 和 PostgreSQL 共用
+func Init(db *database.Handle) {
+	Use(NewSQLStore(db))
+}
+
+func Tools() ToolRepository {
+	return store.Tools
+}
+
+func ToolRevisions() ToolRevisionRepository {
+	return store.ToolRevisions
+}
+
+func Tags() TagRepository {
+	return store.Tags
+}
+
+func Catelogs() CatelogRepository {
+	return store.Catelogs
+}
+
+func Settings() SettingRepository {
+	return store.Settings
+}
+
+func Users() UserRepository {
+	return store.Users
+}
+
+func Tokens() TokenRepository {
+	return store.Tokens
+}
+
+func Images() ImageRepository {
+	return store.Images
+}
+
+func Sessions() SessionRepository {
+	return store.Sessions
+}
+
+func Totp() TotpRepository {
+	return store.Totp
+}
+
+func LoginAttempts() LoginAttemptRepository {
+	return store.LoginAttempts
+}
+
+func Audit() AuditRepository {
+	return store.Audit
+}
+
+func GuestLinks() GuestLinkRepository {
+	return store.GuestLinks
+}
+
+func Secrets() SecretRepository {
+	return store.Secrets
+}
+
+func State() StateRepository {
+	return store.State
+}
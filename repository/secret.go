@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlSecretRepository struct {
+	db *database.Handle
+}
+
+func (r *sqlSecretRepository) Get(name string) (types.Secret, bool, error) {
+	secret := types.Secret{Name: name}
+	var expiresAt sql.NullInt64
+	err := r.db.QueryRow(`SELECT value, created_at, expires_at FROM nav_secret WHERE name = ?;`, name).Scan(&secret.Value, &secret.CreatedAt, &expiresAt)
+	secret.ExpiresAt = expiresAt.Int64
+	ok, err := found(err)
+	return secret, ok, err
+}
+
+func (r *sqlSecretRepository) Save(secrets ...types.Secret) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		for _, secret := range secrets {
+			if secret.Value == "" {
+				if _, err := tx.Exec(`DELETE FROM nav_secret WHERE name = ?;`, secret.Name); err != nil {
+					return err
+				}
+				continue
+			}
+			var expiresAt interface{}
+			if secret.ExpiresAt > 0 {
+				expiresAt = secret.ExpiresAt
+			}
+			_, err := tx.Exec(`
+				INSERT INTO nav_secret (name, value, created_at, expires_at)
+				VALUES (?, ?, ?, ?)
+				ON CONFLICT(name) DO UPDATE SET value = excluded.value, created_at = excluded.created_at, expires_at = excluded.expires_at;
+				`, secret.Name, secret.Value, secret.CreatedAt, expiresAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
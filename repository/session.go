@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlSessionRepository struct {
+	db *database.Handle
+}
+
+func (r *sqlSessionRepository) Add(session types.Session) error {
+	_, err := r.db.Exec(`
+		INSERT INTO nav_session (id, user_id, ip, user_agent, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?);
+		`, session.Id, session.UserId, session.Ip, session.UserAgent, session.CreatedAt, session.ExpiresAt)
+	return err
+}
+
+func (r *sqlSessionRepository) IsActive(id string, now int64) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM nav_session
+		WHERE id = ? AND revoked_at IS NULL AND expires_at > ?;
+		`, id, now).Scan(&count)
+	return count > 0, err
+}
+
+func (r *sqlSessionRepository) ListActive(now int64) ([]types.Session, error) {
+	results := make([]types.Session, 0)
+	rows, err := r.db.Query(`
+		SELECT s.id, s.user_id, COALESCE(u.name, ''), COALESCE(s.ip, ''), COALESCE(s.user_agent, ''), s.created_at, s.expires_at
+		FROM nav_session s
+		LEFT JOIN nav_user u ON u.id = s.user_id
+		WHERE s.revoked_at IS NULL AND s.expires_at > ?
+		ORDER BY s.created_at DESC;
+		`, now)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var session types.Session
+		if err = rows.Scan(&session.Id, &session.UserId, &session.UserName, &session.Ip, &session.UserAgent, &session.CreatedAt, &session.ExpiresAt); err != nil {
+			return results, err
+		}
+		results = append(results, session)
+	}
+	return results, rows.Err()
+}
+
+func (r *sqlSessionRepository) Revoke(id string, at int64) (int64, error) {
+	res, err := r.db.Exec(`UPDATE nav_session SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL;`, at, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *sqlSessionRepository) RevokeAll(exceptId string, at int64) (int64, error) {
+	res, err := r.db.Exec(`UPDATE nav_session SET revoked_at = ? WHERE revoked_at IS NULL AND id != ?;`, at, exceptId)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *sqlSessionRepository) DeleteExpired(before int64) error {
+	_, err := r.db.Exec(`DELETE FROM nav_session WHERE expires_at < ?;`, before)
+	return err
+}
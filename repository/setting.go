@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlSettingRepository struct {
+	db *database.Handle
+}
+
+func (r *sqlSettingRepository) Get() (types.Setting, error) {
+	var setting types.Setting
+	var hideAdmin, hideGithub, jumpTargetBlank sql.NullBool
+	var customJS, customCSS, guestPassword sql.NullString
+	err := r.db.QueryRow(`
+		SELECT id,favicon,title,govRecord,logo192,logo512,hideAdmin,hideGithub,jumpTargetBlank,customJS,customCSS,guestPassword
+		FROM nav_setting
+		ORDER BY id ASC
+		LIMIT 1;
+		`).Scan(&setting.Id, &setting.Favicon, &setting.Title, &setting.GovRecord, &setting.Logo192, &setting.Logo512, &hideAdmin, &hideGithub, &jumpTargetBlank, &customJS, &customCSS, &guestPassword)
+	setting.HideAdmin = hideAdmin.Bool
+	setting.HideGithub = hideGithub.Bool
+	// 老版本没有这一列时默认新窗口打开
+	setting.JumpTargetBlank = !jumpTargetBlank.Valid || jumpTargetBlank.Bool
+	setting.CustomJS = customJS.String
+	setting.CustomCSS = customCSS.String
+	setting.GuestPassword = guestPassword.String
+	return setting, err
+}
+
+func (r *sqlSettingRepository) GuestPasswordHash() (string, error) {
+	var guestPassword sql.NullString
+	err := r.db.QueryRow(`SELECT guestPassword FROM nav_setting ORDER BY id ASC LIMIT 1;`).Scan(&guestPassword)
+	return guestPassword.String, err
+}
+
+func (r *sqlSettingRepository) Update(data types.Setting) error {
+	_, err := r.db.Exec(`
+		UPDATE nav_setting
+		SET favicon = ?, title = ?, govRecord = ?, logo192 = ?, logo512 = ?, hideAdmin = ?, hideGithub = ?, jumpTargetBlank = ?, customJS = ?, customCSS = ?, guestPassword = ?
+		WHERE id = (SELECT id FROM nav_setting ORDER BY id ASC LIMIT 1);
+		`, data.Favicon, data.Title, data.GovRecord, data.Logo192, data.Logo512, data.HideAdmin, data.HideGithub, data.JumpTargetBlank, data.CustomJS, data.CustomCSS, data.GuestPassword)
+	return err
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/mereith/nav/database"
+)
+
+// 基于 database/sql 的实现，占位符和自增 id 的差异由 database.Handle 处理
+func NewSQLStore(db *database.Handle) *Store {
+	return &Store{
+		Tools:         &sqlToolRepository{db: db},
+		ToolRevisions: &sqlToolRevisionRepository{db: db},
+		Tags:          &sqlTagRepository{db: db},
+		Catelogs:      &sqlCatelogRepository{db: db},
+		Settings:      &sqlSettingRepository{db: db},
+		Users:         &sqlUserRepository{db: db},
+		Tokens:        &sqlTokenRepository{db: db},
+		Images:        &sqlImageRepository{db: db},
+		Sessions:      &sqlSessionRepository{db: db},
+		Totp:          &sqlTotpRepository{db: db},
+		LoginAttempts: &sqlLoginAttemptRepository{db: db},
+		Audit:         &sqlAuditRepository{db: db},
+		GuestLinks:    &sqlGuestLinkRepository{db: db},
+		Secrets:       &sqlSecretRepository{db: db},
+		State:         &sqlStateRepository{db: db},
+	}
+}
+
+type scanner interface {
+	Scan(...interface{}) error
+}
+
+// 把查不到记录转换为 found = false
+func found(err error) (bool, error) {
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// 引用其他表的 id 为 0 时写入 NULL，避免违反外键约束
+func nullId(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// 在事务中执行，fn 返回错误时回滚
+func withTx(db *database.Handle, fn func(tx *database.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"github.com/mereith/nav/database"
+)
+
+type sqlStateRepository struct {
+	db *database.Handle
+}
+
+func (r *sqlStateRepository) Get(name string) (string, bool, error) {
+	var value string
+	err := r.db.QueryRow(`SELECT value FROM nav_state WHERE name = ?;`, name).Scan(&value)
+	ok, err := found(err)
+	return value, ok, err
+}
+
+func (r *sqlStateRepository) Save(name string, value string, at int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO nav_state (name, value, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at;
+		`, name, value, at)
+	return err
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlTagRepository struct {
+	db *database.Handle
+}
+
+const sql_select_tag = `
+		SELECT g.id, g.name, (
+			SELECT COUNT(*) FROM nav_tool_tag tt JOIN nav_table t ON t.id = tt.tool_id
+			WHERE tt.tag_id = g.id AND t.deleted_at IS NULL
+		) FROM nav_tag g `
+
+func scanTag(row scanner) (types.Tag, error) {
+	var tag types.Tag
+	err := row.Scan(&tag.Id, &tag.Name, &tag.ToolCount)
+	return tag, err
+}
+
+func (r *sqlTagRepository) List() ([]types.Tag, error) {
+	results := make([]types.Tag, 0)
+	rows, err := r.db.Query(sql_select_tag + `ORDER BY g.name;`)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, tag)
+	}
+	return results, rows.Err()
+}
+
+func (r *sqlTagRepository) Get(id int) (types.Tag, bool, error) {
+	tag, err := scanTag(r.db.QueryRow(sql_select_tag+`WHERE g.id = ?;`, id))
+	ok, err := found(err)
+	return tag, ok, err
+}
+
+func (r *sqlTagRepository) GetByName(name string) (types.Tag, bool, error) {
+	tag, err := scanTag(r.db.QueryRow(sql_select_tag+`WHERE g.name = ?;`, name))
+	ok, err := found(err)
+	return tag, ok, err
+}
+
+func (r *sqlTagRepository) Add(name string) (int64, error) {
+	return r.db.InsertId(`INSERT INTO nav_tag (name) VALUES (?);`, name)
+}
+
+func (r *sqlTagRepository) Update(id int, name string) error {
+	_, err := r.db.Exec(`UPDATE nav_tag SET name = ? WHERE id = ?;`, name, id)
+	return err
+}
+
+func (r *sqlTagRepository) Delete(id int) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM nav_tool_tag WHERE tag_id = ?;`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM nav_tag WHERE id = ?;`, id)
+		return err
+	})
+}
+
+// 把工具的标签替换为 names，不存在的标签自动创建
+func setToolTags(tx *database.Tx, toolId int, names []string) error {
+	if _, err := tx.Exec(`DELETE FROM nav_tool_tag WHERE tool_id = ?;`, toolId); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := tx.Exec(`INSERT INTO nav_tag (name) VALUES (?) ON CONFLICT (name) DO NOTHING;`, name); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT INTO nav_tool_tag (tool_id, tag_id)
+			SELECT ?, id FROM nav_tag WHERE name = ?
+			ON CONFLICT (tool_id, tag_id) DO NOTHING;
+			`, toolId, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 查出工具的标签填入 Tags，没有标签的工具为空数组
+func loadToolTags(db *database.Handle, tools []types.Tool) error {
+	if len(tools) == 0 {
+		return nil
+	}
+	index := make(map[int]int)
+	args := make([]interface{}, 0, len(tools))
+	for i := range tools {
+		tools[i].Tags = make([]string, 0)
+		index[tools[i].Id] = i
+		args = append(args, tools[i].Id)
+	}
+	rows, err := db.Query(`
+		SELECT tt.tool_id, g.name FROM nav_tool_tag tt JOIN nav_tag g ON g.id = tt.tag_id
+		WHERE tt.tool_id IN (?`+strings.Repeat(",?", len(args)-1)+`)
+		ORDER BY g.name;
+		`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var toolId int
+		var name string
+		if err = rows.Scan(&toolId, &name); err != nil {
+			return err
+		}
+		if i, ok := index[toolId]; ok {
+			tools[i].Tags = append(tools[i].Tags, name)
+		}
+	}
+	return rows.Err()
+}
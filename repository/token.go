@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlTokenRepository struct {
+	db *database.Handle
+}
+
+const sql_select_api_token = `
+		SELECT id,name,COALESCE(prefix,''),COALESCE(token_hash,''),disabled,COALESCE(scopes,''),COALESCE(expires_at,0),COALESCE(created_at,0),COALESCE(last_used_at,0),COALESCE(last_used_ip,'')
+		FROM nav_api_token
+		`
+
+func scanApiToken(row scanner) (types.Token, error) {
+	var token types.Token
+	var scopes string
+	err := row.Scan(&token.Id, &token.Name, &token.Prefix, &token.TokenHash, &token.Disabled, &scopes, &token.ExpiresAt, &token.CreatedAt, &token.LastUsedAt, &token.LastUsedIp)
+	token.Scopes = splitScopes(scopes)
+	return token, err
+}
+
+func splitScopes(scopes string) []string {
+	result := make([]string, 0)
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope != "" {
+			result = append(result, scope)
+		}
+	}
+	return result
+}
+
+func (r *sqlTokenRepository) ListActive() ([]types.Token, error) {
+	results := make([]types.Token, 0)
+	rows, err := r.db.Query(sql_select_api_token + `WHERE disabled = 0;`)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		token, err := scanApiToken(rows)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, token)
+	}
+	return results, rows.Err()
+}
+
+func (r *sqlTokenRepository) GetActive(id int) (types.Token, bool, error) {
+	token, err := scanApiToken(r.db.QueryRow(sql_select_api_token+`WHERE id = ? AND disabled = 0;`, id))
+	ok, err := found(err)
+	return token, ok, err
+}
+
+func (r *sqlTokenRepository) GetActiveByHash(hash string) (types.Token, bool, error) {
+	token, err := scanApiToken(r.db.QueryRow(sql_select_api_token+`WHERE token_hash = ? AND disabled = 0;`, hash))
+	ok, err := found(err)
+	return token, ok, err
+}
+
+func (r *sqlTokenRepository) Add(token types.Token) error {
+	var expiresAt interface{}
+	if token.ExpiresAt > 0 {
+		expiresAt = token.ExpiresAt
+	}
+	return withTx(r.db, func(tx *database.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO nav_api_token (id,name,value,prefix,token_hash,disabled,scopes,expires_at,created_at)
+			VALUES (?,?,'',?,?,?,?,?,?);
+			`, token.Id, token.Name, token.Prefix, token.TokenHash, token.Disabled, strings.Join(token.Scopes, ","), expiresAt, token.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return tx.SyncSequence("nav_api_token")
+	})
+}
+
+func (r *sqlTokenRepository) Touch(id int, ip string, at int64) error {
+	_, err := r.db.Exec(`UPDATE nav_api_token SET last_used_at = ?, last_used_ip = ? WHERE id = ?;`, at, ip, id)
+	return err
+}
+
+func (r *sqlTokenRepository) Disable(id int) error {
+	_, err := r.db.Exec(`UPDATE nav_api_token SET disabled = 1 WHERE id = ?;`, id)
+	return err
+}
@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlToolRepository struct {
+	db *database.Handle
+}
+
+// desc 在 PostgreSQL 中是保留字，统一加引号。分类名称通过 catelog_id 关联查出
+const sql_tool_columns = `t.id,t.name,t.url,t.logo,t.catelog_id,c.name,t."desc",t.sort,t.hide,t.deleted_at`
+
+const sql_from_tool = `
+		FROM nav_table t LEFT JOIN nav_catelog c ON c.id = t.catelog_id `
+
+const sql_select_tool = `
+		SELECT ` + sql_tool_columns + sql_from_tool
+
+func scanTool(row scanner) (types.Tool, error) {
+	var tool types.Tool
+	var catelogId, sort, deletedAt sql.NullInt64
+	var catelog sql.NullString
+	var hide sql.NullBool
+	err := row.Scan(&tool.Id, &tool.Name, &tool.Url, &tool.Logo, &catelogId, &catelog, &tool.Desc, &sort, &hide, &deletedAt)
+	tool.CatelogId = int(catelogId.Int64)
+	tool.Catelog = catelog.String
+	tool.Sort = int(sort.Int64)
+	tool.Hide = hide.Bool
+	tool.DeletedAt = deletedAt.Int64
+	return tool, err
+}
+
+func scanTools(rows *sql.Rows, err error) ([]types.Tool, error) {
+	results := make([]types.Tool, 0)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		tool, err := scanTool(rows)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, tool)
+	}
+	return results, rows.Err()
+}
+
+// 查询工具列表并填入标签
+func (r *sqlToolRepository) query(query string, args ...interface{}) ([]types.Tool, error) {
+	tools, err := scanTools(r.db.Query(query, args...))
+	if err != nil {
+		return tools, err
+	}
+	return tools, loadToolTags(r.db, tools)
+}
+
+// 查询一个工具并填入标签
+func (r *sqlToolRepository) get(query string, args ...interface{}) (types.Tool, bool, error) {
+	tool, err := scanTool(r.db.QueryRow(query, args...))
+	ok, err := found(err)
+	if !ok {
+		return tool, ok, err
+	}
+	tools := []types.Tool{tool}
+	err = loadToolTags(r.db, tools)
+	return tools[0], ok, err
+}
+
+func (r *sqlToolRepository) List() ([]types.Tool, error) {
+	return r.query(sql_select_tool + `WHERE t.deleted_at IS NULL ORDER BY t.sort;`)
+}
+
+func (r *sqlToolRepository) ListByCatelog(catelogId int) ([]types.Tool, error) {
+	return r.query(sql_select_tool+`WHERE t.catelog_id = ? ORDER BY t.sort;`, catelogId)
+}
+
+func (r *sqlToolRepository) Page(query types.ToolQueryDto) ([]types.Tool, int64, error) {
+	from := sql_from_tool
+	whereClause := "WHERE t.deleted_at IS NULL"
+	order := "t.sort"
+	args := []interface{}{}
+	if terms := searchTerms(query.Keyword); len(terms) > 0 {
+		if r.useFts(terms) {
+			// 使用全文索引，按相关度排序
+			from += `JOIN (SELECT rowid AS id, ` + sql_fts_rank + ` AS score FROM nav_tool_fts WHERE nav_tool_fts MATCH ?) f ON f.id = t.id `
+			args = append(args, ftsQuery(terms))
+			order = "f.score, t.sort"
+		} else {
+			clause, likeArgs := likeClause(terms, false)
+			whereClause += clause
+			args = append(args, likeArgs...)
+		}
+	}
+	if query.Catelog != "" {
+		whereClause += " AND c.name = ?"
+		args = append(args, query.Catelog)
+	}
+	if len(query.Tags) > 0 {
+		// 包含任意一个标签，或者包含的标签数量等于要求的标签数量
+		whereClause += ` AND t.id IN (
+			SELECT tt.tool_id FROM nav_tool_tag tt JOIN nav_tag g ON g.id = tt.tag_id
+			WHERE g.name IN (?` + strings.Repeat(",?", len(query.Tags)-1) + `)`
+		for _, tag := range query.Tags {
+			args = append(args, tag)
+		}
+		if query.MatchAll {
+			whereClause += ` GROUP BY tt.tool_id HAVING COUNT(DISTINCT g.id) = ?`
+			args = append(args, len(query.Tags))
+		}
+		whereClause += `)`
+	}
+	var total int64
+	err := r.db.QueryRow("SELECT count(*) "+from+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	args = append(args, query.PageSize, (query.Page-1)*query.PageSize)
+	tools, err := r.query("SELECT "+sql_tool_columns+from+whereClause+" ORDER BY "+order+" LIMIT ? OFFSET ?", args...)
+	return tools, total, err
+}
+
+func (r *sqlToolRepository) Get(id int) (types.Tool, bool, error) {
+	return r.get(sql_select_tool+`WHERE t.id = ? AND t.deleted_at IS NULL;`, id)
+}
+
+func (r *sqlToolRepository) ListTrashed() ([]types.Tool, error) {
+	return r.query(sql_select_tool + `WHERE t.deleted_at IS NOT NULL ORDER BY t.deleted_at DESC;`)
+}
+
+func (r *sqlToolRepository) GetTrashed(id int) (types.Tool, bool, error) {
+	return r.get(sql_select_tool+`WHERE t.id = ? AND t.deleted_at IS NOT NULL;`, id)
+}
+
+func (r *sqlToolRepository) Add(data types.AddToolDto) (int64, error) {
+	var id int64
+	err := withTx(r.db, func(tx *database.Tx) error {
+		var err error
+		id, err = tx.InsertId(`
+			INSERT INTO nav_table (name, url, logo, catelog_id, "desc", sort, hide)
+			VALUES (?, ?, ?, ?, ?, ?, ?);
+			`, data.Name, data.Url, data.Logo, nullId(data.CatelogId), data.Desc, data.Sort, data.Hide)
+		if err != nil {
+			return err
+		}
+		if err = setToolPinyin(tx, int(id), data.Name, data.Desc); err != nil {
+			return err
+		}
+		return setToolTags(tx, int(id), data.Tags)
+	})
+	return id, err
+}
+
+func (r *sqlToolRepository) Update(data types.UpdateToolDto) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE nav_table
+			SET name = ?, url = ?, logo = ?, catelog_id = ?, "desc" = ?, sort = ?, hide = ?
+			WHERE id = ?;
+			`, data.Name, data.Url, data.Logo, nullId(data.CatelogId), data.Desc, data.Sort, data.Hide, data.Id)
+		if err != nil {
+			return err
+		}
+		if err = setToolPinyin(tx, data.Id, data.Name, data.Desc); err != nil || data.Tags == nil {
+			return err
+		}
+		return setToolTags(tx, data.Id, data.Tags)
+	})
+}
+
+func (r *sqlToolRepository) UpdateLogo(id int64, logo string) error {
+	_, err := r.db.Exec(`UPDATE nav_table SET logo = ? WHERE id = ?;`, logo, id)
+	return err
+}
+
+func (r *sqlToolRepository) UpdateSort(updates []types.UpdateToolsSortDto) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		stmt, err := tx.Prepare(`UPDATE nav_table SET sort = ? WHERE id = ?;`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, update := range updates {
+			if _, err = stmt.Exec(update.Sort, update.Id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *sqlToolRepository) Import(tools []types.Tool) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO nav_table (id, name, catelog_id, url, logo, "desc", sort, hide)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET name = excluded.name, catelog_id = excluded.catelog_id, url = excluded.url,
+				logo = excluded.logo, "desc" = excluded."desc", sort = excluded.sort, hide = excluded.hide, deleted_at = NULL;
+			`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, v := range tools {
+			if _, err = stmt.Exec(v.Id, v.Name, nullId(v.CatelogId), v.Url, v.Logo, v.Desc, v.Sort, v.Hide); err != nil {
+				return err
+			}
+			if err = setToolPinyin(tx, v.Id, v.Name, v.Desc); err != nil {
+				return err
+			}
+			if v.Tags != nil {
+				if err = setToolTags(tx, v.Id, v.Tags); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.SyncSequence("nav_table")
+	})
+}
+
+func (r *sqlToolRepository) Trash(ids []int, at int64) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		stmt, err := tx.Prepare(`UPDATE nav_table SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL;`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, id := range ids {
+			if _, err = stmt.Exec(at, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *sqlToolRepository) Restore(id int, catelogId int) error {
+	_, err := r.db.Exec(`UPDATE nav_table SET deleted_at = NULL, catelog_id = ? WHERE id = ?;`, nullId(catelogId), id)
+	return err
+}
+
+func (r *sqlToolRepository) Delete(ids []int) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		for _, id := range ids {
+			var logo sql.NullString
+			err := tx.QueryRow(`SELECT logo FROM nav_table WHERE id = ?;`, id).Scan(&logo)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if _, err = tx.Exec(`DELETE FROM nav_tool_tag WHERE tool_id = ?;`, id); err != nil {
+				return err
+			}
+			if _, err = tx.Exec(`DELETE FROM nav_tool_pinyin WHERE tool_id = ?;`, id); err != nil {
+				return err
+			}
+			if _, err = tx.Exec(`DELETE FROM nav_table WHERE id = ?;`, id); err != nil {
+				return err
+			}
+			if _, err = tx.Exec(`DELETE FROM nav_tool_revision WHERE tool_id = ?;`, id); err != nil {
+				return err
+			}
+			if logo.String != "" {
+				if _, err = tx.Exec(`DELETE FROM nav_img WHERE url = ?;`, url.QueryEscape(logo.String)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (r *sqlToolRepository) Purge(before int64) (int, error) {
+	rows, err := r.db.Query(`SELECT id FROM nav_table WHERE deleted_at < ?;`, before)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	return len(ids), r.Delete(ids)
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/utils"
+)
+
+// 重新计算工具名称和描述的拼音索引，与工具在同一个事务中写入
+func setToolPinyin(tx *database.Tx, toolId int, name string, desc string) error {
+	nameFull, nameInitials := utils.Pinyin(name)
+	descFull, descInitials := utils.Pinyin(desc)
+	_, err := tx.Exec(`
+		INSERT INTO nav_tool_pinyin (tool_id, name_full, name_initials, desc_full, desc_initials)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (tool_id) DO UPDATE SET name_full = excluded.name_full, name_initials = excluded.name_initials,
+			desc_full = excluded.desc_full, desc_initials = excluded.desc_initials;
+		`, toolId, nameFull, nameInitials, descFull, descInitials)
+	return err
+}
+
+// 一个词在拼音索引中要查找的内容：不含汉字的词原样（小写）查找，例如 gh、gonghao；
+// 汉字和其他字符混合的词转为全拼和首字母查找，例如「工号xt」查找 gonghaoxt 和 ghxt；纯汉字的词不查拼音
+func pinyinKeys(term string) []string {
+	hasHan, hasOther := false, false
+	for _, r := range term {
+		if unicode.Is(unicode.Han, r) {
+			hasHan = true
+		} else {
+			hasOther = true
+		}
+	}
+	switch {
+	case !hasHan:
+		return []string{strings.ToLower(term)}
+	case hasOther:
+		full, initials := utils.Pinyin(term)
+		if full == initials {
+			return []string{full}
+		}
+		return []string{full, initials}
+	}
+	return nil
+}
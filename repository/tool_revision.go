@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlToolRevisionRepository struct {
+	db *database.Handle
+}
+
+const sql_select_tool_revision = `
+		SELECT id,tool_id,created_at,actor_type,actor_id,actor,action,name,url,logo,catelog_id,catelog,"desc",sort,hide,deleted_at
+		FROM nav_tool_revision `
+
+func scanToolRevision(row scanner) (types.ToolRevision, error) {
+	var revision types.ToolRevision
+	var name, url, logo, catelog, desc sql.NullString
+	var catelogId, sort, deletedAt sql.NullInt64
+	var hide sql.NullBool
+	err := row.Scan(&revision.Id, &revision.ToolId, &revision.CreatedAt, &revision.ActorType, &revision.ActorId, &revision.Actor, &revision.Action,
+		&name, &url, &logo, &catelogId, &catelog, &desc, &sort, &hide, &deletedAt)
+	revision.Tool = types.Tool{
+		Id:        revision.ToolId,
+		Name:      name.String,
+		Url:       url.String,
+		Logo:      logo.String,
+		CatelogId: int(catelogId.Int64),
+		Catelog:   catelog.String,
+		Desc:      desc.String,
+		Sort:      int(sort.Int64),
+		Hide:      hide.Bool,
+		DeletedAt: deletedAt.Int64,
+	}
+	return revision, err
+}
+
+func (r *sqlToolRevisionRepository) Record(ids []int, actor types.Actor, action string, at int64) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO nav_tool_revision (tool_id, created_at, actor_type, actor_id, actor, action,
+				name, url, logo, catelog_id, catelog, "desc", sort, hide, deleted_at)
+			SELECT t.id, ?, ?, ?, ?, ?, t.name, t.url, t.logo, t.catelog_id, c.name, t."desc", t.sort, t.hide, t.deleted_at
+			FROM nav_table t LEFT JOIN nav_catelog c ON c.id = t.catelog_id WHERE t.id = ?;
+			`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, id := range ids {
+			if _, err = stmt.Exec(at, actor.Type, actor.Id, actor.Name, action, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *sqlToolRevisionRepository) List(toolId int) ([]types.ToolRevision, error) {
+	results := make([]types.ToolRevision, 0)
+	rows, err := r.db.Query(sql_select_tool_revision+`WHERE tool_id = ? ORDER BY id DESC;`, toolId)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		revision, err := scanToolRevision(rows)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, revision)
+	}
+	return results, rows.Err()
+}
+
+func (r *sqlToolRevisionRepository) Get(toolId int, id int64) (types.ToolRevision, bool, error) {
+	revision, err := scanToolRevision(r.db.QueryRow(sql_select_tool_revision+`WHERE tool_id = ? AND id = ?;`, toolId, id))
+	ok, err := found(err)
+	return revision, ok, err
+}
@@ -0,0 +1,371 @@
+package repository
+
+import (
+	"database/sql"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+// 全文索引中各列的权重：名称、描述、网址、分类、标签、名称拼音、描述拼音
+const sql_fts_rank = `bm25(nav_tool_fts, 10.0, 1.0, 2.0, 3.0, 5.0, 8.0, 0.5)`
+
+// 与 sql_fts_rank 对应的列名和权重，pinyin 为通过拼音匹配时的权重。LIKE 匹配时在 Go 中计算相关度
+var searchFields = []struct {
+	name   string
+	weight float64
+	pinyin float64
+}{{"name", 10, 8}, {"desc", 1, 0.5}, {"url", 2, 0}, {"catelog", 3, 0}, {"tags", 5, 0}}
+
+// 高亮标记，转义之后替换为 <mark>，避免工具内容中的 HTML 被当作标签
+const (
+	markStart = "\x01"
+	markEnd   = "\x02"
+)
+
+// 描述较长时只保留匹配附近的片段
+const snippetRunes = 48
+
+// 关键词按空白拆分，所有词都需要匹配
+func searchTerms(keyword string) []string {
+	return strings.Fields(keyword)
+}
+
+// 一个词在全文索引中查找的短语：词本身，以及与它不同的拼音（见 pinyinKeys）
+func ftsPhrases(term string) []string {
+	phrases := []string{term}
+	for _, key := range pinyinKeys(term) {
+		if key != strings.ToLower(term) {
+			phrases = append(phrases, key)
+		}
+	}
+	return phrases
+}
+
+// SQLite 使用 FTS5 全文索引。trigram 分词无法匹配少于 3 个字符的词，这时和 PostgreSQL 一样退化为 LIKE
+func (r *sqlToolRepository) useFts(terms []string) bool {
+	if r.db.Dialect != database.SQLite || len(terms) == 0 {
+		return false
+	}
+	for _, term := range terms {
+		for _, phrase := range ftsPhrases(term) {
+			if utf8.RuneCountInString(phrase) < 3 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// 每个短语加引号，避免被解析为 FTS5 的查询语法。一个词的多个短语匹配任意一个即可，词之间需要显式的 AND，
+// FTS5 不支持括号之间的隐式 AND
+func ftsQuery(terms []string) string {
+	groups := make([]string, len(terms))
+	for i, term := range terms {
+		phrases := ftsPhrases(term)
+		for j, phrase := range phrases {
+			phrases[j] = `"` + strings.ReplaceAll(phrase, `"`, `""`) + `"`
+		}
+		groups[i] = "(" + strings.Join(phrases, " OR ") + ")"
+	}
+	return strings.Join(groups, " AND ")
+}
+
+// 每个词都需要出现在名称、描述、网址、分类名称或标签中，或者出现在名称、描述的拼音中。
+// loose 为 true 时允许全拼和首字母混合使用（例如 gonghx），索引中没有这样的字符串，因此只要求拼音的字母按顺序出现在全拼中，
+// 查出的工具需要再用 toolMatchesTerm 确认。PostgreSQL 的 LIKE 区分大小写，统一转为小写
+func likeClause(terms []string, loose bool) (string, []interface{}) {
+	clause := ""
+	args := []interface{}{}
+	for _, term := range terms {
+		like := "%" + strings.ToLower(term) + "%"
+		clause += ` AND (LOWER(t.name) LIKE ? OR LOWER(t."desc") LIKE ? OR LOWER(t.url) LIKE ? OR LOWER(c.name) LIKE ?
+			OR t.id IN (SELECT tt.tool_id FROM nav_tool_tag tt JOIN nav_tag g ON g.id = tt.tag_id WHERE LOWER(g.name) LIKE ?)`
+		args = append(args, like, like, like, like, like)
+		keys := pinyinKeys(term)
+		if loose && len(keys) > 0 {
+			// 首字母和混合的写法都是全拼的子序列
+			like = "%" + strings.Join(strings.Split(keys[0], ""), "%") + "%"
+			clause += `
+			OR t.id IN (SELECT p.tool_id FROM nav_tool_pinyin p WHERE p.name_full LIKE ? OR p.desc_full LIKE ?)`
+			args = append(args, like, like)
+			keys = nil
+		}
+		for _, key := range keys {
+			like = "%" + key + "%"
+			clause += `
+			OR t.id IN (SELECT p.tool_id FROM nav_tool_pinyin p
+				WHERE p.name_full LIKE ? OR p.name_initials LIKE ? OR p.desc_full LIKE ? OR p.desc_initials LIKE ?)`
+			args = append(args, like, like, like, like)
+		}
+		clause += `)`
+	}
+	return clause, args
+}
+
+// 确认工具匹配一个词：原文中出现，拼音索引中出现（见 pinyinKeys），或者能用 utils.PinyinMatch 对应到名称或描述
+func toolMatchesTerm(tool types.Tool, term string) bool {
+	lower := strings.ToLower(term)
+	for _, value := range append([]string{tool.Name, tool.Desc, tool.Url, tool.Catelog}, tool.Tags...) {
+		if strings.Contains(strings.ToLower(value), lower) {
+			return true
+		}
+	}
+	keys := pinyinKeys(term)
+	if len(keys) == 0 {
+		return false
+	}
+	for _, text := range []string{tool.Name, tool.Desc} {
+		full, initials := utils.Pinyin(text)
+		for _, key := range keys {
+			if strings.Contains(full, key) || strings.Contains(initials, key) {
+				return true
+			}
+		}
+		if _, _, ok := utils.PinyinMatch(text, term); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 在查询工具的列之后再扫描额外的列
+type extraScanner struct {
+	row   scanner
+	extra []interface{}
+}
+
+func (s extraScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
+
+func (r *sqlToolRepository) Search(keyword string) ([]types.ToolSearchResult, error) {
+	terms := searchTerms(keyword)
+	if len(terms) == 0 {
+		return make([]types.ToolSearchResult, 0), nil
+	}
+	if !r.useFts(terms) {
+		return r.searchLike(terms)
+	}
+	results, err := r.searchFts(terms)
+	if err != nil {
+		return results, err
+	}
+	// 全文索引只能匹配完整的全拼或首字母，混合写法的拼音由 LIKE 补充，排在全文索引的结果之后
+	more, err := r.searchLike(terms)
+	if err != nil {
+		return results, err
+	}
+	found := make(map[int]bool, len(results))
+	for _, result := range results {
+		found[result.Tool.Id] = true
+	}
+	for _, result := range more {
+		if !found[result.Tool.Id] {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func (r *sqlToolRepository) searchFts(terms []string) ([]types.ToolSearchResult, error) {
+	results := make([]types.ToolSearchResult, 0)
+	rows, err := r.db.Query(`
+		SELECT `+sql_tool_columns+`, `+sql_fts_rank+` AS score,
+			highlight(nav_tool_fts, 0, char(1), char(2)),
+			snippet(nav_tool_fts, 1, char(1), char(2), '…', 32),
+			highlight(nav_tool_fts, 2, char(1), char(2)),
+			highlight(nav_tool_fts, 3, char(1), char(2)),
+			highlight(nav_tool_fts, 4, char(1), char(2))
+		FROM nav_tool_fts
+		JOIN nav_table t ON t.id = nav_tool_fts.rowid
+		LEFT JOIN nav_catelog c ON c.id = t.catelog_id
+		WHERE nav_tool_fts MATCH ? AND t.deleted_at IS NULL
+		ORDER BY score, t.sort;
+		`, ftsQuery(terms))
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	tools := make([]types.Tool, 0)
+	for rows.Next() {
+		var result types.ToolSearchResult
+		fields := make([]sql.NullString, len(searchFields))
+		extra := []interface{}{&result.Rank}
+		for i := range fields {
+			extra = append(extra, &fields[i])
+		}
+		tool, err := scanTool(extraScanner{row: rows, extra: extra})
+		if err != nil {
+			return results, err
+		}
+		result.Highlights = make(map[string]string)
+		for i, field := range searchFields {
+			marked := fields[i].String
+			// 全文索引只能标出原文中的匹配，通过拼音匹配的名称和描述在 Go 中标出对应的汉字
+			if field.pinyin > 0 && !strings.Contains(marked, markStart) {
+				text := tool.Name
+				if field.name == "desc" {
+					text = tool.Desc
+				}
+				if pinyinMarked, _, n := markTerms(text, terms, true); n > 0 {
+					marked = pinyinMarked
+					if field.name == "desc" {
+						marked = snippetOf(marked)
+					}
+				}
+			}
+			if strings.Contains(marked, markStart) || field.name == "name" {
+				result.Highlights[field.name] = markHtml(marked)
+			}
+		}
+		tools = append(tools, tool)
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return results, err
+	}
+	if err = loadToolTags(r.db, tools); err != nil {
+		return results, err
+	}
+	for i := range results {
+		results[i].Tool = tools[i]
+	}
+	return results, nil
+}
+
+// 用 LIKE 查出候选的工具，去掉没有匹配所有词的，再按 searchFields 的权重计算相关度并标出匹配的部分
+func (r *sqlToolRepository) searchLike(terms []string) ([]types.ToolSearchResult, error) {
+	clause, args := likeClause(terms, true)
+	tools, err := r.query(sql_select_tool+`WHERE t.deleted_at IS NULL`+clause+` ORDER BY t.sort;`, args...)
+	results := make([]types.ToolSearchResult, 0, len(tools))
+	if err != nil {
+		return results, err
+	}
+	for _, tool := range tools {
+		matched := true
+		for _, term := range terms {
+			matched = matched && toolMatchesTerm(tool, term)
+		}
+		if !matched {
+			continue
+		}
+		values := map[string]string{
+			"name":    tool.Name,
+			"desc":    tool.Desc,
+			"url":     tool.Url,
+			"catelog": tool.Catelog,
+			"tags":    strings.Join(tool.Tags, " "),
+		}
+		result := types.ToolSearchResult{Tool: tool, Highlights: make(map[string]string)}
+		for _, field := range searchFields {
+			marked, matched, pinyinMatched := markTerms(values[field.name], terms, field.pinyin > 0)
+			if matched+pinyinMatched == 0 && field.name != "name" {
+				continue
+			}
+			if field.name == "desc" {
+				marked = snippetOf(marked)
+			}
+			result.Highlights[field.name] = markHtml(marked)
+			result.Rank -= field.weight*float64(matched) + field.pinyin*float64(pinyinMatched)
+		}
+		results = append(results, result)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank < results[j].Rank
+	})
+	return results, nil
+}
+
+// 不区分大小写地标出 text 中出现的所有词，返回带标记的文本和匹配到的词的数量。
+// pinyin 为 true 时，原文中没有出现的词再按拼音匹配（见 utils.PinyinMatch），标出第一处对应的文字并单独计数
+func markTerms(text string, terms []string, pinyin bool) (string, int, int) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	marked := make([]bool, len(runes))
+	matched, pinyinMatched := 0, 0
+	for _, term := range terms {
+		needle := []rune(strings.ToLower(term))
+		found := false
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) == string(needle) {
+				found = true
+				for j := i; j < i+len(needle); j++ {
+					marked[j] = true
+				}
+			}
+		}
+		if found {
+			matched++
+			continue
+		}
+		if !pinyin {
+			continue
+		}
+		if start, end, ok := utils.PinyinMatch(text, term); ok {
+			pinyinMatched++
+			for j := start; j < end; j++ {
+				marked[j] = true
+			}
+		}
+	}
+	var b strings.Builder
+	for i, r := range runes {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			b.WriteString(markStart)
+		}
+		b.WriteRune(r)
+		if marked[i] && (i == len(runes)-1 || !marked[i+1]) {
+			b.WriteString(markEnd)
+		}
+	}
+	return b.String(), matched, pinyinMatched
+}
+
+// 截取第一个匹配附近的片段，与 FTS5 的 snippet 类似
+func snippetOf(marked string) string {
+	runes := []rune(marked)
+	if len(runes) <= snippetRunes {
+		return marked
+	}
+	first := strings.Index(marked, markStart)
+	start := 0
+	if first > 0 {
+		start = utf8.RuneCountInString(marked[:first]) - snippetRunes/4
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetRunes
+	if end > len(runes) {
+		end = len(runes)
+	}
+	snippet := string(runes[start:end])
+	// 截断的位置可能在标记中间，补上缺少的结束标记
+	if strings.Count(snippet, markStart) > strings.Count(snippet, markEnd) {
+		snippet += markEnd
+	}
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// 转义 HTML 之后把标记替换为 <mark>
+func markHtml(text string) string {
+	escaped := html.EscapeString(text)
+	escaped = strings.ReplaceAll(escaped, markStart, "<mark>")
+	return strings.ReplaceAll(escaped, markEnd, "</mark>")
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+func TestFtsQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+		want  string
+	}{
+		{"普通词", []string{"github"}, `("github")`},
+		{"大小写不产生额外的短语", []string{"GitHub"}, `("GitHub")`},
+		{"多个词都要匹配", []string{"git", "hub"}, `("git") AND ("hub")`},
+		{"引号转义", []string{`say"hi"`}, `("say""hi""")`},
+		{"FTS5 语法按原样匹配", []string{"NEAR(a", "name:x", "abc*", "-abc", "OR"}, `("NEAR(a") AND ("name:x") AND ("abc*") AND ("-abc") AND ("OR")`},
+		{"纯汉字不加拼音", []string{"工号系统"}, `("工号系统")`},
+		{"汉字混合字母时加上全拼和首字母", []string{"工号xt"}, `("工号xt" OR "gonghaoxt" OR "ghxt")`},
+		{"混合词和普通词", []string{"工号abc", "hr"}, `("工号abc" OR "gonghaoabc" OR "ghabc") AND ("hr")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ftsQuery(tt.terms); got != tt.want {
+				t.Errorf("ftsQuery(%q) = %s, want %s", tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func openTestStore(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	database.Configure(dir, filepath.Join(dir, "nav.db"))
+	database.InitDB()
+	Init(database.DB)
+	t.Cleanup(func() {
+		database.DB.Close()
+	})
+}
+
+func TestToolSearch(t *testing.T) {
+	openTestStore(t)
+	catelogId, err := Catelogs().Add(types.AddCatelogDto{Name: "常用"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools := []types.AddToolDto{
+		{Name: "工号系统", Url: "https://hr.example.com", Desc: "查询员工工号", CatelogId: int(catelogId)},
+		{Name: `Quote "Tool"`, Url: "https://quote.example.com", Desc: `say "hello" (NEAR)`, CatelogId: int(catelogId)},
+		{Name: "GitHub", Url: "https://github.com", Desc: "Code hosting", CatelogId: int(catelogId), Tags: []string{"dev"}},
+	}
+	for _, tool := range tools {
+		if _, err := Tools().Add(tool); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		keyword string
+		want    []string
+	}{
+		// 少于 3 个字符时退化为 LIKE，其余使用全文索引
+		{"工号", []string{"工号系统"}},
+		{"员工工号", []string{"工号系统"}},
+		{`"hello"`, []string{`Quote "Tool"`}},
+		{"(NEAR)", []string{`Quote "Tool"`}},
+		{"(NEAR", []string{`Quote "Tool"`}},
+		{"hello hr", nil},
+		{"github hosting", []string{"GitHub"}},
+		{"dev", []string{"GitHub"}},
+		{"github 工号", nil},
+		{"name:github", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyword, func(t *testing.T) {
+			results, err := Tools().Search(tt.keyword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, 0, len(results))
+			for _, result := range results {
+				names = append(names, result.Tool.Name)
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("Search(%q) = %q, want %q", tt.keyword, names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Errorf("Search(%q) = %q, want %q", tt.keyword, names, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestToolSearchPinyin(t *testing.T) {
+	openTestStore(t)
+	catelogId, err := Catelogs().Add(types.AddCatelogDto{Name: "常用"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := Tools().Add(types.AddToolDto{Name: "工号系统", Url: "https://hr.example.com", Desc: "查询员工工号", CatelogId: int(catelogId)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Tools().Add(types.AddToolDto{Name: "GitHub", Url: "https://github.com", Desc: "Code hosting", CatelogId: int(catelogId)}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		keyword string
+		found   bool
+	}{
+		// 少于 3 个字符时走 LIKE，其余走全文索引
+		{"gh", true},
+		{"GH", true},
+		{"gonghao", true},
+		{"ghxt", true},
+		{"gonghaoxitong", true},
+		{"工号xt", true},
+		{"yuangong", true},
+		// 全拼和首字母混合，索引中没有对应的字符串
+		{"gonghx", true},
+		{"GongHX", true},
+		{"gonghaoxit", true},
+		{"ghxitong", true},
+		{"gonghx hr", true},
+		{"gonghao github", false},
+		{"gonghx github", false},
+		{"xitongg", false},
+		// 字母按顺序出现在全拼中，但不能对应到连续的汉字
+		{"gx", false},
+		{"gonghz", false},
+	}
+	for _, tt := range tests {
+		results, err := Tools().Search(tt.keyword)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.keyword, err)
+		}
+		found := len(results) == 1 && results[0].Tool.Id == int(id)
+		if found != tt.found {
+			t.Errorf("Search(%q) 找到工号系统 = %v, want %v (%d 个结果)", tt.keyword, found, tt.found, len(results))
+		}
+	}
+
+	// 修改名称后拼音索引随之更新
+	tool, _, err := Tools().Get(int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Tools().Update(types.UpdateToolDto{Id: tool.Id, Name: "考勤系统", Url: tool.Url, Desc: tool.Desc, CatelogId: tool.CatelogId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := Tools().Search("kaoqin"); len(results) != 1 {
+		t.Errorf("更新后按新名称的拼音没有找到，%d 个结果", len(results))
+	}
+	if results, _ := Tools().Search("gonghaoxitong"); len(results) != 0 {
+		t.Errorf("更新后仍能按旧名称的拼音找到，%d 个结果", len(results))
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlTotpRepository struct {
+	db *database.Handle
+}
+
+func (r *sqlTotpRepository) Get(userId int) (types.Totp, bool, error) {
+	var totp types.Totp
+	err := r.db.QueryRow(`SELECT totp_secret, totp_enabled, totp_last_step FROM nav_user WHERE id = ?;`, userId).Scan(&totp.Secret, &totp.Enabled, &totp.LastStep)
+	ok, err := found(err)
+	return totp, ok, err
+}
+
+func (r *sqlTotpRepository) CountRecoveryCodes(userId int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM nav_recovery_code WHERE user_id = ? AND used_at IS NULL;`, userId).Scan(&count)
+	return count, err
+}
+
+func (r *sqlTotpRepository) SetSecret(userId int, secret string) error {
+	_, err := r.db.Exec(`UPDATE nav_user SET totp_secret = ?, totp_last_step = 0 WHERE id = ?;`, secret, userId)
+	return err
+}
+
+func (r *sqlTotpRepository) Enable(userId int, step int64, codeHashes []string) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		if _, err := tx.Exec(`UPDATE nav_user SET totp_enabled = 1, totp_last_step = ? WHERE id = ?;`, step, userId); err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userId, codeHashes)
+	})
+}
+
+func (r *sqlTotpRepository) ReplaceRecoveryCodes(userId int, codeHashes []string) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		return replaceRecoveryCodes(tx, userId, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *database.Tx, userId int, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM nav_recovery_code WHERE user_id = ?;`, userId); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO nav_recovery_code (user_id, code_hash) VALUES (?, ?);`, userId, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *sqlTotpRepository) UseStep(userId int, step int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE nav_user SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?;`, step, userId, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *sqlTotpRepository) UseRecoveryCode(userId int, codeHash string, at int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE nav_recovery_code SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL;`, at, userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *sqlTotpRepository) Disable(userId int) (bool, error) {
+	var ok bool
+	err := withTx(r.db, func(tx *database.Tx) error {
+		res, err := tx.Exec(`UPDATE nav_user SET totp_secret = '', totp_enabled = 0, totp_last_step = 0 WHERE id = ?;`, userId)
+		if err != nil {
+			return err
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			return nil
+		}
+		ok = true
+		_, err = tx.Exec(`DELETE FROM nav_recovery_code WHERE user_id = ?;`, userId)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/types"
+)
+
+type sqlUserRepository struct {
+	db *database.Handle
+}
+
+const sql_select_user = `
+		SELECT id,name,password,role,totp_enabled,external_issuer,external_subject FROM nav_user `
+
+func scanUser(row scanner) (types.User, error) {
+	var user types.User
+	var password sql.NullString
+	err := row.Scan(&user.Id, &user.Name, &password, &user.Role, &user.TotpEnabled, &user.ExternalIssuer, &user.ExternalSubject)
+	user.Password = password.String
+	return user, err
+}
+
+func (r *sqlUserRepository) Get(id int) (types.User, bool, error) {
+	user, err := scanUser(r.db.QueryRow(sql_select_user+`WHERE id = ?;`, id))
+	ok, err := found(err)
+	return user, ok, err
+}
+
+func (r *sqlUserRepository) GetByName(name string) (types.User, bool, error) {
+	user, err := scanUser(r.db.QueryRow(sql_select_user+`WHERE name = ?;`, name))
+	ok, err := found(err)
+	return user, ok, err
+}
+
+func (r *sqlUserRepository) GetByExternal(issuer string, subject string) (types.User, bool, error) {
+	user, err := scanUser(r.db.QueryRow(sql_select_user+`WHERE external_issuer = ? AND external_subject = ?;`, issuer, subject))
+	ok, err := found(err)
+	return user, ok, err
+}
+
+// 列表不返回密码
+func (r *sqlUserRepository) List() ([]types.User, error) {
+	results := make([]types.User, 0)
+	rows, err := r.db.Query(`SELECT id,name,role,totp_enabled,external_issuer,external_subject FROM nav_user ORDER BY id;`)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user types.User
+		if err = rows.Scan(&user.Id, &user.Name, &user.Role, &user.TotpEnabled, &user.ExternalIssuer, &user.ExternalSubject); err != nil {
+			return results, err
+		}
+		results = append(results, user)
+	}
+	return results, rows.Err()
+}
+
+func (r *sqlUserRepository) NameTaken(name string, excludeId int) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM nav_user WHERE name = ? AND id != ?;`, name, excludeId).Scan(&count)
+	return count > 0, err
+}
+
+func (r *sqlUserRepository) CountByRole(role string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM nav_user WHERE role = ?;`, role).Scan(&count)
+	return count, err
+}
+
+func (r *sqlUserRepository) Add(name string, passwordHash string, role string) (int64, error) {
+	return r.db.InsertId(`
+		INSERT INTO nav_user (name, password, role)
+		VALUES (?, ?, ?);
+		`, name, passwordHash, role)
+}
+
+func (r *sqlUserRepository) AddExternal(name string, passwordHash string, role string, issuer string, subject string) (int64, error) {
+	return r.db.InsertId(`
+		INSERT INTO nav_user (name, password, role, external_issuer, external_subject)
+		VALUES (?, ?, ?, ?, ?);
+		`, name, passwordHash, role, issuer, subject)
+}
+
+func (r *sqlUserRepository) Update(id int, name string, passwordHash string, role string) error {
+	_, err := r.db.Exec(`UPDATE nav_user SET name = ?, password = ?, role = ? WHERE id = ?;`, name, passwordHash, role, id)
+	return err
+}
+
+func (r *sqlUserRepository) UpdateName(id int, name string) error {
+	_, err := r.db.Exec(`UPDATE nav_user SET name = ? WHERE id = ?;`, name, id)
+	return err
+}
+
+func (r *sqlUserRepository) UpdatePassword(id int, passwordHash string) error {
+	_, err := r.db.Exec(`UPDATE nav_user SET password = ? WHERE id = ?;`, passwordHash, id)
+	return err
+}
+
+func (r *sqlUserRepository) UpdateRole(id int, role string) error {
+	_, err := r.db.Exec(`UPDATE nav_user SET role = ? WHERE id = ?;`, role, id)
+	return err
+}
+
+func (r *sqlUserRepository) UpdateExternal(id int, issuer string, subject string) error {
+	_, err := r.db.Exec(`UPDATE nav_user SET external_issuer = ?, external_subject = ? WHERE id = ?;`, issuer, subject, id)
+	return err
+}
+
+func (r *sqlUserRepository) Delete(id int) error {
+	return withTx(r.db, func(tx *database.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM nav_user WHERE id = ?;`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE nav_session SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL;`, time.Now().Unix(), id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM nav_recovery_code WHERE user_id = ?;`, id)
+		return err
+	})
+}
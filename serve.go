@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,37 +17,73 @@ type ServeFileSystem interface {
 	Exists(prefix string, path string) bool
 }
 
-func Serve(urlPrefix string, fs ServeFileSystem) gin.HandlerFunc {
-	fileserver := http.FileServer(fs)
-	if urlPrefix != "" {
-		fileserver = http.StripPrefix(urlPrefix, fileserver)
-	}
+// basePath 为部署的路径前缀，例如 /nav，根路径部署时为空
+func Serve(basePath string, fs ServeFileSystem) gin.HandlerFunc {
+	urlPrefix := basePath + "/"
+	fileserver := http.StripPrefix(urlPrefix, http.FileServer(fs))
+	index := &indexPage{fs: fs, basePath: basePath}
 	return func(c *gin.Context) {
-		if fs.Exists(urlPrefix, c.Request.URL.Path) {
-			if strings.HasSuffix(c.Request.URL.Path, "service-worker.js") {
+		path := c.Request.URL.Path
+		if path == basePath {
+			c.Redirect(http.StatusMovedPermanently, urlPrefix)
+			c.Abort()
+			return
+		}
+		if !strings.HasPrefix(path, urlPrefix) {
+			return
+		}
+		rel := strings.TrimPrefix(path, urlPrefix)
+		if rel != "" && rel != INDEX && fs.Exists(urlPrefix, path) {
+			if strings.HasSuffix(path, "service-worker.js") {
 				c.Header("Cache-Control", "no-cache")
 			}
 			fileserver.ServeHTTP(c.Writer, c.Request)
 			c.Abort()
 		} else {
-			path := c.Request.URL.Path
-			pathHasAPI := strings.Contains(path, "/api") && !strings.Contains(path, "/api-token")
+			pathHasAPI := strings.HasPrefix(rel, "api") && !strings.HasPrefix(rel, "api-token")
 			// pathHasAdmin := strings.Contains(path, "/admin")
 			// pathHasLogin := strings.Contains(path, "/login")
 			if pathHasAPI {
 				return
 			} else {
-				file, err := fs.Open("index.html")
+				content, err := index.content()
 				if err != nil {
 					logger.LogError("文件不存在: %s", c.Request.URL.Path)
 					return
 				}
-				defer file.Close()
 				// 把文件返回
-				http.ServeContent(c.Writer, c.Request, "index.html", time.Now(), file)
+				http.ServeContent(c.Writer, c.Request, INDEX, time.Now(), bytes.NewReader(content))
 				c.Abort()
 			}
 
 		}
 	}
 }
+
+// 首页，注入路径前缀后缓存，前端据此拼接静态资源和接口地址
+type indexPage struct {
+	fs       ServeFileSystem
+	basePath string
+	once     sync.Once
+	data     []byte
+	err      error
+}
+
+func (p *indexPage) content() ([]byte, error) {
+	p.once.Do(func() {
+		file, err := p.fs.Open(INDEX)
+		if err != nil {
+			p.err = err
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			p.err = err
+			return
+		}
+		inject := `<head><base href="` + p.basePath + `/" /><script>window.__NAV_BASE__ = "` + p.basePath + `";</script>`
+		p.data = bytes.Replace(data, []byte("<head>"), []byte(inject), 1)
+	})
+	return p.data, p.err
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+// 审计日志保留时长，0 表示永久保留
+var auditRetention = 180 * 24 * time.Hour
+
+// 这些字段只记录是否变化，不记录具体的值
+var auditSensitiveFields = []string{"password", "guestPassword", "code"}
+
+const auditRedacted = "******"
+
+func SetAuditRetention(retention time.Duration) {
+	if retention < 0 {
+		retention = 0
+	}
+	auditRetention = retention
+}
+
+// 把对象展开成 JSON 字段，不是对象时放在 value 字段里
+func auditFields(v interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if v == nil {
+		return fields
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		utils.CheckErr(err)
+		return fields
+	}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		var value interface{}
+		json.Unmarshal(data, &value)
+		return map[string]interface{}{"value": value}
+	}
+	return fields
+}
+
+// 比较操作前后的对象，返回发生变化的字段。新建时 before 为 nil，删除时 after 为 nil
+func AuditDiff(before, after interface{}) map[string]types.AuditChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+	changes := make(map[string]types.AuditChange)
+	for key, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[key]) {
+			changes[key] = types.AuditChange{Before: value, After: afterFields[key]}
+		}
+	}
+	for key, value := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			changes[key] = types.AuditChange{Before: nil, After: value}
+		}
+	}
+	for key, change := range changes {
+		if !utils.In(key, auditSensitiveFields) {
+			continue
+		}
+		if change.Before != nil && change.Before != "" {
+			change.Before = auditRedacted
+		}
+		if change.After != nil && change.After != "" {
+			change.After = auditRedacted
+		}
+		changes[key] = change
+	}
+	return changes
+}
+
+// 写入一条审计日志，写入失败只记录错误，不影响已经完成的操作
+func RecordAudit(entry types.Audit, before, after interface{}) {
+	entry.CreatedAt = time.Now().Unix()
+	entry.Changes = AuditDiff(before, after)
+	if err := repository.Audit().Add(entry); err != nil {
+		logger.LogError("写入审计日志失败: %s %+v", err, entry)
+	}
+}
+
+func GetAuditPage(query types.AuditQueryDto) ([]types.Audit, int64) {
+	results, total, err := repository.Audit().Page(query)
+	if err != nil {
+		utils.CheckErr(err)
+		return make([]types.Audit, 0), 0
+	}
+	return results, total
+}
+
+// 删除超过保留时长的审计日志
+func PruneAudit() (int64, error) {
+	if auditRetention <= 0 {
+		return 0, nil
+	}
+	return repository.Audit().Prune(time.Now().Add(-auditRetention).Unix())
+}
+
+// 启动后台任务，每天清理一次过期的审计日志
+func StartAuditPruner() {
+	go func() {
+		for {
+			release := database.Acquire()
+			count, err := PruneAudit()
+			release()
+			if err != nil {
+				utils.CheckErr(err)
+			} else if count > 0 {
+				logger.LogInfo("清理过期审计日志 %d 条", count)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
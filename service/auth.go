@@ -1,63 +1,104 @@
 package service
 
 import (
-	"github.com/mereith/nav/database"
+	"fmt"
+	"time"
+
+	"github.com/mereith/nav/repository"
 	"github.com/mereith/nav/types"
 	"github.com/mereith/nav/utils"
 )
 
 func GetApiTokens() []types.Token {
-	sql_get_api_tokens := `
-		SELECT id,name,value,disabled FROM nav_api_token WHERE disabled = 0;
-		`
-	results := make([]types.Token, 0)
-	rows, err := database.DB.Query(sql_get_api_tokens)
+	results, err := repository.Tokens().ListActive()
 	utils.CheckErr(err)
-	for rows.Next() {
-		var token types.Token
-		err = rows.Scan(&token.Id, &token.Name, &token.Value, &token.Disabled)
-		utils.CheckErr(err)
-		results = append(results, token)
-	}
-	defer rows.Close()
 	return results
 }
 
-func GetUser(name string) types.User {
-	sql_get_user := `
-		SELECT id,name,password FROM nav_user WHERE name = ?;
-		`
-	var user types.User
-	row := database.DB.QueryRow(sql_get_user, name)
-	err := row.Scan(&user.Id, &user.Name, &user.Password)
+// 根据 token 值查找可用（未删除、未过期）的 API Token，数据库中只保存哈希
+func GetActiveApiToken(value string) (types.Token, bool) {
+	token, ok, err := repository.Tokens().GetActiveByHash(utils.HashApiToken(value))
 	utils.CheckErr(err)
-	return user
+	if !ok {
+		return token, false
+	}
+	if token.ExpiresAt > 0 && time.Now().Unix() >= token.ExpiresAt {
+		return token, false
+	}
+	return token, true
 }
 
-func AddApiTokenInDB(data types.Token) {
-	sql_add_api_token := `
-		INSERT INTO nav_api_token (id,name,value,disabled)
-		VALUES (?,?,?,?);
-		`
-	stmt, err := database.DB.Prepare(sql_add_api_token)
+// 根据 id 查找未删除的 API Token
+func GetApiTokenById(id int) (types.Token, bool) {
+	token, ok, err := repository.Tokens().GetActive(id)
 	utils.CheckErr(err)
+	return token, ok
+}
 
-	res, err := stmt.Exec(data.Id, data.Name, data.Value, data.Disabled)
-	utils.CheckErr(err)
-	_, err = res.LastInsertId()
+// 删除 API Token，只标记为 disabled，保留记录
+func DeleteApiToken(id int) error {
+	return repository.Tokens().Disable(id)
+}
+
+// 记录 API Token 最近一次使用的时间和 IP
+func TouchApiToken(id int, ip string) {
+	err := repository.Tokens().Touch(id, ip, time.Now().Unix())
 	utils.CheckErr(err)
 }
 
-func UpdateUser(data types.UpdateUserDto) {
-	sql_update_user := `
-		UPDATE nav_user
-		SET name = ?, password = ?
-		WHERE id = ?;
-		`
-	stmt, err := database.DB.Prepare(sql_update_user)
+// 校验申请的权限范围，未指定时默认只读
+func NormalizeTokenScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return []string{types.ScopeToolsRead}, nil
+	}
+	result := make([]string, 0)
+	for _, scope := range scopes {
+		if !utils.In(scope, types.TokenScopes) {
+			return nil, fmt.Errorf("不支持的权限范围: %s", scope)
+		}
+		if !utils.In(scope, result) {
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+func GetUser(name string) types.User {
+	user, _, err := repository.Users().GetByName(name)
 	utils.CheckErr(err)
-	res, err := stmt.Exec(data.Name, data.Password, data.Id)
+	return user
+}
+
+func AddApiTokenInDB(data types.Token) {
+	err := repository.Tokens().Add(data)
 	utils.CheckErr(err)
-	_, err = res.RowsAffected()
+}
+
+func UpdateUser(data types.UpdateUserDto) error {
+	if err := checkUserName(data.Name, int(data.Id)); err != nil {
+		return err
+	}
+	// 密码留空时只修改用户名
+	if data.Password == "" {
+		return repository.Users().UpdateName(int(data.Id), data.Name)
+	}
+	hash, err := utils.HashPassword(data.Password)
+	if err != nil {
+		return err
+	}
+	if err = repository.Users().UpdateName(int(data.Id), data.Name); err != nil {
+		return err
+	}
+	return repository.Users().UpdatePassword(int(data.Id), hash)
+}
+
+// 登录时把旧的明文密码升级为哈希
+func UpgradeUserPassword(id int, password string) {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		utils.CheckErr(err)
+		return
+	}
+	err = repository.Users().UpdatePassword(id, hash)
 	utils.CheckErr(err)
 }
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
+)
+
+// 用上传的备份替换当前数据库，完成后重新加载依赖数据库的状态。
+// 整个过程独占数据库，调用方不能持有 database.Acquire 获取的读锁
+func RestoreDatabase(path string) error {
+	release := database.Exclusive()
+	defer release()
+	err := database.Restore(path)
+	// 即使迁移失败也已经换成了新的连接
+	repository.Init(database.DB)
+	if err != nil {
+		return err
+	}
+	if !jwtSecretOverridden {
+		InitJWTSecret("")
+	}
+	InitGuestSecret()
+	// 备份中保存的是生成快照之前的备份结果，用当前的覆盖
+	saveBackupStatus()
+	logger.LogInfo("已从备份恢复数据库")
+	return nil
+}
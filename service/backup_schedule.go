@@ -0,0 +1,277 @@
+package service
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+	"github.com/robfig/cron/v3"
+)
+
+// 自动备份文件名前缀，只有这类文件会被保留策略清理，迁移和恢复前的备份不受影响
+const autoBackupPrefix = "nav-auto-"
+
+// 最近一次自动备份的结果保存在 nav_state 中，重启后健康检查仍能报告上次失败
+const stateBackupStatus = "backup_status"
+
+// 需要持久化的备份结果，计划相关的字段由配置决定，不保存
+type savedBackupStatus struct {
+	LastRunAt     int64  `json:"lastRunAt"`
+	LastSuccessAt int64  `json:"lastSuccessAt"`
+	LastFile      string `json:"lastFile"`
+	LastError     string `json:"lastError"`
+}
+
+type BackupConfig struct {
+	// 标准 5 段 cron 表达式或 @daily 等描述符，留空表示不自动备份
+	Schedule string
+	Compress bool
+	// 最多保留的份数和最长保留时间，0 表示不限制
+	Keep   int
+	MaxAge time.Duration
+}
+
+var (
+	backupConfig   BackupConfig
+	backupSchedule cron.Schedule
+	backupStatus   types.BackupStatus
+	// 同一时间只运行一个自动备份
+	backupRunMutex    sync.Mutex
+	backupStatusMutex sync.Mutex
+)
+
+func SetBackupConfig(config BackupConfig) error {
+	backupConfig = config
+	backupSchedule = nil
+	if config.Schedule != "" {
+		schedule, err := cron.ParseStandard(config.Schedule)
+		if err != nil {
+			return fmt.Errorf("备份计划 %q 无效: %w", config.Schedule, err)
+		}
+		backupSchedule = schedule
+	}
+	backupStatusMutex.Lock()
+	backupStatus.Enabled = backupSchedule != nil
+	backupStatus.Schedule = config.Schedule
+	backupStatusMutex.Unlock()
+	return nil
+}
+
+func backupDir() string {
+	return filepath.Join(database.DataDir(), "backups")
+}
+
+// 启动后台任务，按计划生成备份；PostgreSQL 不支持，直接跳过
+func StartBackupScheduler() {
+	loadBackupStatus()
+	if backupSchedule == nil {
+		return
+	}
+	if database.DB.Dialect == database.Postgres {
+		logger.LogInfo("PostgreSQL 不支持自动备份，请使用 pg_dump")
+		backupStatusMutex.Lock()
+		backupStatus.Enabled = false
+		backupStatusMutex.Unlock()
+		return
+	}
+	go func() {
+		for {
+			next := backupSchedule.Next(time.Now())
+			backupStatusMutex.Lock()
+			backupStatus.NextRunAt = next.Unix()
+			backupStatusMutex.Unlock()
+			time.Sleep(time.Until(next))
+			// 与恢复数据库互斥，避免用到已经被替换关闭的连接
+			release := database.Acquire()
+			_, err := RunBackup()
+			release()
+			if err != nil {
+				logger.LogError("自动备份失败: %s", err)
+			}
+		}
+	}()
+}
+
+// 立即生成一份自动备份并按保留策略清理旧备份，返回备份文件名
+func RunBackup() (string, error) {
+	backupRunMutex.Lock()
+	defer backupRunMutex.Unlock()
+	name, err := writeBackup()
+	now := time.Now().Unix()
+	backupStatusMutex.Lock()
+	backupStatus.LastRunAt = now
+	if err != nil {
+		backupStatus.LastError = err.Error()
+	} else {
+		backupStatus.LastSuccessAt = now
+		backupStatus.LastFile = name
+		backupStatus.LastError = ""
+	}
+	backupStatusMutex.Unlock()
+	saveBackupStatus()
+	if err != nil {
+		return "", err
+	}
+	logger.LogInfo("已生成自动备份 %s", name)
+	if count, err := pruneBackups(); err != nil {
+		utils.CheckErr(err)
+	} else if count > 0 {
+		logger.LogInfo("清理过期自动备份 %d 份", count)
+	}
+	return name, nil
+}
+
+func writeBackup() (string, error) {
+	name := autoBackupPrefix + time.Now().Format("20060102150405") + ".db"
+	path := filepath.Join(backupDir(), name)
+	if !backupConfig.Compress {
+		return name, database.Snapshot(path)
+	}
+	// 先生成快照再压缩，压缩失败时不留下不完整的文件
+	tmp := path + ".tmp"
+	if err := database.Snapshot(tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	defer os.Remove(tmp)
+	name += ".gz"
+	if err := gzipFile(tmp, path+".gz"); err != nil {
+		os.Remove(path + ".gz")
+		return "", err
+	}
+	return name, nil
+}
+
+func gzipFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(out)
+	_, err = io.Copy(writer, in)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// 列出备份目录中的全部备份，最新的在前
+func ListBackups() ([]types.BackupFile, error) {
+	files := make([]types.BackupFile, 0)
+	entries, err := os.ReadDir(backupDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return files, nil
+	}
+	if err != nil {
+		return files, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".db") || strings.HasSuffix(name, ".db.gz")) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return files, err
+		}
+		files = append(files, types.BackupFile{Name: name, Size: info.Size(), CreatedAt: info.ModTime().Unix()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CreatedAt > files[j].CreatedAt
+	})
+	return files, nil
+}
+
+// 按份数和时间清理自动备份，返回删除的数量
+func pruneBackups() (int, error) {
+	files, err := ListBackups()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	kept := 0
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name, autoBackupPrefix) {
+			continue
+		}
+		expired := backupConfig.MaxAge > 0 && time.Since(time.Unix(file.CreatedAt, 0)) > backupConfig.MaxAge
+		if (backupConfig.Keep > 0 && kept >= backupConfig.Keep) || expired {
+			if err = os.Remove(filepath.Join(backupDir(), file.Name)); err != nil {
+				return count, err
+			}
+			count++
+			continue
+		}
+		kept++
+	}
+	return count, nil
+}
+
+// 读取保存的备份结果。升级前没有保存过时，以最近一份自动备份作为上次成功的时间
+func loadBackupStatus() {
+	var saved savedBackupStatus
+	ok, err := getState(stateBackupStatus, &saved)
+	if err != nil {
+		utils.CheckErr(err)
+		return
+	}
+	if !ok {
+		files, err := ListBackups()
+		if err != nil {
+			return
+		}
+		for _, file := range files {
+			if strings.HasPrefix(file.Name, autoBackupPrefix) {
+				saved.LastSuccessAt = file.CreatedAt
+				saved.LastFile = file.Name
+				break
+			}
+		}
+	}
+	backupStatusMutex.Lock()
+	backupStatus.LastRunAt = saved.LastRunAt
+	backupStatus.LastSuccessAt = saved.LastSuccessAt
+	backupStatus.LastFile = saved.LastFile
+	backupStatus.LastError = saved.LastError
+	backupStatusMutex.Unlock()
+}
+
+// 保存当前的备份结果，失败时只记录日志，不影响备份本身
+func saveBackupStatus() {
+	backupStatusMutex.Lock()
+	saved := savedBackupStatus{
+		LastRunAt:     backupStatus.LastRunAt,
+		LastSuccessAt: backupStatus.LastSuccessAt,
+		LastFile:      backupStatus.LastFile,
+		LastError:     backupStatus.LastError,
+	}
+	backupStatusMutex.Unlock()
+	if err := saveState(stateBackupStatus, saved); err != nil {
+		logger.LogError("保存备份状态失败: %s", err)
+	}
+}
+
+func GetBackupStatus() types.BackupStatus {
+	backupStatusMutex.Lock()
+	defer backupStatusMutex.Unlock()
+	return backupStatus
+}
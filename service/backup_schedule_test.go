@@ -0,0 +1,54 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mereith/nav/types"
+)
+
+// 备份结果保存在数据库中，重启（重新加载）后仍然可以看到上次的失败
+func TestBackupStatusPersisted(t *testing.T) {
+	openTestDB(t)
+	if err := SetBackupConfig(BackupConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		backupStatus = types.BackupStatus{}
+	})
+
+	name, err := RunBackup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backupStatus = types.BackupStatus{}
+	loadBackupStatus()
+	status := GetBackupStatus()
+	if status.LastFile != name || status.LastSuccessAt == 0 || status.LastRunAt != status.LastSuccessAt || status.LastError != "" {
+		t.Fatalf("成功后重新加载的状态 = %+v", status)
+	}
+	lastSuccess := status.LastSuccessAt
+
+	// 备份目录被占用为普通文件时备份失败
+	if err := os.RemoveAll(backupDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(backupDir(), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunBackup(); err == nil {
+		t.Fatal("备份目录不可用时备份成功")
+	}
+	backupStatus = types.BackupStatus{}
+	loadBackupStatus()
+	status = GetBackupStatus()
+	if status.LastError == "" {
+		t.Error("重新加载后丢失了上次的错误")
+	}
+	if status.LastSuccessAt != lastSuccess || status.LastFile != name {
+		t.Errorf("失败后上次成功的记录被修改: %+v", status)
+	}
+	if status.LastRunAt < lastSuccess {
+		t.Errorf("LastRunAt = %d, want >= %d", status.LastRunAt, lastSuccess)
+	}
+}
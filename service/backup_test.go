@@ -0,0 +1,80 @@
+package service
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/repository"
+)
+
+// 把快照复制为等待恢复的文件
+func restoreFileFrom(t *testing.T, snapshot string) string {
+	t.Helper()
+	src, err := os.Open(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dst, err := database.CreateRestoreFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if _, err = io.Copy(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	return dst.Name()
+}
+
+// 恢复期间并发的请求不会用到已经关闭的连接
+func TestRestoreDatabaseConcurrentRequests(t *testing.T) {
+	openTestDB(t)
+	InitGuestSecret()
+	snapshot, err := database.TempSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, `UPDATE nav_user SET name = 'changed';`)
+
+	var stop atomic.Bool
+	var failures atomic.Int64
+	var queries atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				release := database.Acquire()
+				if _, err := repository.Users().List(); err != nil {
+					failures.Add(1)
+					t.Log(err)
+				}
+				release()
+				queries.Add(1)
+			}
+		}()
+	}
+	// 同一秒内多次恢复，恢复前的备份文件名不能冲突
+	for i := 0; i < 3; i++ {
+		if err = RestoreDatabase(restoreFileFrom(t, snapshot)); err != nil {
+			break
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures.Load() > 0 {
+		t.Errorf("恢复期间 %d/%d 次查询失败", failures.Load(), queries.Load())
+	}
+	users, err := repository.Users().List()
+	if err != nil || len(users) != 1 || users[0].Name != "admin" {
+		t.Errorf("恢复后的用户 = %+v, %v", users, err)
+	}
+}
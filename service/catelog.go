@@ -1,115 +1,141 @@
 package service
 
 import (
-	"github.com/mereith/nav/database"
+	"errors"
+	"time"
+
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
 	"github.com/mereith/nav/types"
 	"github.com/mereith/nav/utils"
 )
 
+// 修改分类，工具通过 id 引用分类，改名不影响工具
 func UpdateCatelog(data types.UpdateCatelogDto) {
-
-	// 查询分类原名称
-	sql_select_old_catelog_name := `select name from nav_catelog where id = ?;`
-	var oldName string
-	err := database.DB.QueryRow(sql_select_old_catelog_name, data.Id).Scan(&oldName)
-	utils.CheckErr(err)
-
-	// 开启事务
-	tx, err := database.DB.Begin()
+	err := repository.Catelogs().Update(data)
 	utils.CheckErr(err)
+}
 
-	// 更新分类新名称
-	sql_update_catelog := `
-		UPDATE nav_catelog
-		SET name = ?, sort = ?, hide = ?
-		WHERE id = ?;
-		`
-	stmt, err := tx.Prepare(sql_update_catelog)
-	utils.CheckTxErr(err, tx)
-	res, err := stmt.Exec(data.Name, data.Sort, data.Hide, data.Id)
-	utils.CheckTxErr(err, tx)
-	_, err = res.RowsAffected()
-	utils.CheckTxErr(err, tx)
-
-	if oldName != data.Name {
-		// 更新工具分类新名称
-		sql_update_tools := `
-		UPDATE nav_table
-		SET catelog = ?
-		WHERE catelog = ?;
-		`
-		stmt2, err := tx.Prepare(sql_update_tools)
-		utils.CheckTxErr(err, tx)
-		res2, err := stmt2.Exec(data.Name, oldName)
-		utils.CheckTxErr(err, tx)
-		_, err = res2.RowsAffected()
-		utils.CheckTxErr(err, tx)
+// 添加分类，同一个上级分类下不能有同名的分类，返回新分类的 id
+func AddCatelog(data types.AddCatelogDto) (int64, error) {
+	for _, catelog := range GetAllCatelog() {
+		if catelog.ParentId == data.ParentId && catelog.Name == data.Name {
+			return 0, errors.New("同级分类中已存在同名分类")
+		}
 	}
-	// 提交事务
-	err = tx.Commit()
-	utils.CheckErr(err)
+	return repository.Catelogs().Add(data)
 }
 
-func AddCatelog(data types.AddCatelogDto) {
-	// 先检查重复不重复
-	existCatelogs := GetAllCatelog()
-	var existCatelogsArr []string
-	for _, catelogDto := range existCatelogs {
-		existCatelogsArr = append(existCatelogsArr, catelogDto.Name)
-	}
-	if utils.In(data.Name, existCatelogsArr) {
-		return
-	}
-	sql_add_catelog := `
-		INSERT INTO nav_catelog (name,sort,hide)
-		VALUES (?,?,?);
-		`
-	stmt, err := database.DB.Prepare(sql_add_catelog)
-	utils.CheckErr(err)
-	res, err := stmt.Exec(data.Name, data.Sort, data.Hide)
+// 未删除的分类，按树的顺序排列：上级分类后面紧跟它的子分类，同级分类按排序
+func GetAllCatelog() []types.Catelog {
+	results, err := repository.Catelogs().List()
 	utils.CheckErr(err)
-	_, err = res.LastInsertId()
+	return utils.SortCatelogTree(results)
+}
+
+func GetCatelogById(id int) (types.Catelog, bool) {
+	catelog, ok, err := repository.Catelogs().Get(id)
 	utils.CheckErr(err)
+	return catelog, ok
 }
 
-func GetAllCatelog() []types.Catelog {
-	sql_get_all := `
-		SELECT id,name,sort,hide FROM nav_catelog order by sort;
-	`
-	results := make([]types.Catelog, 0)
-	rows, err := database.DB.Query(sql_get_all)
+func GetCatelogByName(name string) (types.Catelog, bool) {
+	catelog, ok, err := repository.Catelogs().GetByName(name)
 	utils.CheckErr(err)
-	for rows.Next() {
-		var catelog types.Catelog
-		err = rows.Scan(&catelog.Id, &catelog.Name, &catelog.Sort, &catelog.Hide)
-		utils.CheckErr(err)
-		results = append(results, catelog)
-	}
-	defer rows.Close()
-	return results
+	return catelog, ok
 }
 
+// 更新分类排序，排序只在同一个上级分类下比较。指定了 ParentId 的分类同时移动到该上级分类下，
+// 调用前需要先用 CheckCatelogParents 检查
 func UpdateCatelogsSort(updates []types.UpdateCatelogsSortDto) error {
-	tx, err := database.DB.Begin()
-	if err != nil {
-		return err
+	return repository.Catelogs().UpdateSort(updates)
+}
+
+// 修改分类的上级分类，parentId 为 0 表示移动为顶级分类。调用前需要先用 CheckCatelogParents 检查
+func MoveCatelog(id int, parentId int) error {
+	return repository.Catelogs().Move(id, parentId)
+}
+
+// 检查把分类 id 的上级分类改为 moves[id] 之后的结构是否有效：分类和上级分类都存在，且不会形成环
+func CheckCatelogParents(moves map[int]int) error {
+	parents := make(map[int]int)
+	for _, catelog := range GetAllCatelog() {
+		parents[catelog.Id] = catelog.ParentId
+	}
+	for id, parentId := range moves {
+		if _, ok := parents[id]; !ok {
+			return errors.New("分类不存在")
+		}
+		if _, ok := parents[parentId]; parentId != 0 && !ok {
+			return errors.New("上级分类不存在")
+		}
+	}
+	for id, parentId := range moves {
+		parents[id] = parentId
 	}
+	for id := range moves {
+		// 沿着上级分类向上查找，回到自己说明形成了环
+		for current, depth := parents[id], 0; current != 0; current, depth = parents[current], depth+1 {
+			if current == id || depth > len(parents) {
+				return errors.New("不能把分类移动到它自己或它的子分类下")
+			}
+		}
+	}
+	return nil
+}
 
-	sql := `UPDATE nav_catelog SET sort = ? WHERE id = ?`
-	stmt, err := tx.Prepare(sql)
-	if err != nil {
-		tx.Rollback()
+// 把分类移入回收站。moveTo 不为 0 时其中的工具移动到该分类，否则一起移入回收站
+func DeleteCatelog(id int, moveTo int, actor types.Actor) error {
+	ids := toolIdsInCatelog(GetAllTool(), id)
+	if err := repository.Catelogs().Trash(id, time.Now().Unix(), moveTo); err != nil {
 		return err
 	}
-	defer stmt.Close()
+	if moveTo != 0 {
+		recordToolRevisions(actor, "update", ids...)
+	} else {
+		recordToolRevisions(actor, "delete", ids...)
+	}
+	return nil
+}
 
-	for _, update := range updates {
-		_, err = stmt.Exec(update.Sort, update.Id)
-		if err != nil {
-			tx.Rollback()
-			return err
+// 分类下未删除的工具数量
+func CountCatelogTools(id int) int {
+	return len(toolIdsInCatelog(GetAllTool(), id))
+}
+
+// 筛选出属于分类 catelogId 的工具 id
+func toolIdsInCatelog(tools []types.Tool, catelogId int) []int {
+	ids := make([]int, 0)
+	for _, tool := range tools {
+		if tool.CatelogId == catelogId {
+			ids = append(ids, tool.Id)
 		}
 	}
+	return ids
+}
 
-	return tx.Commit()
+// 确定工具所属的分类 id。传了名称时以名称为准，兼容只认识分类名称的旧接口：
+// id 对应的分类同名时使用 id，否则按名称查找，不存在时创建；只传 id 时分类必须存在。都没有传时返回 0
+func resolveCatelogId(id int, name string) (int, error) {
+	if name == "" {
+		if id == 0 {
+			return 0, nil
+		}
+		if _, ok := GetCatelogById(id); !ok {
+			return 0, errors.New("分类不存在")
+		}
+		return id, nil
+	}
+	if catelog, ok := GetCatelogById(id); ok && catelog.Name == name {
+		return id, nil
+	}
+	if catelog, ok := GetCatelogByName(name); ok {
+		return catelog.Id, nil
+	}
+	newId, err := repository.Catelogs().Add(types.AddCatelogDto{Name: name})
+	if err != nil {
+		return 0, err
+	}
+	logger.LogInfo("新增分类: %s", name)
+	return int(newId), nil
 }
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mereith/nav/types"
+)
+
+// 同名分类只在同一个上级分类下冲突
+func TestAddCatelogSiblingName(t *testing.T) {
+	openTestDB(t)
+	add := func(name string, parentId int) (int, error) {
+		id, err := AddCatelog(types.AddCatelogDto{Name: name, ParentId: parentId})
+		return int(id), err
+	}
+	dev, err := add("Dev", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := add("Ops", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		catelog  string
+		parentId int
+		ok       bool
+	}{
+		{"子分类", "Docs", dev, true},
+		{"另一个上级分类下的同名子分类", "Docs", ops, true},
+		{"同级重名的子分类", "Docs", dev, false},
+		{"与子分类同名的顶级分类", "Docs", 0, true},
+		{"同级重名的顶级分类", "Dev", 0, false},
+		{"与顶级分类同名的子分类", "Dev", ops, true},
+	}
+	for _, tt := range tests {
+		id, err := add(tt.catelog, tt.parentId)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok = %v", tt.name, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		catelog, ok := GetCatelogById(id)
+		if !ok || catelog.Name != tt.catelog || catelog.ParentId != tt.parentId {
+			t.Errorf("%s: 创建的分类 = %+v", tt.name, catelog)
+		}
+	}
+	if count := len(GetAllCatelog()); count != 6 {
+		t.Errorf("分类数量 = %d, want 6", count)
+	}
+}
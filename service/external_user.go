@@ -0,0 +1,148 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+// 解析 "group=role,group2=role2" 形式的组到角色映射
+func ParseRoleMapping(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(item, "=")
+		group = strings.TrimSpace(group)
+		role = strings.TrimSpace(role)
+		if !ok || group == "" {
+			return nil, errors.New("无效的角色映射: " + item)
+		}
+		if err := checkRole(role); err != nil {
+			return nil, err
+		}
+		mapping[group] = role
+	}
+	return mapping, nil
+}
+
+// 按映射取权限最高的角色，都没有匹配时返回 defaultRole
+func MapGroupsToRole(groups []string, mapping map[string]string, defaultRole string) string {
+	best := -1
+	for _, group := range groups {
+		role, ok := mapping[group]
+		if !ok {
+			continue
+		}
+		// types.Roles 按权限从高到低排列
+		for i, r := range types.Roles {
+			if r == role && (best == -1 || i < best) {
+				best = i
+			}
+		}
+	}
+	if best == -1 {
+		return defaultRole
+	}
+	return types.Roles[best]
+}
+
+// 反向代理认证没有 issuer，用这个固定值和用户名作为外部身份
+const proxyAuthIssuer = "proxy"
+
+// 外部身份（OIDC、反向代理）登录时对应到本地用户，只按 issuer + subject 查找已关联的用户，不按用户名匹配，
+// 避免 IdP 中同名的用户登录到本地账号。role 为空表示没有匹配的角色，拒绝登录。
+// 没有关联的用户时以 name 创建新用户，name 已被本地账号占用时拒绝，需要管理员先关联
+func ProvisionExternalUser(issuer string, subject string, name string, role string) (types.User, error) {
+	if issuer == "" || subject == "" {
+		return types.User{}, errors.New("外部身份缺少 issuer 或 subject")
+	}
+	user, ok, err := repository.Users().GetByExternal(issuer, subject)
+	if err != nil {
+		return types.User{}, err
+	}
+	if role == "" {
+		return types.User{}, errors.New("用户 " + name + " 未被授权访问")
+	}
+	if !ok {
+		if name == "" {
+			return user, errors.New("外部身份缺少用户名")
+		}
+		taken, err := repository.Users().NameTaken(name, 0)
+		if err != nil {
+			return user, err
+		}
+		if taken {
+			return user, errors.New("用户名 " + name + " 已被本地账号占用，需要管理员先关联外部身份")
+		}
+		// 外部用户不使用本地密码登录，设置一个随机密码
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return user, err
+		}
+		hash, err := utils.HashPassword(hex.EncodeToString(buf))
+		if err != nil {
+			return user, err
+		}
+		id, err := repository.Users().AddExternal(name, hash, role, issuer, subject)
+		if err != nil {
+			return user, err
+		}
+		logger.LogInfo("自动创建外部用户 %s，角色 %s", name, role)
+		user, _ = GetUserById(int(id))
+		return user, nil
+	}
+	if role != user.Role {
+		if role != types.RoleOwner {
+			last, err := isLastOwner(user)
+			if err != nil {
+				return user, err
+			}
+			if last {
+				// 不降级最后一个 owner，保持原角色登录
+				return user, nil
+			}
+		}
+		if err := updateUserRole(user.Id, role); err != nil {
+			return user, err
+		}
+		logger.LogInfo("同步外部用户 %s 的角色: %s -> %s", user.Name, user.Role, role)
+		user.Role = role
+	}
+	return user, nil
+}
+
+// 管理员把外部身份关联到已有用户，之后该身份登录时使用这个用户；subject 为空表示解除关联
+func SetUserExternalIdentity(id int, data types.UserExternalIdentityDto) error {
+	if _, ok := GetUserById(id); !ok {
+		return errors.New("用户不存在")
+	}
+	issuer := strings.TrimSpace(data.Issuer)
+	subject := strings.TrimSpace(data.Subject)
+	if subject == "" {
+		return repository.Users().UpdateExternal(id, "", "")
+	}
+	if issuer == "" {
+		return errors.New("issuer 不能为空")
+	}
+	other, ok, err := repository.Users().GetByExternal(issuer, subject)
+	if err != nil {
+		return err
+	}
+	if ok && other.Id != id {
+		return errors.New("该外部身份已关联到用户 " + other.Name)
+	}
+	return repository.Users().UpdateExternal(id, issuer, subject)
+}
+
+func updateUserRole(id int, role string) error {
+	return repository.Users().UpdateRole(id, role)
+}
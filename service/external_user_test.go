@@ -0,0 +1,65 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mereith/nav/types"
+)
+
+func TestParseRoleMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"空", "", map[string]string{}, false},
+		{"多个映射", "nav-admins=owner, nav-editors = editor", map[string]string{"nav-admins": "owner", "nav-editors": "editor"}, false},
+		{"忽略多余的逗号", ",nav-admins=owner,,", map[string]string{"nav-admins": "owner"}, false},
+		{"缺少等号", "nav-admins", nil, true},
+		{"组名为空", "=owner", nil, true},
+		{"不支持的角色", "nav-admins=root", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRoleMapping(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapGroupsToRole(t *testing.T) {
+	mapping := map[string]string{
+		"nav-admins":  types.RoleOwner,
+		"nav-editors": types.RoleEditor,
+		"nav-viewers": types.RoleViewer,
+	}
+	tests := []struct {
+		name        string
+		groups      []string
+		defaultRole string
+		want        string
+	}{
+		{"单个组", []string{"nav-editors"}, "", types.RoleEditor},
+		{"取权限最高的角色", []string{"nav-viewers", "nav-admins", "nav-editors"}, "", types.RoleOwner},
+		{"忽略没有映射的组", []string{"others", "nav-viewers"}, "", types.RoleViewer},
+		{"没有匹配时使用默认角色", []string{"others"}, types.RoleViewer, types.RoleViewer},
+		{"没有匹配且没有默认角色", []string{"others"}, "", ""},
+		{"没有组", nil, "", ""},
+		{"组名区分大小写", []string{"NAV-ADMINS"}, "", ""},
+		{"匹配时不使用默认角色", []string{"nav-viewers"}, types.RoleEditor, types.RoleViewer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MapGroupsToRole(tt.groups, mapping, tt.defaultRole); got != tt.want {
+				t.Errorf("MapGroupsToRole(%v) = %q, want %q", tt.groups, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+const secretGuest = "guest"
+
+// 访客会话有效期
+const GuestSessionTTL = 30 * 24 * time.Hour
+
+var (
+	guestSecret      []byte
+	guestSecretMutex sync.RWMutex
+)
+
+// 启动时加载访客会话签名密钥，首次启动时生成并保存
+func InitGuestSecret() {
+	secret, _ := getSecret(secretGuest)
+	if secret == "" {
+		if err := RotateGuestSecret(); err != nil {
+			utils.CheckErr(err)
+		}
+		return
+	}
+	guestSecretMutex.Lock()
+	guestSecret = []byte(secret)
+	guestSecretMutex.Unlock()
+}
+
+// 更换访客会话签名密钥，之前签发的所有访客会话立即失效
+func RotateGuestSecret() error {
+	secret := utils.RandomJWTKey()
+	if err := repository.Secrets().Save(newSecret(secretGuest, secret, time.Time{})); err != nil {
+		return err
+	}
+	guestSecretMutex.Lock()
+	guestSecret = []byte(secret)
+	guestSecretMutex.Unlock()
+	logger.LogInfo("访客会话密钥已更换，所有访客会话失效")
+	return nil
+}
+
+func signGuestPayload(payload string) string {
+	guestSecretMutex.RLock()
+	mac := hmac.New(sha256.New, guestSecret)
+	guestSecretMutex.RUnlock()
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// 签发访客会话，格式为 base64(payload).base64(hmac)
+func SignGuestSession(session types.GuestSession) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + signGuestPayload(payload), nil
+}
+
+// 校验访客会话的签名和有效期
+func ParseGuestSession(value string) (types.GuestSession, bool) {
+	var session types.GuestSession
+	payload, signature, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signGuestPayload(payload))) {
+		return session, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return session, false
+	}
+	if err = json.Unmarshal(data, &session); err != nil {
+		return session, false
+	}
+	if time.Now().Unix() >= session.ExpiresAt {
+		return session, false
+	}
+	return session, true
+}
+
+// 新建一个从现在开始计算有效期的访客会话
+func NewGuestSession() types.GuestSession {
+	now := time.Now()
+	return types.GuestSession{
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(GuestSessionTTL).Unix(),
+	}
+}
+
+func HasGuestPassword() bool {
+	return getGuestPasswordHash() != ""
+}
+
+func VerifyGuestPassword(password string) bool {
+	hash := getGuestPasswordHash()
+	if hash == "" {
+		return false
+	}
+	return utils.VerifyPassword(hash, password)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+func GetGuestLinks() []types.GuestLink {
+	results, err := repository.GuestLinks().List()
+	utils.CheckErr(err)
+	return results
+}
+
+// 获取未过期的邀请链接，用于校验由链接签发的访客会话
+func GetActiveGuestLink(id string) (types.GuestLink, bool) {
+	link, ok, err := repository.GuestLinks().Get(id)
+	if !ok {
+		utils.CheckErr(err)
+		return link, false
+	}
+	return link, time.Now().Unix() < link.ExpiresAt
+}
+
+// 创建邀请链接，返回只展示一次的完整 token
+func AddGuestLink(data types.AddGuestLinkDto) (string, types.GuestLink, error) {
+	var link types.GuestLink
+	if data.ExpiresInHours <= 0 {
+		return "", link, errors.New("有效期必须大于 0")
+	}
+	if data.MaxUses < 0 {
+		return "", link, errors.New("使用次数不能为负数")
+	}
+	token, prefix, hash, err := utils.GenerateGuestLinkToken()
+	if err != nil {
+		return "", link, err
+	}
+	now := time.Now()
+	link = types.GuestLink{
+		// id 由哈希再次哈希得到，只用于管理和会话关联，无法还原出 token
+		Id:         utils.HashApiToken(hash)[:16],
+		Name:       data.Name,
+		Prefix:     prefix,
+		CatelogIds: data.CatelogIds,
+		ExpiresAt:  now.Add(time.Duration(data.ExpiresInHours) * time.Hour).Unix(),
+		MaxUses:    data.MaxUses,
+		CreatedAt:  now.Unix(),
+	}
+	if link.CatelogIds == nil {
+		link.CatelogIds = make([]int, 0)
+	}
+	if err = repository.GuestLinks().Add(link, hash); err != nil {
+		return "", link, err
+	}
+	return token, link, nil
+}
+
+// 删除邀请链接，通过它获得的访客会话同时失效
+func DeleteGuestLink(id string) (int64, error) {
+	return repository.GuestLinks().Delete(id)
+}
+
+// 使用一次邀请链接，过期或次数用尽时返回错误
+func UseGuestLink(token string) (types.GuestLink, error) {
+	link, ok, err := repository.GuestLinks().Use(utils.HashApiToken(token), time.Now().Unix())
+	if err != nil {
+		return link, err
+	}
+	if !ok {
+		return link, errors.New("链接无效、已过期或已达到使用次数上限")
+	}
+	return link, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mereith/nav/types"
+)
+
+func TestGuestSessionSignature(t *testing.T) {
+	openTestDB(t)
+	InitGuestSecret()
+	now := time.Now().Unix()
+	valid, err := SignGuestSession(types.GuestSession{IssuedAt: now, ExpiresAt: now + 3600, LinkId: "link"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(valid, ".")
+	forged, err := SignGuestSession(types.GuestSession{IssuedAt: now, ExpiresAt: now + 3600*24*365})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	expired, err := SignGuestSession(types.GuestSession{IssuedAt: now - 7200, ExpiresAt: now - 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresNow, err := SignGuestSession(types.GuestSession{IssuedAt: now - 7200, ExpiresAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 签名不是合法 JSON 的 payload
+	garbage := base64.RawURLEncoding.EncodeToString([]byte("not json"))
+	garbageSigned := garbage + "." + signGuestPayload(garbage)
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"有效的会话", valid, true},
+		{"替换 payload", forgedPayload + "." + signature, false},
+		{"篡改签名", payload + "." + strings.Repeat("A", len(signature)), false},
+		{"缺少签名", payload, false},
+		{"空签名", payload + ".", false},
+		{"已过期", expired, false},
+		{"恰好到期", expiresNow, false},
+		{"payload 不是 JSON", garbageSigned, false},
+		{"空值", "", false},
+		{"随机内容", "abc.def", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, ok := ParseGuestSession(tt.value)
+			if ok != tt.want {
+				t.Fatalf("ParseGuestSession = %v, want %v", ok, tt.want)
+			}
+			if ok && (session.LinkId != "link" || session.ExpiresAt != now+3600) {
+				t.Errorf("session = %+v", session)
+			}
+		})
+	}
+}
+
+func TestGuestSessionRotate(t *testing.T) {
+	openTestDB(t)
+	InitGuestSecret()
+	value, err := SignGuestSession(NewGuestSession())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 重新加载密钥后之前的会话仍然有效
+	InitGuestSecret()
+	if _, ok := ParseGuestSession(value); !ok {
+		t.Fatal("重新加载密钥后会话失效")
+	}
+	if err := RotateGuestSecret(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ParseGuestSession(value); ok {
+		t.Error("更换密钥后之前的会话仍然有效")
+	}
+	value, err = SignGuestSession(NewGuestSession())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ParseGuestSession(value); !ok {
+		t.Error("更换密钥后签发的会话无效")
+	}
+}
+
+func TestNewGuestSession(t *testing.T) {
+	session := NewGuestSession()
+	if got := time.Duration(session.ExpiresAt-session.IssuedAt) * time.Second; got != GuestSessionTTL {
+		t.Errorf("有效期 = %v, want %v", got, GuestSessionTTL)
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"net/url"
 	"strings"
 
-	"github.com/mereith/nav/database"
 	"github.com/mereith/nav/goscraper"
 	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
 	"github.com/mereith/nav/types"
 	"github.com/mereith/nav/utils"
 )
@@ -46,19 +46,8 @@ func LazyFetchLogo(url string, id int64) {
 
 func GetImgFromDB(url1 string) types.Img {
 	urlEncoded := url.QueryEscape(url1)
-	sql_get_img := `
-		SELECT id,url,value FROM nav_img
-		WHERE url=?;
-		`
-	rows, err := database.DB.Query(sql_get_img, urlEncoded)
+	result, has, err := repository.Images().Get(urlEncoded)
 	utils.CheckErr(err)
-	var result types.Img
-	var has bool = false
-	for rows.Next() {
-		err = rows.Scan(&result.Id, &result.Url, &result.Value)
-		utils.CheckErr(err)
-		has = true
-	}
 	if !has {
 		var nullImg string
 		l := strings.Split(url1, ".")
@@ -72,7 +61,6 @@ func GetImgFromDB(url1 string) types.Img {
 		return types.Img{Id: 0, Url: url1, Value: nullImg}
 	}
 
-	defer rows.Close()
 	return result
 }
 
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/utils"
+)
+
+// 登录防爆破配置
+type LoginGuardConfig struct {
+	// 连续失败多少次后开始锁定
+	MaxAttempts int
+	// 首次锁定时长，之后每多失败一次翻倍
+	Lockout time.Duration
+	// 最长锁定时长，超过该时长没有新的失败记录时计数清零
+	MaxLockout time.Duration
+}
+
+var loginGuard = LoginGuardConfig{
+	MaxAttempts: 5,
+	Lockout:     time.Minute,
+	MaxLockout:  time.Hour,
+}
+
+func SetLoginGuardConfig(config LoginGuardConfig) {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	if config.Lockout <= 0 {
+		config.Lockout = time.Second
+	}
+	if config.MaxLockout < config.Lockout {
+		config.MaxLockout = config.Lockout
+	}
+	loginGuard = config
+}
+
+func LoginIpKey(scope string, ip string) string {
+	return scope + ":ip:" + ip
+}
+
+func LoginUserKey(name string) string {
+	return "login:user:" + name
+}
+
+// 返回给定 key 中最长的剩余锁定时间，没有被锁定时返回 0
+func LoginLockedFor(keys ...string) time.Duration {
+	now := time.Now().Unix()
+	var longest int64
+	for _, key := range keys {
+		attempt, ok, err := repository.LoginAttempts().Get(key)
+		if !ok {
+			utils.CheckErr(err)
+			continue
+		}
+		if attempt.LockedUntil-now > longest {
+			longest = attempt.LockedUntil - now
+		}
+	}
+	return time.Duration(longest) * time.Second
+}
+
+// 记录一次失败，达到阈值后按指数退避锁定
+func RecordLoginFailure(keys ...string) {
+	now := time.Now()
+	for _, key := range keys {
+		attempt, _, err := repository.LoginAttempts().Get(key)
+		if err != nil {
+			utils.CheckErr(err)
+			continue
+		}
+		// 距离上次失败已经很久了，重新计数
+		if now.Sub(time.Unix(attempt.LastFailureAt, 0)) > loginGuard.MaxLockout {
+			attempt.Failures = 0
+		}
+		attempt.Failures++
+		attempt.LastFailureAt = now.Unix()
+		attempt.LockedUntil = 0
+		if attempt.Failures >= loginGuard.MaxAttempts {
+			attempt.LockedUntil = now.Add(lockoutDuration(attempt.Failures - loginGuard.MaxAttempts)).Unix()
+		}
+		utils.CheckErr(repository.LoginAttempts().Save(attempt))
+	}
+}
+
+func lockoutDuration(exceeded int) time.Duration {
+	if exceeded > 30 {
+		return loginGuard.MaxLockout
+	}
+	duration := time.Duration(float64(loginGuard.Lockout) * math.Pow(2, float64(exceeded)))
+	if duration > loginGuard.MaxLockout {
+		return loginGuard.MaxLockout
+	}
+	return duration
+}
+
+// 登录成功后清除失败记录
+func ResetLoginFailures(keys ...string) {
+	for _, key := range keys {
+		utils.CheckErr(repository.LoginAttempts().Delete(key))
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/mereith/nav/types"
+	"golang.org/x/oauth2"
+)
+
+// OIDC 单点登录配置
+type OIDCConfig struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	// 回调地址，形如 https://nav.example.com/api/oidc/callback
+	RedirectURL string
+	Scopes      []string
+	// 自动创建用户时作为本地用户名的 claim，例如 preferred_username 或 email。
+	// 登录时只按 issuer + sub 查找已关联的用户，不按用户名匹配
+	UserClaim string
+	// 包含用户组的 claim
+	GroupsClaim string
+	// 组到角色的映射
+	RoleMapping map[string]string
+	// 没有匹配到组时的角色，为空表示拒绝登录
+	DefaultRole string
+}
+
+var (
+	oidcConfig   OIDCConfig
+	oidcProvider *oidc.Provider
+	oidcMutex    sync.Mutex
+)
+
+func SetOIDCConfig(config OIDCConfig) error {
+	if config.DefaultRole != "" {
+		if err := checkRole(config.DefaultRole); err != nil {
+			return err
+		}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if config.UserClaim == "" {
+		config.UserClaim = "preferred_username"
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	oidcMutex.Lock()
+	defer oidcMutex.Unlock()
+	oidcConfig = config
+	oidcProvider = nil
+	return nil
+}
+
+func OIDCEnabled() bool {
+	return oidcConfig.Issuer != "" && oidcConfig.ClientId != "" && oidcConfig.RedirectURL != ""
+}
+
+// 首次使用时才请求 issuer 的发现文档，失败时下次请求重试，避免 IdP 暂时不可用导致无法启动
+func getOIDCProvider(ctx context.Context) (*oidc.Provider, error) {
+	oidcMutex.Lock()
+	defer oidcMutex.Unlock()
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+	provider, err := oidc.NewProvider(ctx, oidcConfig.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	oidcProvider = provider
+	return provider, nil
+}
+
+func oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     oidcConfig.ClientId,
+		ClientSecret: oidcConfig.ClientSecret,
+		RedirectURL:  oidcConfig.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       oidcConfig.Scopes,
+	}
+}
+
+// 生成一次登录流程所需的 state、nonce 和 PKCE verifier
+func NewOIDCLoginState() (state string, nonce string, verifier string) {
+	return oauth2.GenerateVerifier(), oauth2.GenerateVerifier(), oauth2.GenerateVerifier()
+}
+
+// 生成跳转到 IdP 的授权地址，使用 PKCE(S256) 和 nonce
+func OIDCAuthURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	if !OIDCEnabled() {
+		return "", errors.New("未启用 OIDC 登录")
+	}
+	provider, err := getOIDCProvider(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauth2Config(provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// 用授权码换取 id_token，校验签名和 nonce 后映射为本地用户
+func OIDCExchange(ctx context.Context, code string, nonce string, verifier string) (types.User, error) {
+	if !OIDCEnabled() {
+		return types.User{}, errors.New("未启用 OIDC 登录")
+	}
+	provider, err := getOIDCProvider(ctx)
+	if err != nil {
+		return types.User{}, err
+	}
+	token, err := oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return types.User{}, err
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return types.User{}, errors.New("IdP 未返回 id_token")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: oidcConfig.ClientId}).Verify(ctx, rawIdToken)
+	if err != nil {
+		return types.User{}, err
+	}
+	if idToken.Nonce != nonce {
+		return types.User{}, errors.New("id_token nonce 不匹配")
+	}
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return types.User{}, err
+	}
+	name, _ := claims[oidcConfig.UserClaim].(string)
+	if name == "" {
+		return types.User{}, fmt.Errorf("id_token 中缺少 %s", oidcConfig.UserClaim)
+	}
+	if oidcConfig.UserClaim == "email" {
+		if verified, ok := claims["email_verified"].(bool); ok && !verified {
+			return types.User{}, errors.New("邮箱未验证")
+		}
+	}
+	role := MapGroupsToRole(claimStrings(claims[oidcConfig.GroupsClaim]), oidcConfig.RoleMapping, oidcConfig.DefaultRole)
+	return ProvisionExternalUser(idToken.Issuer, idToken.Subject, name, role)
+}
+
+// 组 claim 可能是数组，也可能是逗号分隔的字符串
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	case string:
+		return strings.Split(v, ",")
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/mereith/nav/types"
+	"golang.org/x/oauth2"
+)
+
+const testOIDCClientId = "nav"
+
+// 模拟的 OIDC issuer，提供发现文档、JWKS 和校验 PKCE 的 token 端点
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	mutex  sync.Mutex
+	// 授权码对应的 code_challenge 和 id_token claims
+	codes map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{key: key, codes: make(map[string]mockAuthorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", m.handleToken)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// 授权码只能使用一次，code_verifier 必须与授权请求中的 S256 challenge 对应
+func (m *mockIssuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m.mutex.Lock()
+	auth, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mutex.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, auth.claims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// 模拟用户在 IdP 完成登录：从授权地址中取出 challenge 和 nonce，返回授权码
+func (m *mockIssuer) authorize(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("授权请求没有使用 S256 PKCE: %s", authURL)
+	}
+	now := time.Now()
+	full := jwt.MapClaims{
+		"iss":   m.server.URL,
+		"aud":   testOIDCClientId,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for k, v := range claims {
+		full[k] = v
+	}
+	code := oauth2.GenerateVerifier()
+	m.mutex.Lock()
+	m.codes[code] = mockAuthorization{challenge: query.Get("code_challenge"), claims: full}
+	m.mutex.Unlock()
+	return code
+}
+
+func setupMockOIDC(t *testing.T, defaultRole string) *mockIssuer {
+	t.Helper()
+	openTestDB(t)
+	m := newMockIssuer(t)
+	err := SetOIDCConfig(OIDCConfig{
+		Issuer:      m.server.URL,
+		ClientId:    testOIDCClientId,
+		RedirectURL: "http://nav.test/api/oidc/callback",
+		RoleMapping: map[string]string{"nav-admins": types.RoleOwner, "nav-editors": types.RoleEditor},
+		DefaultRole: defaultRole,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		SetOIDCConfig(OIDCConfig{})
+	})
+	return m
+}
+
+// 走一遍完整的登录流程，verifier 和 nonce 可以被替换以模拟攻击
+func (m *mockIssuer) login(t *testing.T, claims jwt.MapClaims, tamper func(nonce, verifier *string)) (types.User, error) {
+	t.Helper()
+	ctx := context.Background()
+	state, nonce, verifier := NewOIDCLoginState()
+	authURL, err := OIDCAuthURL(ctx, state, nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := m.authorize(t, authURL, claims)
+	if tamper != nil {
+		tamper(&nonce, &verifier)
+	}
+	return OIDCExchange(ctx, code, nonce, verifier)
+}
+
+func TestOIDCExchangeProvision(t *testing.T) {
+	m := setupMockOIDC(t, "")
+	claims := jwt.MapClaims{"sub": "u-1001", "preferred_username": "alice", "groups": []string{"nav-editors"}}
+
+	user, err := m.login(t, claims, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "alice" || user.Role != types.RoleEditor {
+		t.Fatalf("新用户 = %s/%s, want alice/editor", user.Name, user.Role)
+	}
+	if user.ExternalIssuer != m.server.URL || user.ExternalSubject != "u-1001" {
+		t.Errorf("外部身份 = %s/%s", user.ExternalIssuer, user.ExternalSubject)
+	}
+
+	// 再次登录时按 sub 找到同一个用户，用户名变化也不影响，角色同步为映射的最高权限
+	claims["preferred_username"] = "alice-renamed"
+	claims["groups"] = []string{"nav-editors", "nav-admins"}
+	again, err := m.login(t, claims, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Id != user.Id || again.Role != types.RoleOwner {
+		t.Errorf("再次登录 = %d/%s, want %d/owner", again.Id, again.Role, user.Id)
+	}
+
+	// 没有匹配的组时已有的外部用户也不能登录
+	claims["groups"] = []string{"others"}
+	if _, err := m.login(t, claims, nil); err == nil {
+		t.Error("没有匹配角色的已有用户登录成功")
+	}
+	stored, _ := GetUserById(user.Id)
+	if stored.Role != types.RoleOwner {
+		t.Errorf("拒绝登录后角色被修改为 %s", stored.Role)
+	}
+}
+
+func TestOIDCExchangeNameCollision(t *testing.T) {
+	m := setupMockOIDC(t, types.RoleViewer)
+	admin := adminId(t)
+	claims := jwt.MapClaims{"sub": "attacker", "preferred_username": "admin", "groups": []string{"nav-admins"}}
+
+	// IdP 中同名的用户不能登录到本地的 admin
+	if user, err := m.login(t, claims, nil); err == nil {
+		t.Fatalf("同名的外部用户登录到了 %s(%d)", user.Name, user.Id)
+	}
+
+	// 管理员显式关联后才可以
+	err := SetUserExternalIdentity(admin, types.UserExternalIdentityDto{Issuer: m.server.URL, Subject: "admin-sub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims["sub"] = "admin-sub"
+	user, err := m.login(t, claims, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != admin {
+		t.Errorf("关联后登录到了用户 %d, want %d", user.Id, admin)
+	}
+
+	// 同一个外部身份不能关联到两个用户
+	other, err := AddUser(types.AddUserDto{Name: "bob", Password: "bob", Role: types.RoleViewer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetUserExternalIdentity(int(other), types.UserExternalIdentityDto{Issuer: m.server.URL, Subject: "admin-sub"}); err == nil {
+		t.Error("同一个外部身份关联到了两个用户")
+	}
+
+	// 解除关联后不能再登录
+	if err := SetUserExternalIdentity(admin, types.UserExternalIdentityDto{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.login(t, claims, nil); err == nil {
+		t.Error("解除关联后仍然登录到了 admin")
+	}
+}
+
+func TestOIDCExchangeReject(t *testing.T) {
+	m := setupMockOIDC(t, "")
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		tamper func(nonce, verifier *string)
+		// 错误信息中应包含的内容，确认是因为预期的原因被拒绝
+		err string
+	}{
+		{"没有匹配的组", jwt.MapClaims{"sub": "u-1", "preferred_username": "u1", "groups": []string{"others"}}, nil, "未被授权"},
+		{"没有组", jwt.MapClaims{"sub": "u-2", "preferred_username": "u2"}, nil, "未被授权"},
+		{"PKCE verifier 不对", jwt.MapClaims{"sub": "u-3", "preferred_username": "u3", "groups": "nav-editors"}, func(nonce, verifier *string) {
+			*verifier = strings.Repeat("x", 43)
+		}, "invalid_grant"},
+		{"nonce 不对", jwt.MapClaims{"sub": "u-4", "preferred_username": "u4", "groups": "nav-editors"}, func(nonce, verifier *string) {
+			*nonce = "other"
+		}, "nonce"},
+		{"audience 不对", jwt.MapClaims{"sub": "u-5", "preferred_username": "u5", "groups": "nav-editors", "aud": "other-client"}, nil, "audience"},
+		{"issuer 不对", jwt.MapClaims{"sub": "u-6", "preferred_username": "u6", "groups": "nav-editors", "iss": "https://evil.example.com"}, nil, "different provider"},
+		{"已过期", jwt.MapClaims{"sub": "u-7", "preferred_username": "u7", "groups": "nav-editors", "exp": time.Now().Add(-time.Hour).Unix()}, nil, "expired"},
+		{"缺少用户名", jwt.MapClaims{"sub": "u-8", "groups": "nav-editors"}, nil, "preferred_username"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := m.login(t, tt.claims, tt.tamper)
+			if err == nil {
+				t.Fatalf("登录成功: %s", user.Name)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+	if users := GetUsers(); len(users) != 1 {
+		t.Errorf("被拒绝的登录创建了用户，共 %d 个", len(users))
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/types"
+)
+
+// 反向代理（Authelia、oauth2-proxy 等）身份头认证配置
+type ProxyAuthConfig struct {
+	// 用户名所在的请求头，为空表示不启用
+	UserHeader string
+	// 用户组所在的请求头，逗号分隔
+	GroupsHeader string
+	// 只信任来自这些地址的身份头
+	TrustedProxies []*net.IPNet
+	RoleMapping    map[string]string
+	// 没有匹配到组时的角色，为空表示拒绝登录
+	DefaultRole string
+}
+
+var proxyAuthConfig ProxyAuthConfig
+
+func SetProxyAuthConfig(config ProxyAuthConfig) error {
+	if config.UserHeader != "" && len(config.TrustedProxies) == 0 {
+		return errors.New("启用反向代理认证时必须配置受信任的代理地址")
+	}
+	if config.DefaultRole != "" {
+		if err := checkRole(config.DefaultRole); err != nil {
+			return err
+		}
+	}
+	proxyAuthConfig = config
+	return nil
+}
+
+func ProxyAuthEnabled() bool {
+	return proxyAuthConfig.UserHeader != ""
+}
+
+// 解析逗号分隔的 CIDR 列表，单个 IP 视为 /32 或 /128
+func ParseCIDRs(value string) ([]*net.IPNet, error) {
+	result := make([]*net.IPNet, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, errors.New("无效的地址: " + item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			result = append(result, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, network)
+	}
+	return result, nil
+}
+
+// 判断直接连接的对端地址是否为受信任的代理，这里不能使用 X-Forwarded-For
+func IsTrustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range proxyAuthConfig.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 从受信任代理传来的身份头中获取用户，没有身份头或来源不可信时返回 false
+func ProxyAuthUser(remoteAddr string, header http.Header) (types.User, bool) {
+	if !ProxyAuthEnabled() {
+		return types.User{}, false
+	}
+	name := strings.TrimSpace(header.Get(proxyAuthConfig.UserHeader))
+	if name == "" || !IsTrustedProxy(remoteAddr) {
+		return types.User{}, false
+	}
+	var groups []string
+	if proxyAuthConfig.GroupsHeader != "" {
+		for _, group := range strings.Split(header.Get(proxyAuthConfig.GroupsHeader), ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+	role := MapGroupsToRole(groups, proxyAuthConfig.RoleMapping, proxyAuthConfig.DefaultRole)
+	user, err := ProvisionExternalUser(proxyAuthIssuer, name, name, role)
+	if err != nil {
+		logger.LogError("反向代理认证失败: %v", err)
+		return user, false
+	}
+	return user, true
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mereith/nav/types"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"空", "", []string{}, false},
+		{"CIDR", "10.0.0.0/8, 192.168.1.0/24", []string{"10.0.0.0/8", "192.168.1.0/24"}, false},
+		{"单个 IPv4 视为 /32", "127.0.0.1", []string{"127.0.0.1/32"}, false},
+		{"单个 IPv6 视为 /128", "::1", []string{"::1/128"}, false},
+		{"IPv6 CIDR", "fd00::/8", []string{"fd00::/8"}, false},
+		{"CIDR 按网络地址归一", "10.1.2.3/8", []string{"10.0.0.0/8"}, false},
+		{"无效的地址", "10.0.0.256", nil, true},
+		{"无效的 CIDR", "10.0.0.0/33", nil, true},
+		{"主机名", "localhost", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCIDRs(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i, network := range got {
+				if network.String() != tt.want[i] {
+					t.Errorf("got %s, want %s", network, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func setTestProxyAuthConfig(t *testing.T, trusted string, defaultRole string) {
+	t.Helper()
+	networks, err := ParseCIDRs(trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SetProxyAuthConfig(ProxyAuthConfig{
+		UserHeader:     "Remote-User",
+		GroupsHeader:   "Remote-Groups",
+		TrustedProxies: networks,
+		RoleMapping:    map[string]string{"nav-admins": types.RoleOwner},
+		DefaultRole:    defaultRole,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		proxyAuthConfig = ProxyAuthConfig{}
+	})
+}
+
+func TestIsTrustedProxy(t *testing.T) {
+	setTestProxyAuthConfig(t, "10.0.0.0/8,127.0.0.1,fd00::/8", "")
+	tests := []struct {
+		remoteAddr string
+		want       bool
+	}{
+		{"10.1.2.3:50000", true},
+		{"10.255.255.255:1", true},
+		{"11.0.0.1:50000", false},
+		{"127.0.0.1:8080", true},
+		{"127.0.0.2:8080", false},
+		{"[fd12::1]:443", true},
+		{"[fe80::1]:443", false},
+		{"[::1]:443", false},
+		// 没有端口时直接按地址判断
+		{"10.0.0.1", true},
+		// IPv4 映射的 IPv6 地址按 IPv4 判断
+		{"[::ffff:10.0.0.1]:80", true},
+		{"", false},
+		{"not-an-ip:80", false},
+	}
+	for _, tt := range tests {
+		if got := IsTrustedProxy(tt.remoteAddr); got != tt.want {
+			t.Errorf("IsTrustedProxy(%q) = %v, want %v", tt.remoteAddr, got, tt.want)
+		}
+	}
+}
+
+func TestSetProxyAuthConfigRequiresTrustedProxies(t *testing.T) {
+	t.Cleanup(func() {
+		proxyAuthConfig = ProxyAuthConfig{}
+	})
+	if err := SetProxyAuthConfig(ProxyAuthConfig{UserHeader: "Remote-User"}); err == nil {
+		t.Error("没有受信任的代理时启用了反向代理认证")
+	}
+}
+
+func TestProxyAuthUser(t *testing.T) {
+	openTestDB(t)
+	setTestProxyAuthConfig(t, "10.0.0.0/8", "")
+	header := http.Header{}
+	header.Set("Remote-User", "carol")
+	header.Set("Remote-Groups", "others, nav-admins")
+
+	// 不是来自受信任代理的身份头被忽略
+	if _, ok := ProxyAuthUser("192.168.1.10:5000", header); ok {
+		t.Fatal("信任了来自不受信任地址的身份头")
+	}
+	user, ok := ProxyAuthUser("10.0.0.5:5000", header)
+	if !ok {
+		t.Fatal("来自受信任代理的身份头没有通过")
+	}
+	if user.Name != "carol" || user.Role != types.RoleOwner || user.ExternalIssuer != proxyAuthIssuer || user.ExternalSubject != "carol" {
+		t.Errorf("user = %+v", user)
+	}
+
+	// 与本地账号同名时不会登录到本地账号
+	header.Set("Remote-User", "admin")
+	if _, ok := ProxyAuthUser("10.0.0.5:5000", header); ok {
+		t.Error("反向代理的同名用户登录到了本地 admin")
+	}
+
+	// 没有匹配的组时拒绝
+	header.Set("Remote-User", "carol")
+	header.Set("Remote-Groups", "others")
+	if _, ok := ProxyAuthUser("10.0.0.5:5000", header); ok {
+		t.Error("没有匹配角色的用户通过了反向代理认证")
+	}
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+// 按相关度排序的搜索结果，包括隐藏的工具，由调用方按访问权限过滤
+func SearchTools(keyword string) []types.ToolSearchResult {
+	results, err := repository.Tools().Search(keyword)
+	utils.CheckErr(err)
+	return results
+}
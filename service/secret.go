@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+const (
+	secretJWT         = "jwt"
+	secretJWTPrevious = "jwt_previous"
+)
+
+// 为 true 时 JWT 密钥来自环境变量或启动参数，不允许在线轮换
+var jwtSecretOverridden bool
+
+// 读取一条密钥，不存在或已过期时返回空字符串
+func getSecret(name string) (string, time.Time) {
+	secret, ok, err := repository.Secrets().Get(name)
+	if !ok {
+		utils.CheckErr(err)
+		return "", time.Time{}
+	}
+	if secret.ExpiresAt == 0 {
+		return secret.Value, time.Time{}
+	}
+	expires := time.Unix(secret.ExpiresAt, 0)
+	if time.Now().After(expires) {
+		return "", time.Time{}
+	}
+	return secret.Value, expires
+}
+
+// 要保存的密钥，expiresAt 为零值表示永不过期
+func newSecret(name string, value string, expiresAt time.Time) types.Secret {
+	secret := types.Secret{Name: name, Value: value, CreatedAt: time.Now().Unix()}
+	if !expiresAt.IsZero() {
+		secret.ExpiresAt = expiresAt.Unix()
+	}
+	return secret
+}
+
+// 启动时加载 JWT 签名密钥：优先使用配置指定的密钥，否则读取数据库，首次启动时生成并保存
+func InitJWTSecret(override string) {
+	if override != "" {
+		jwtSecretOverridden = true
+		utils.SetJWTSecret(override)
+		logger.LogInfo("使用配置指定的 JWT 密钥")
+		return
+	}
+	secret, _ := getSecret(secretJWT)
+	if secret == "" {
+		secret = utils.RandomJWTKey()
+		utils.CheckErr(repository.Secrets().Save(newSecret(secretJWT, secret, time.Time{})))
+		logger.LogInfo("已生成新的 JWT 密钥")
+	}
+	utils.SetJWTSecret(secret)
+	previous, expiresAt := getSecret(secretJWTPrevious)
+	utils.SetPreviousJWTSecret(previous, expiresAt)
+}
+
+// 轮换 JWT 密钥，旧密钥在宽限期内仍然有效
+func RotateJWTSecret(grace time.Duration) error {
+	if jwtSecretOverridden {
+		return errors.New("JWT 密钥由环境变量或启动参数指定，无法在线轮换")
+	}
+	current, _ := getSecret(secretJWT)
+	secret := utils.RandomJWTKey()
+	expiresAt := time.Now().Add(grace)
+
+	// 没有宽限期时删除旧密钥
+	previous := newSecret(secretJWTPrevious, "", time.Time{})
+	if current != "" && grace > 0 {
+		previous = newSecret(secretJWTPrevious, current, expiresAt)
+	}
+	if err := repository.Secrets().Save(previous, newSecret(secretJWT, secret, time.Time{})); err != nil {
+		return err
+	}
+
+	utils.SetJWTSecret(secret)
+	if current != "" && grace > 0 {
+		utils.SetPreviousJWTSecret(current, expiresAt)
+	} else {
+		utils.SetPreviousJWTSecret("", time.Time{})
+	}
+	logger.LogInfo("JWT 密钥已轮换，旧密钥宽限期: %s", grace)
+	return nil
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/repository"
+)
+
+// 在临时目录中初始化一个完整的数据库（包含默认的 admin 用户），测试结束后关闭
+func openTestDB(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	database.Configure(dir, filepath.Join(dir, "nav.db"))
+	database.InitDB()
+	repository.Init(database.DB)
+	t.Cleanup(func() {
+		database.DB.Close()
+	})
+}
+
+func mustExec(t *testing.T, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := database.DB.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+func adminId(t *testing.T) int {
+	t.Helper()
+	var id int
+	if err := database.DB.QueryRow(`SELECT id FROM nav_user WHERE name = 'admin';`).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
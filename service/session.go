@@ -0,0 +1,80 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+// 登录会话有效期
+const sessionTTL = time.Hour * 24 * 30
+
+func newSessionId() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// 为用户创建一个登录会话并签发 JWT
+func CreateSession(user types.User, ip string, userAgent string) (string, error) {
+	jti, err := newSessionId()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	expiresAt := now.Add(sessionTTL)
+	token, err := utils.SignJWT(user, jti, expiresAt)
+	if err != nil {
+		return "", err
+	}
+	// 顺便清理已过期的会话
+	utils.CheckErr(repository.Sessions().DeleteExpired(now.Unix()))
+	err = repository.Sessions().Add(types.Session{
+		Id:        jti,
+		UserId:    user.Id,
+		Ip:        ip,
+		UserAgent: userAgent,
+		CreatedAt: now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// 会话存在、未过期且未被撤销
+func IsSessionActive(jti string) bool {
+	active, err := repository.Sessions().IsActive(jti, time.Now().Unix())
+	if err != nil {
+		utils.CheckErr(err)
+		return false
+	}
+	return active
+}
+
+// 获取所有有效会话，currentJti 对应的会话会被标记出来
+func GetActiveSessions(currentJti string) []types.Session {
+	results, err := repository.Sessions().ListActive(time.Now().Unix())
+	utils.CheckErr(err)
+	for i := range results {
+		results[i].Current = results[i].Id == currentJti
+	}
+	return results
+}
+
+// 撤销指定会话
+func RevokeSession(jti string) (int64, error) {
+	return repository.Sessions().Revoke(jti, time.Now().Unix())
+}
+
+// 撤销所有会话，exceptJti 不为空时保留该会话
+func RevokeAllSessions(exceptJti string) (int64, error) {
+	return repository.Sessions().RevokeAll(exceptJti, time.Now().Unix())
+}
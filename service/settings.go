@@ -1,31 +1,14 @@
 package service
 
 import (
-	"database/sql"
-
-	"github.com/mereith/nav/database"
 	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
 	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
 )
 
 func GetSetting() types.Setting {
-	sql_get_user := `
-		SELECT id,favicon,title,govRecord,logo192,logo512,hideAdmin,hideGithub,jumpTargetBlank,customJS,customCSS,guestPassword
-		FROM nav_setting 
-		ORDER BY id ASC 
-		LIMIT 1;
-		`
-	var setting types.Setting
-	row := database.DB.QueryRow(sql_get_user)
-	// 建立一个空变量
-	var hideGithub interface{}
-	var hideAdmin interface{}
-	var jumpTargetBlank interface{}
-	var customJS sql.NullString
-	var customCSS sql.NullString
-	var guestPassword sql.NullString
-
-	err := row.Scan(&setting.Id, &setting.Favicon, &setting.Title, &setting.GovRecord, &setting.Logo192, &setting.Logo512, &hideAdmin, &hideGithub, &jumpTargetBlank, &customJS, &customCSS, &guestPassword)
+	setting, err := repository.Settings().Get()
 	if err != nil {
 		logger.LogError("获取配置失败: %s", err)
 		return types.Setting{
@@ -40,63 +23,20 @@ func GetSetting() types.Setting {
 			JumpTargetBlank: true,
 		}
 	}
-	if hideGithub == nil {
-		setting.HideGithub = false
-	} else {
-		if hideGithub.(int64) == 0 {
-			setting.HideGithub = false
-		} else {
-			setting.HideGithub = true
-		}
-	}
-	if hideAdmin == nil {
-		setting.HideAdmin = false
-	} else {
-		if hideAdmin.(int64) == 0 {
-			setting.HideAdmin = false
-		} else {
-			setting.HideAdmin = true
-		}
-	}
-
-	if jumpTargetBlank == nil {
-		setting.JumpTargetBlank = true
-	} else {
-		if jumpTargetBlank.(int64) == 0 {
-			setting.JumpTargetBlank = false
-		} else {
-			setting.JumpTargetBlank = true
-		}
-	}
-
-	if customJS.Valid {
-		setting.CustomJS = customJS.String
-	}
-	if customCSS.Valid {
-		setting.CustomCSS = customCSS.String
-	}
 	// Mask the password for security
-	if guestPassword.Valid && guestPassword.String != "" {
+	if setting.GuestPassword != "" {
 		setting.GuestPassword = "********"
-	} else {
-		setting.GuestPassword = ""
 	}
-
 	return setting
 }
 
-// Internal function to get real password
-func GetRealGuestPassword() string {
-	sql_get := `SELECT guestPassword FROM nav_setting ORDER BY id ASC LIMIT 1`
-	var guestPassword sql.NullString
-	err := database.DB.QueryRow(sql_get).Scan(&guestPassword)
+// 读取访客密码的哈希，未设置时返回空字符串
+func getGuestPasswordHash() string {
+	hash, err := repository.Settings().GuestPasswordHash()
 	if err != nil {
 		return ""
 	}
-	if guestPassword.Valid {
-		return guestPassword.String
-	}
-	return ""
+	return hash
 }
 
 func UpdateSetting(data types.Setting) error {
@@ -108,29 +48,29 @@ func UpdateSetting(data types.Setting) error {
 	// For simplicity: If user sends "********", we keep old password.
 	// If user sends anything else, we update it. (Empty string clears it)
 
-	currentRealPwd := GetRealGuestPassword()
+	currentHash := getGuestPasswordHash()
 	newPwd := data.GuestPassword
+	passwordChanged := false
 	if newPwd == "********" {
-		newPwd = currentRealPwd
+		newPwd = currentHash
+	} else if newPwd != "" {
+		hash, err := utils.HashPassword(newPwd)
+		if err != nil {
+			return err
+		}
+		newPwd = hash
+		passwordChanged = true
+	} else {
+		passwordChanged = currentHash != ""
 	}
 
-	sql_update_setting := `
-		UPDATE nav_setting
-		SET favicon = ?, title = ?, govRecord = ?, logo192 = ?, logo512 = ?, hideAdmin = ?, hideGithub = ?, jumpTargetBlank = ?, customJS = ?, customCSS = ?, guestPassword = ?
-		WHERE id = (SELECT id FROM nav_setting ORDER BY id ASC LIMIT 1);
-		`
-
-	stmt, err := database.DB.Prepare(sql_update_setting)
-	if err != nil {
-		return err
-	}
-	res, err := stmt.Exec(data.Favicon, data.Title, data.GovRecord, data.Logo192, data.Logo512, data.HideAdmin, data.HideGithub, data.JumpTargetBlank, data.CustomJS, data.CustomCSS, newPwd)
-	if err != nil {
+	data.GuestPassword = newPwd
+	if err := repository.Settings().Update(data); err != nil {
 		return err
 	}
-	_, err = res.RowsAffected()
-	if err != nil {
-		return err
+	// 修改或清除访客密码后，之前的访客会话全部失效
+	if passwordChanged {
+		return RotateGuestSecret()
 	}
 	return nil
 }
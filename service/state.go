@@ -0,0 +1,26 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mereith/nav/repository"
+)
+
+// 读取一条运行状态并解析到 v，不存在时返回 false
+func getState(name string, v interface{}) (bool, error) {
+	value, ok, err := repository.State().Get(name)
+	if !ok {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(value), v)
+}
+
+// 保存一条运行状态，已存在时覆盖
+func saveState(name string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return repository.State().Save(name, string(value), time.Now().Unix())
+}
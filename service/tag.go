@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+func GetAllTags() []types.Tag {
+	results, err := repository.Tags().List()
+	utils.CheckErr(err)
+	return results
+}
+
+func GetTagById(id int) (types.Tag, bool) {
+	tag, ok, err := repository.Tags().Get(id)
+	utils.CheckErr(err)
+	return tag, ok
+}
+
+// 新增标签，名称不能为空或与已有标签重复
+func AddTag(name string) (int, error) {
+	name = strings.TrimSpace(name)
+	if err := checkTagName(0, name); err != nil {
+		return 0, err
+	}
+	id, err := repository.Tags().Add(name)
+	return int(id), err
+}
+
+// 标签改名，使用这个标签的工具随之改变
+func UpdateTag(id int, name string) error {
+	name = strings.TrimSpace(name)
+	if err := checkTagName(id, name); err != nil {
+		return err
+	}
+	return repository.Tags().Update(id, name)
+}
+
+func DeleteTag(id int) error {
+	return repository.Tags().Delete(id)
+}
+
+func checkTagName(id int, name string) error {
+	if name == "" {
+		return errors.New("标签名称不能为空")
+	}
+	if tag, ok, err := repository.Tags().GetByName(name); err != nil {
+		return err
+	} else if ok && tag.Id != id {
+		return errors.New("标签已存在")
+	}
+	return nil
+}
+
+// 去掉标签名称两端的空白、空的和重复的名称。nil 表示不修改标签，原样返回
+func normalizeTags(names []string) []string {
+	if names == nil {
+		return nil
+	}
+	result := make([]string, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+// 不是由请求触发的修改
+var systemActor = types.Actor{Type: "system", Name: "system"}
+
+// 把工具修改之后的内容保存为新的版本，写入失败只记录错误，不影响已经完成的修改
+func recordToolRevisions(actor types.Actor, action string, ids ...int) {
+	if len(ids) == 0 {
+		return
+	}
+	if err := repository.ToolRevisions().Record(ids, actor, action, time.Now().Unix()); err != nil {
+		logger.LogError("保存工具历史版本失败: %s %v", err, ids)
+	}
+}
+
+func GetToolRevisions(toolId int) []types.ToolRevision {
+	results, err := repository.ToolRevisions().List(toolId)
+	utils.CheckErr(err)
+	return results
+}
+
+func GetToolRevision(toolId int, id int64) (types.ToolRevision, bool) {
+	revision, ok, err := repository.ToolRevisions().Get(toolId, id)
+	utils.CheckErr(err)
+	return revision, ok
+}
+
+// 把工具的内容回滚到某个历史版本，排序保持不变。版本中的分类已被删除时按名称查找，没有时重新创建
+func RollbackTool(toolId int, revisionId int64, actor types.Actor) error {
+	tool, ok := GetToolById(toolId)
+	if !ok {
+		return errors.New("工具不存在")
+	}
+	revision, ok := GetToolRevision(toolId, revisionId)
+	if !ok {
+		return errors.New("历史版本不存在")
+	}
+	catelogId := revision.Tool.CatelogId
+	if _, ok = GetCatelogById(catelogId); !ok {
+		var err error
+		if catelogId, err = resolveCatelogId(0, revision.Tool.Catelog); err != nil {
+			return err
+		}
+	}
+	err := repository.Tools().Update(types.UpdateToolDto{
+		Id:        toolId,
+		Name:      revision.Tool.Name,
+		Url:       revision.Tool.Url,
+		Logo:      revision.Tool.Logo,
+		CatelogId: catelogId,
+		Desc:      revision.Tool.Desc,
+		Sort:      tool.Sort,
+		Hide:      revision.Tool.Hide,
+	})
+	if err != nil {
+		return err
+	}
+	recordToolRevisions(actor, "rollback", toolId)
+	return nil
+}
@@ -1,129 +1,72 @@
 package service
 
 import (
-	"sync"
+	"fmt"
+	"time"
 
-	"github.com/mereith/nav/database"
 	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
 	"github.com/mereith/nav/types"
 	"github.com/mereith/nav/utils"
 )
 
-func ImportTools(data []types.Tool) {
-	var catelogs []string
-
-	tx, err := database.DB.Begin()
-	if err != nil {
-		utils.CheckErr(err)
-		return
-	}
-	defer func() {
-		// 如果事务还未提交（比如发生错误提前返回），则回滚
-		// sql.Tx.Rollback() returns error if already committed/rolled back, which is fine to ignore or check
-		_ = tx.Rollback()
-	}()
-
-	sql_add_tool := `
-		INSERT OR REPLACE INTO nav_table (id, name, catelog, url, logo, desc, sort, hide)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?);
-		`
-	stmt, err := tx.Prepare(sql_add_tool)
-	if err != nil {
-		utils.CheckErr(err)
-		return
-	}
-	defer stmt.Close()
-
-	for _, v := range data {
-		if !utils.In(v.Catelog, catelogs) {
-			catelogs = append(catelogs, v.Catelog)
-		}
-		_, err = stmt.Exec(v.Id, v.Name, v.Catelog, v.Url, v.Logo, v.Desc, v.Sort, v.Hide)
+// 导入工具，分类按名称匹配，不存在时创建。标签同样按名称匹配，没有 tags 字段时保留已有的标签
+func ImportTools(data []types.Tool, actor types.Actor) error {
+	for i, v := range data {
+		catelogId, err := resolveCatelogId(v.CatelogId, v.Catelog)
 		if err != nil {
-			utils.CheckErr(err)
-			// Continue with other items even if one fails
-			continue
+			return fmt.Errorf("工具 %s: %w", v.Name, err)
 		}
+		data[i].CatelogId = catelogId
+		data[i].Tags = normalizeTags(v.Tags)
 	}
-
-	// 显式提交事务，释放锁，以便后续 AddCatelog 可以执行
-	err = tx.Commit()
-	if err != nil {
-		utils.CheckErr(err)
-		return
+	if err := repository.Tools().Import(data); err != nil {
+		return err
 	}
-
-	for _, catelog := range catelogs {
-		var addCatelogDto types.AddCatelogDto
-		addCatelogDto.Name = catelog
-		AddCatelog(addCatelogDto)
+	ids := make([]int, 0, len(data))
+	for _, v := range data {
+		ids = append(ids, v.Id)
 	}
+	recordToolRevisions(actor, "import", ids...)
+
 	// 转存所有图片,异步
 	go func(data []types.Tool) {
 		for _, v := range data {
 			UpdateImg(v.Logo)
 		}
 	}(data)
-
+	return nil
 }
 
-func UpdateTool(data types.UpdateToolDto) {
+func UpdateTool(data types.UpdateToolDto, actor types.Actor) error {
+	catelogId, err := resolveCatelogId(data.CatelogId, data.Catelog)
+	if err != nil {
+		return err
+	}
+	data.CatelogId = catelogId
+	data.Tags = normalizeTags(data.Tags)
 	// 除了更新工具本身之外，也要更新 img 表
-	sql_update_tool := `
-		UPDATE nav_table
-		SET name = ?, url = ?, logo = ?, catelog = ?, desc = ?, sort = ?, hide = ?
-		WHERE id = ?;
-		`
-	stmt, err := database.DB.Prepare(sql_update_tool)
-	utils.CheckErr(err)
-	res, err := stmt.Exec(data.Name, data.Url, data.Logo, data.Catelog, data.Desc, data.Sort, data.Hide, data.Id)
-	utils.CheckErr(err)
-	_, err = res.RowsAffected()
-	utils.CheckErr(err)
+	if err = repository.Tools().Update(data); err != nil {
+		return err
+	}
+	recordToolRevisions(actor, "update", data.Id)
 	// 更新 img
 	// UpdateImg(data.Logo)
+	return nil
 }
 
-func AddTool(data types.AddToolDto) (int64, error) {
-	// 创建一个互斥锁来保护数据库操作
-	var mu sync.Mutex
-	mu.Lock()
-	defer mu.Unlock()
-
-	tx, err := database.DB.Begin()
-	if err != nil {
-		return 0, err
-	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		}
-	}()
-
-	sql_add_tool := `
-		INSERT INTO nav_table (name, url, logo, catelog, desc, sort, hide)
-		VALUES (?, ?, ?, ?, ?, ?, ?);
-		`
-	stmt, err := tx.Prepare(sql_add_tool)
-	if err != nil {
-		return 0, err
-	}
-	defer stmt.Close()
-
-	res, err := stmt.Exec(data.Name, data.Url, data.Logo, data.Catelog, data.Desc, data.Sort, data.Hide)
+func AddTool(data types.AddToolDto, actor types.Actor) (int64, error) {
+	catelogId, err := resolveCatelogId(data.CatelogId, data.Catelog)
 	if err != nil {
 		return 0, err
 	}
-
-	id, err := res.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-
-	err = tx.Commit()
+	data.CatelogId = catelogId
+	data.Tags = normalizeTags(data.Tags)
+	id, err := repository.Tools().Add(data)
 	if err != nil {
 		return 0, err
 	}
+	recordToolRevisions(actor, "create", int(id))
 	logger.LogInfo("新增工具: %s", data.Name)
 
 	// 在事务完成后再异步更新图片
@@ -135,84 +78,61 @@ func AddTool(data types.AddToolDto) (int64, error) {
 }
 
 func GetAllTool() []types.Tool {
-	sql_get_all := `
-		SELECT id,name,url,logo,catelog,desc,sort,hide FROM nav_table order by sort;
-		`
-	results := make([]types.Tool, 0)
-	rows, err := database.DB.Query(sql_get_all)
+	results, err := repository.Tools().List()
 	utils.CheckErr(err)
-	for rows.Next() {
-		var tool types.Tool
-		var hide interface{}
-		var sort interface{}
-		err = rows.Scan(&tool.Id, &tool.Name, &tool.Url, &tool.Logo, &tool.Catelog, &tool.Desc, &sort, &hide)
-		if hide == nil {
-			tool.Hide = false
-		} else {
-			if hide.(int64) == 0 {
-				tool.Hide = false
-			} else {
-				tool.Hide = true
-			}
-		}
-		if sort == nil {
-			tool.Sort = 0
-		} else {
-			i64 := sort.(int64)
-			tool.Sort = int(i64)
-		}
-		utils.CheckErr(err)
-		results = append(results, tool)
-	}
-	defer rows.Close()
 	return results
 }
 
-func GetToolLogoUrlById(id int) string {
-	sql_get_tool := `
-		SELECT logo FROM nav_table WHERE id=?;
-		`
-	rows, err := database.DB.Query(sql_get_tool, id)
+func GetToolById(id int) (types.Tool, bool) {
+	tool, ok, err := repository.Tools().Get(id)
 	utils.CheckErr(err)
-	var tool types.Tool
-	for rows.Next() {
-		err = rows.Scan(&tool.Logo)
-		utils.CheckErr(err)
+	return tool, ok
+}
 
-	}
-	defer rows.Close()
+func GetToolLogoUrlById(id int) string {
+	tool, _ := GetToolById(id)
 	return tool.Logo
 }
 
 func UpdateToolIcon(id int64, logo string) {
-	sql_update_tool := `
-		UPDATE nav_table SET logo=? WHERE id=?;
-		`
-	_, err := database.DB.Exec(sql_update_tool, logo, id)
+	err := repository.Tools().UpdateLogo(id, logo)
 	utils.CheckErr(err)
+	if err == nil {
+		recordToolRevisions(systemActor, "update", int(id))
+	}
 	UpdateImg(logo)
 }
-func UpdateToolsSort(updates []types.UpdateToolsSortDto) error {
-	tx, err := database.DB.Begin()
-	if err != nil {
-		return err
-	}
 
-	sql := `UPDATE nav_table SET sort = ? WHERE id = ?`
-	stmt, err := tx.Prepare(sql)
-	if err != nil {
-		tx.Rollback()
+// 只为排序确实变化的工具保存历史版本
+func UpdateToolsSort(updates []types.UpdateToolsSortDto, actor types.Actor) error {
+	sorts := make(map[int]int)
+	for _, tool := range GetAllTool() {
+		sorts[tool.Id] = tool.Sort
+	}
+	if err := repository.Tools().UpdateSort(updates); err != nil {
 		return err
 	}
-	defer stmt.Close()
-
+	changed := make([]int, 0)
 	for _, update := range updates {
-		_, err = stmt.Exec(update.Sort, update.Id)
-		if err != nil {
-			tx.Rollback()
-			return err
+		if sort, ok := sorts[update.Id]; ok && sort != update.Sort {
+			changed = append(changed, update.Id)
 		}
 	}
+	recordToolRevisions(actor, "sort", changed...)
+	return nil
+}
 
-	return tx.Commit()
+// 把工具移入回收站
+func DeleteTools(ids []int, actor types.Actor) error {
+	active := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := GetToolById(id); ok {
+			active = append(active, id)
+		}
+	}
+	if err := repository.Tools().Trash(active, time.Now().Unix()); err != nil {
+		return err
+	}
+	recordToolRevisions(actor, "delete", active...)
+	return nil
 }
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+const recoveryCodeCount = 10
+
+type TotpSetup struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"`
+	QRCode string `json:"qrcode"`
+}
+
+type TotpStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recoveryCodesLeft"`
+}
+
+func getTotpState(userId int) (types.Totp, error) {
+	totp, ok, err := repository.Totp().Get(userId)
+	if err == nil && !ok {
+		err = errors.New("用户不存在")
+	}
+	return totp, err
+}
+
+func GetTotpStatus(userId int) (TotpStatus, error) {
+	var status TotpStatus
+	totp, err := getTotpState(userId)
+	if err != nil {
+		return status, err
+	}
+	status.Enabled = totp.Enabled
+	status.RecoveryCodesLeft, err = repository.Totp().CountRecoveryCodes(userId)
+	return status, err
+}
+
+// 生成新的待确认密钥，确认验证码之前不会生效
+func BeginTotpSetup(user types.User) (TotpSetup, error) {
+	var setup TotpSetup
+	totp, err := getTotpState(user.Id)
+	if err != nil {
+		return setup, err
+	}
+	if totp.Enabled {
+		return setup, errors.New("已开启两步验证，请先关闭")
+	}
+	secret, err := utils.GenerateTotpSecret()
+	if err != nil {
+		return setup, err
+	}
+	issuer := GetSetting().Title
+	if issuer == "" {
+		issuer = "Van Nav"
+	}
+	uri := utils.TotpURI(issuer, user.Name, secret)
+	qr, err := utils.TotpQRCode(uri)
+	if err != nil {
+		return setup, err
+	}
+	if err = repository.Totp().SetSecret(user.Id, secret); err != nil {
+		return setup, err
+	}
+	return TotpSetup{Secret: secret, Uri: uri, QRCode: qr}, nil
+}
+
+// 校验验证码后开启两步验证，返回只展示一次的恢复码
+func EnableTotp(userId int, code string) ([]string, error) {
+	totp, err := getTotpState(userId)
+	if err != nil {
+		return nil, err
+	}
+	if totp.Enabled {
+		return nil, errors.New("已开启两步验证")
+	}
+	if totp.Secret == "" {
+		return nil, errors.New("请先获取两步验证密钥")
+	}
+	step, ok := utils.VerifyTotp(totp.Secret, code, time.Now())
+	if !ok {
+		return nil, errors.New("验证码错误")
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = repository.Totp().Enable(userId, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// 生成一组恢复码和它们的哈希
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// 重新生成恢复码，旧的恢复码全部作废
+func RegenerateRecoveryCodes(userId int) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = repository.Totp().ReplaceRecoveryCodes(userId, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// 校验第二因素：TOTP 验证码或未使用过的恢复码，两者都只能使用一次
+func VerifySecondFactor(userId int, code string) bool {
+	totp, err := getTotpState(userId)
+	if err != nil || !totp.Enabled {
+		return false
+	}
+	if step, ok := utils.VerifyTotp(totp.Secret, code, time.Now()); ok {
+		if step <= totp.LastStep {
+			return false
+		}
+		used, err := repository.Totp().UseStep(userId, step)
+		utils.CheckErr(err)
+		return used
+	}
+	used, err := repository.Totp().UseRecoveryCode(userId, utils.HashRecoveryCode(code), time.Now().Unix())
+	utils.CheckErr(err)
+	return used
+}
+
+// 关闭两步验证并清除密钥和恢复码
+func DisableTotp(userId int) error {
+	ok, err := repository.Totp().Disable(userId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("用户不存在")
+	}
+	return nil
+}
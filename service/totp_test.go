@@ -0,0 +1,126 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mereith/nav/database"
+)
+
+const testTotpSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// 按 RFC 6238 计算指定时间步的 6 位验证码
+func testTotpCode(t *testing.T, step int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(testTotpSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// 当前时间步，临近边界时等到下一个时间步，避免测试过程中跨过边界
+func currentTestStep() int64 {
+	if now := time.Now(); now.Unix()%30 >= 28 {
+		time.Sleep(time.Duration(30-now.Unix()%30) * time.Second)
+	}
+	return time.Now().Unix() / 30
+}
+
+func enableTestTotp(t *testing.T, userId int) {
+	t.Helper()
+	mustExec(t, `UPDATE nav_user SET totp_secret = ?, totp_enabled = 1, totp_last_step = 0 WHERE id = ?;`, testTotpSecret, userId)
+}
+
+func TestVerifySecondFactorReplay(t *testing.T) {
+	openTestDB(t)
+	id := adminId(t)
+	enableTestTotp(t, id)
+	step := currentTestStep()
+
+	if !VerifySecondFactor(id, testTotpCode(t, step-1)) {
+		t.Fatal("有效的验证码没有通过校验")
+	}
+	if VerifySecondFactor(id, testTotpCode(t, step-1)) {
+		t.Error("同一个验证码被重复使用")
+	}
+	if !VerifySecondFactor(id, testTotpCode(t, step)) {
+		t.Error("更新的时间步没有通过校验")
+	}
+	// 已经用过更新的时间步后，之前窗口内的验证码也不再接受
+	if VerifySecondFactor(id, testTotpCode(t, step-1)) {
+		t.Error("较旧的时间步在较新的之后通过了校验")
+	}
+	if VerifySecondFactor(id, testTotpCode(t, step+5)) {
+		t.Error("超出时间窗口的验证码通过了校验")
+	}
+}
+
+func TestVerifySecondFactorRecoveryCode(t *testing.T) {
+	openTestDB(t)
+	id := adminId(t)
+	enableTestTotp(t, id)
+	codes, err := RegenerateRecoveryCodes(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySecondFactor(id, codes[0]) {
+		t.Fatal("恢复码没有通过校验")
+	}
+	if VerifySecondFactor(id, codes[0]) {
+		t.Error("恢复码被重复使用")
+	}
+	status, err := GetTotpStatus(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Errorf("RecoveryCodesLeft = %d, want %d", status.RecoveryCodesLeft, recoveryCodeCount-1)
+	}
+	// 重新生成后旧的恢复码全部作废
+	if _, err := RegenerateRecoveryCodes(id); err != nil {
+		t.Fatal(err)
+	}
+	if VerifySecondFactor(id, codes[1]) {
+		t.Error("重新生成后旧的恢复码仍然可用")
+	}
+}
+
+func TestVerifySecondFactorDisabled(t *testing.T) {
+	openTestDB(t)
+	id := adminId(t)
+	enableTestTotp(t, id)
+	codes, err := RegenerateRecoveryCodes(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DisableTotp(id); err != nil {
+		t.Fatal(err)
+	}
+	step := currentTestStep()
+	if VerifySecondFactor(id, testTotpCode(t, step)) {
+		t.Error("关闭两步验证后验证码仍然通过校验")
+	}
+	if VerifySecondFactor(id, codes[0]) {
+		t.Error("关闭两步验证后恢复码仍然通过校验")
+	}
+	var left int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM nav_recovery_code WHERE user_id = ?;`, id).Scan(&left); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("关闭后还剩 %d 个恢复码", left)
+	}
+}
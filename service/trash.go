@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mereith/nav/database"
+	"github.com/mereith/nav/logger"
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+// 回收站保留时长，超过后自动彻底删除，0 表示不自动删除
+var trashRetention = 30 * 24 * time.Hour
+
+func SetTrashRetention(retention time.Duration) {
+	trashRetention = retention
+}
+
+func GetTrash() ([]types.Tool, []types.Catelog) {
+	tools, err := repository.Tools().ListTrashed()
+	utils.CheckErr(err)
+	catelogs, err := repository.Catelogs().ListTrashed()
+	utils.CheckErr(err)
+	return tools, catelogs
+}
+
+func GetTrashedTool(id int) (types.Tool, bool) {
+	tool, ok, err := repository.Tools().GetTrashed(id)
+	utils.CheckErr(err)
+	return tool, ok
+}
+
+func GetTrashedCatelog(id int) (types.Catelog, bool) {
+	catelog, ok, err := repository.Catelogs().GetTrashed(id)
+	utils.CheckErr(err)
+	return catelog, ok
+}
+
+// 从回收站恢复工具。所属分类也在回收站中时，恢复到同名的分类，没有时重新创建
+func RestoreTool(id int, actor types.Actor) error {
+	tool, ok := GetTrashedTool(id)
+	if !ok {
+		return errors.New("回收站中没有这个工具")
+	}
+	catelogId := tool.CatelogId
+	if _, ok = GetCatelogById(catelogId); !ok {
+		var err error
+		if catelogId, err = resolveCatelogId(0, tool.Catelog); err != nil {
+			return err
+		}
+	}
+	if err := repository.Tools().Restore(id, catelogId); err != nil {
+		return err
+	}
+	recordToolRevisions(actor, "restore", id)
+	return nil
+}
+
+// 从回收站恢复分类和与它一起删除的工具
+func RestoreCatelog(id int, actor types.Actor) error {
+	catelog, ok := GetTrashedCatelog(id)
+	if !ok {
+		return errors.New("回收站中没有这个分类")
+	}
+	ids := trashedWithCatelog(catelog)
+	if err := repository.Catelogs().Restore(id); err != nil {
+		return err
+	}
+	recordToolRevisions(actor, "restore", ids...)
+	return nil
+}
+
+// 与分类一起移入回收站的工具 id
+func trashedWithCatelog(catelog types.Catelog) []int {
+	tools, _ := GetTrash()
+	ids := make([]int, 0)
+	for _, tool := range tools {
+		if tool.CatelogId == catelog.Id && tool.DeletedAt == catelog.DeletedAt {
+			ids = append(ids, tool.Id)
+		}
+	}
+	return ids
+}
+
+// 彻底删除回收站中的工具
+func PurgeTool(id int) error {
+	if _, ok := GetTrashedTool(id); !ok {
+		return errors.New("回收站中没有这个工具")
+	}
+	return repository.Tools().Delete([]int{id})
+}
+
+// 彻底删除回收站中的分类，以及回收站中仍属于它的工具
+func PurgeCatelog(id int) error {
+	if _, ok := GetTrashedCatelog(id); !ok {
+		return errors.New("回收站中没有这个分类")
+	}
+	_, err := purgeCatelog(id)
+	return err
+}
+
+// 先删除分类下的工具才能删除分类，返回删除的工具数量
+func purgeCatelog(id int) (int, error) {
+	tools, err := repository.Tools().ListByCatelog(id)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int, 0, len(tools))
+	for _, tool := range tools {
+		if tool.DeletedAt == 0 {
+			return 0, fmt.Errorf("分类下还有未删除的工具 %s", tool.Name)
+		}
+		ids = append(ids, tool.Id)
+	}
+	if err = repository.Tools().Delete(ids); err != nil {
+		return 0, err
+	}
+	return len(ids), repository.Catelogs().Delete(id)
+}
+
+// 彻底删除在 before 之前移入回收站的工具和分类，返回删除的数量。
+// 无法删除的分类（例如其中还有未删除的工具）会被跳过，不影响其他分类，错误合并后返回
+func purgeTrash(before int64) (int, error) {
+	count, err := repository.Tools().Purge(before)
+	if err != nil {
+		return count, err
+	}
+	catelogs, err := repository.Catelogs().ListTrashed()
+	if err != nil {
+		return count, err
+	}
+	var errs []error
+	for _, catelog := range catelogs {
+		if catelog.DeletedAt >= before {
+			continue
+		}
+		tools, err := purgeCatelog(catelog.Id)
+		if err != nil {
+			logger.LogError("跳过回收站中的分类 %s: %s", catelog.Name, err)
+			errs = append(errs, fmt.Errorf("分类 %s: %w", catelog.Name, err))
+			continue
+		}
+		count += tools + 1
+	}
+	return count, errors.Join(errs...)
+}
+
+// 清空回收站
+func EmptyTrash() (int, error) {
+	return purgeTrash(time.Now().Unix() + 1)
+}
+
+// 删除超过保留时长的回收站内容
+func PruneTrash() (int, error) {
+	if trashRetention <= 0 {
+		return 0, nil
+	}
+	return purgeTrash(time.Now().Add(-trashRetention).Unix())
+}
+
+// 启动后台任务，每天清理一次回收站中过期的内容
+func StartTrashPruner() {
+	go func() {
+		for {
+			release := database.Acquire()
+			count, err := PruneTrash()
+			release()
+			utils.CheckErr(err)
+			if count > 0 {
+				logger.LogInfo("清理回收站中过期的内容 %d 项", count)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
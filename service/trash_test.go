@@ -0,0 +1,52 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mereith/nav/types"
+)
+
+// 一个无法删除的分类不影响后面其他过期分类的清理
+func TestPurgeTrashSkipsBlockedCatelog(t *testing.T) {
+	openTestDB(t)
+	actor := types.Actor{Type: "user", Id: adminId(t), Name: "admin"}
+	ids := make(map[string]int)
+	for _, name := range []string{"blocked", "expired"} {
+		catelogId, err := AddCatelog(types.AddCatelogDto{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		toolId, err := AddTool(types.AddToolDto{Name: name + "-tool", Url: "https://" + name + ".example.com", CatelogId: int(catelogId)}, actor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = DeleteCatelog(int(catelogId), 0, actor); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = int(catelogId)
+		ids[name+"-tool"] = int(toolId)
+	}
+	// blocked 排在前面，其中的工具被单独恢复
+	mustExec(t, `UPDATE nav_catelog SET deleted_at = 200 WHERE id = ?;`, ids["blocked"])
+	mustExec(t, `UPDATE nav_table SET deleted_at = NULL WHERE id = ?;`, ids["blocked-tool"])
+	mustExec(t, `UPDATE nav_catelog SET deleted_at = 100 WHERE id = ?;`, ids["expired"])
+	mustExec(t, `UPDATE nav_table SET deleted_at = 100 WHERE id = ?;`, ids["expired-tool"])
+
+	count, err := purgeTrash(300)
+	if err == nil || !strings.Contains(err.Error(), "未删除的工具") {
+		t.Errorf("err = %v, want 分类下还有未删除的工具", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+	if _, ok := GetTrashedCatelog(ids["expired"]); ok {
+		t.Error("过期的分类没有被删除")
+	}
+	if _, ok := GetTrashedCatelog(ids["blocked"]); !ok {
+		t.Error("还有工具的分类被删除")
+	}
+	if _, ok := GetToolById(ids["blocked-tool"]); !ok {
+		t.Error("未删除的工具被删除")
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/mereith/nav/repository"
+	"github.com/mereith/nav/types"
+	"github.com/mereith/nav/utils"
+)
+
+func GetUserById(id int) (types.User, bool) {
+	user, ok, err := repository.Users().Get(id)
+	utils.CheckErr(err)
+	return user, ok
+}
+
+func GetUsers() []types.User {
+	results, err := repository.Users().List()
+	utils.CheckErr(err)
+	return results
+}
+
+// 检查用户名非空且没有被其他用户占用
+func checkUserName(name string, selfId int) error {
+	if name == "" {
+		return errors.New("用户名不能为空")
+	}
+	taken, err := repository.Users().NameTaken(name, selfId)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return errors.New("用户名已存在")
+	}
+	return nil
+}
+
+func checkRole(role string) error {
+	if !utils.In(role, types.Roles) {
+		return errors.New("不支持的角色: " + role)
+	}
+	return nil
+}
+
+// 至少要保留一个 owner，否则没人能管理用户和设置
+func isLastOwner(user types.User) (bool, error) {
+	if user.Role != types.RoleOwner {
+		return false, nil
+	}
+	count, err := repository.Users().CountByRole(types.RoleOwner)
+	if err != nil {
+		return false, err
+	}
+	return count <= 1, nil
+}
+
+func AddUser(data types.AddUserDto) (int64, error) {
+	if err := checkUserName(data.Name, 0); err != nil {
+		return 0, err
+	}
+	if err := checkRole(data.Role); err != nil {
+		return 0, err
+	}
+	if data.Password == "" {
+		return 0, errors.New("密码不能为空")
+	}
+	hash, err := utils.HashPassword(data.Password)
+	if err != nil {
+		return 0, err
+	}
+	return repository.Users().Add(data.Name, hash, data.Role)
+}
+
+// 管理员修改用户信息和角色，密码留空表示不修改
+func AdminUpdateUser(id int, data types.AdminUpdateUserDto) error {
+	user, ok := GetUserById(id)
+	if !ok {
+		return errors.New("用户不存在")
+	}
+	if err := checkUserName(data.Name, id); err != nil {
+		return err
+	}
+	if err := checkRole(data.Role); err != nil {
+		return err
+	}
+	if data.Role != types.RoleOwner {
+		last, err := isLastOwner(user)
+		if err != nil {
+			return err
+		}
+		if last {
+			return errors.New("至少需要保留一个 owner")
+		}
+	}
+	password := user.Password
+	if data.Password != "" {
+		hash, err := utils.HashPassword(data.Password)
+		if err != nil {
+			return err
+		}
+		password = hash
+	}
+	return repository.Users().Update(id, data.Name, password, data.Role)
+}
+
+// 删除用户并撤销其所有会话
+func DeleteUser(id int) error {
+	user, ok := GetUserById(id)
+	if !ok {
+		return errors.New("用户不存在")
+	}
+	last, err := isLastOwner(user)
+	if err != nil {
+		return err
+	}
+	if last {
+		return errors.New("不能删除最后一个 owner")
+	}
+	return repository.Users().Delete(id)
+}
@@ -10,12 +10,43 @@ type UpdateUserDto struct {
 	Password string `json:"password"`
 }
 
+type AddUserDto struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// 管理员修改其他用户，密码留空表示不修改
+type AdminUpdateUserDto struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// 关联外部身份，subject 为空表示解除关联
+type UserExternalIdentityDto struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+}
+
 type LoginDto struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
+	// 开启两步验证后需要填写验证码或恢复码
+	Code string `json:"code"`
+}
+type TotpCodeDto struct {
+	Code string `json:"code"`
+}
+type DisableTotpDto struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
 }
 type AddTokenDto struct {
-	Name string `json:"name"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// 有效天数，0 表示永不过期
+	ExpiresInDays int `json:"expiresInDays"`
 }
 
 type UpdateCatelogDto struct {
@@ -27,27 +58,42 @@ type UpdateCatelogDto struct {
 
 type AddCatelogDto struct {
 	Name string `json:"name"`
-	Sort int    `json:"sort"`
-	Hide bool   `json:"hide"`
+	// 上级分类的 id，0 表示顶级分类
+	ParentId int  `json:"parentId"`
+	Sort     int  `json:"sort"`
+	Hide     bool `json:"hide"`
+}
+
+type MoveCatelogDto struct {
+	// 0 表示移动为顶级分类
+	ParentId int `json:"parentId"`
 }
 type UpdateToolDto struct {
-	Id      int    `json:"id"`
-	Name    string `json:"name"`
-	Url     string `json:"url"`
-	Logo    string `json:"logo"`
-	Catelog string `json:"catelog"`
-	Desc    string `json:"desc"`
-	Sort    int    `json:"sort"`
-	Hide    bool   `json:"hide"`
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Url  string `json:"url"`
+	Logo string `json:"logo"`
+	// 分类可以按 id 或名称指定，同时传入时以名称为准；名称对应的分类不存在时自动创建
+	CatelogId int    `json:"catelogId"`
+	Catelog   string `json:"catelog"`
+	Desc      string `json:"desc"`
+	Sort      int    `json:"sort"`
+	Hide      bool   `json:"hide"`
+	// 标签名称，不存在的标签自动创建。为 nil（没有传）时不修改已有的标签
+	Tags []string `json:"tags"`
 }
 type AddToolDto struct {
-	Name    string `json:"name"`
-	Url     string `json:"url"`
-	Logo    string `json:"logo"`
-	Catelog string `json:"catelog"`
-	Desc    string `json:"desc"`
-	Sort    int    `json:"sort"`
-	Hide    bool   `json:"hide"`
+	Name string `json:"name"`
+	Url  string `json:"url"`
+	Logo string `json:"logo"`
+	// 分类可以按 id 或名称指定，同时传入时以名称为准；名称对应的分类不存在时自动创建
+	CatelogId int    `json:"catelogId"`
+	Catelog   string `json:"catelog"`
+	Desc      string `json:"desc"`
+	Sort      int    `json:"sort"`
+	Hide      bool   `json:"hide"`
+	// 标签名称，不存在的标签自动创建。为 nil（没有传）时不修改已有的标签
+	Tags []string `json:"tags"`
 }
 type UpdateToolsSortDto struct {
 	Id   int `json:"id"`
@@ -57,4 +103,40 @@ type UpdateToolsSortDto struct {
 type UpdateCatelogsSortDto struct {
 	Id   int `json:"id"`
 	Sort int `json:"sort"`
+	// 不为空时同时把分类移动到这个上级分类下，0 表示顶级分类
+	ParentId *int `json:"parentId,omitempty"`
+}
+
+type AddGuestLinkDto struct {
+	Name           string `json:"name"`
+	ExpiresInHours int    `json:"expiresInHours"`
+	MaxUses        int    `json:"maxUses"`
+	CatelogIds     []int  `json:"catelogIds"`
+}
+
+type TagDto struct {
+	Name string `json:"name"`
+}
+
+// 工具分页查询条件，字符串为空表示不过滤
+type ToolQueryDto struct {
+	Page     int
+	PageSize int
+	Keyword  string
+	Catelog  string
+	// 按标签名称过滤，MatchAll 为 true 时要求包含全部标签，否则包含任意一个即可
+	Tags     []string
+	MatchAll bool
+}
+
+// 审计日志查询条件，字符串为空、时间为 0 表示不过滤
+type AuditQueryDto struct {
+	Page       int
+	PageSize   int
+	Actor      string
+	Action     string
+	TargetType string
+	TargetId   string
+	Since      int64
+	Until      int64
 }
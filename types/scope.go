@@ -0,0 +1,66 @@
+package types
+
+// 接口权限范围
+const (
+	ScopeToolsRead     = "tools:read"
+	ScopeToolsWrite    = "tools:write"
+	ScopeCatelogsWrite = "catelogs:write"
+	ScopeSettingsWrite = "settings:write"
+	ScopeImport        = "import"
+	// 以下权限只有登录用户拥有，不能授予 API Token
+	// 修改自己的账号
+	ScopeAccount = "account"
+	// 管理 API Token
+	ScopeTokensManage = "tokens:manage"
+	// 管理用户、会话和密钥
+	ScopeUsersManage = "users:manage"
+	// 查看审计日志
+	ScopeAuditRead = "audit:read"
+	// 备份和恢复数据库
+	ScopeBackup = "backup"
+)
+
+// 可以授予 API Token 的权限范围
+var TokenScopes = []string{
+	ScopeToolsRead,
+	ScopeToolsWrite,
+	ScopeCatelogsWrite,
+	ScopeSettingsWrite,
+	ScopeImport,
+}
+
+// 用户角色
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var Roles = []string{RoleOwner, RoleEditor, RoleViewer}
+
+// 各角色拥有的权限范围
+var RoleScopes = map[string][]string{
+	RoleOwner: {
+		ScopeToolsRead,
+		ScopeToolsWrite,
+		ScopeCatelogsWrite,
+		ScopeSettingsWrite,
+		ScopeImport,
+		ScopeAccount,
+		ScopeTokensManage,
+		ScopeUsersManage,
+		ScopeAuditRead,
+		ScopeBackup,
+	},
+	RoleEditor: {
+		ScopeToolsRead,
+		ScopeToolsWrite,
+		ScopeCatelogsWrite,
+		ScopeImport,
+		ScopeAccount,
+	},
+	RoleViewer: {
+		ScopeToolsRead,
+		ScopeAccount,
+	},
+}
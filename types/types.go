@@ -17,17 +17,40 @@ type Setting struct {
 }
 
 type Token struct {
-	Id       int    `json:"id"`
-	Name     string `json:"name"`
-	Value    string `json:"value"`
-	Disabled int    `json:"disabled"`
+	Id         int      `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	TokenHash  string   `json:"-"`
+	Disabled   int      `json:"disabled"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  int64    `json:"expiresAt"`
+	CreatedAt  int64    `json:"createdAt"`
+	LastUsedAt int64    `json:"lastUsedAt"`
+	LastUsedIp string   `json:"lastUsedIp"`
 }
 
 type User struct {
 	Id       int    `json:"id"`
 	Name     string `json:"name"`
-	Password string `json:"password"`
+	Password string `json:"-"`
+	Role     string `json:"role"`
+	// 是否已开启两步验证
+	TotpEnabled bool `json:"totpEnabled"`
+	// 关联的外部身份，OIDC 为 issuer 和 sub，反向代理认证为 proxy 和用户名，未关联时为空
+	ExternalIssuer  string `json:"externalIssuer"`
+	ExternalSubject string `json:"externalSubject"`
+}
+type Session struct {
+	Id        string `json:"id"`
+	UserId    int    `json:"userId"`
+	UserName  string `json:"userName"`
+	Ip        string `json:"ip"`
+	UserAgent string `json:"userAgent"`
+	CreatedAt int64  `json:"createdAt"`
+	ExpiresAt int64  `json:"expiresAt"`
+	Current   bool   `json:"current"`
 }
+
 type Img struct {
 	Id    int    `json:"id"`
 	Url   string `json:"url"`
@@ -35,19 +58,163 @@ type Img struct {
 }
 
 type Tool struct {
-	Id      int    `json:"id"`
-	Name    string `json:"name"`
-	Url     string `json:"url"`
-	Logo    string `json:"logo"`
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Url  string `json:"url"`
+	Logo string `json:"logo"`
+	// 所属分类的 id，0 表示没有分类
+	CatelogId int `json:"catelogId"`
+	// 所属分类的名称，由 CatelogId 查出
 	Catelog string `json:"catelog"`
 	Desc    string `json:"desc"`
 	Sort    int    `json:"sort"`
 	Hide    bool   `json:"hide"`
+	// 标签名称，按名称排序。导入时为 nil（没有 tags 字段）表示不修改已有的标签
+	Tags []string `json:"tags"`
+	// 移入回收站的时间，0 表示未删除
+	DeletedAt int64 `json:"deletedAt,omitempty"`
+}
+
+// 工具搜索结果
+type ToolSearchResult struct {
+	Tool Tool `json:"tool"`
+	// 相关度，越小越相关，与 FTS5 的 bm25 一致
+	Rank float64 `json:"rank"`
+	// 匹配到的字段（name、desc、url、catelog、tags），内容已经过 HTML 转义，匹配的部分用 <mark> 标出，描述只保留匹配附近的片段
+	Highlights map[string]string `json:"highlights"`
+}
+
+type Tag struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	// 使用这个标签的未删除工具数量
+	ToolCount int `json:"toolCount"`
 }
 
 type Catelog struct {
 	Id   int    `json:"id"`
 	Name string `json:"name"`
-	Sort int    `json:"sort"`
-	Hide bool   `json:"hide"`
+	// 上级分类的 id，0 表示顶级分类
+	ParentId int `json:"parentId"`
+	// 同一个上级分类下的顺序
+	Sort int  `json:"sort"`
+	Hide bool `json:"hide"`
+	// 移入回收站的时间，0 表示未删除
+	DeletedAt int64 `json:"deletedAt,omitempty"`
+}
+
+// 分类树中的一个节点
+type CatelogNode struct {
+	Catelog
+	Children []CatelogNode `json:"children"`
+}
+
+// 访客会话，签名后保存在 HttpOnly cookie 中
+type GuestSession struct {
+	IssuedAt  int64 `json:"iat"`
+	ExpiresAt int64 `json:"exp"`
+	// 通过邀请链接获得的会话，记录链接 id，链接删除后会话失效
+	LinkId string `json:"lid,omitempty"`
+}
+
+// 访客邀请链接
+type GuestLink struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+	// 允许访问的分类，为空表示不限制
+	CatelogIds []int `json:"catelogIds"`
+	ExpiresAt  int64 `json:"expiresAt"`
+	// 最大使用次数，0 表示不限制
+	MaxUses   int   `json:"maxUses"`
+	Uses      int   `json:"uses"`
+	CreatedAt int64 `json:"createdAt"`
+}
+
+// 审计日志，只追加不修改
+type Audit struct {
+	Id        int64 `json:"id"`
+	CreatedAt int64 `json:"createdAt"`
+	// 操作者类型：user 或 token
+	ActorType string `json:"actorType"`
+	ActorId   int    `json:"actorId"`
+	// 用户名或 API Token 名称
+	Actor      string `json:"actor"`
+	Action     string `json:"action"`
+	TargetType string `json:"targetType"`
+	TargetId   string `json:"targetId"`
+	// 变化的字段，形如 {"name": {"before": "a", "after": "b"}}
+	Changes map[string]AuditChange `json:"changes"`
+	Ip      string                 `json:"ip"`
+}
+
+// 操作者，写入审计日志和工具的历史版本
+type Actor struct {
+	// user、token 或 system
+	Type string
+	Id   int
+	Name string
+}
+
+// 工具的一个历史版本，保存修改之后的完整内容
+type ToolRevision struct {
+	Id        int64  `json:"id"`
+	ToolId    int    `json:"toolId"`
+	CreatedAt int64  `json:"createdAt"`
+	ActorType string `json:"actorType"`
+	ActorId   int    `json:"actorId"`
+	Actor     string `json:"actor"`
+	// init、create、update、sort、import、delete、restore、rollback
+	Action string `json:"action"`
+	Tool   Tool   `json:"tool"`
+}
+
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// 自动备份的运行状态，时间均为秒级时间戳，0 表示还没有发生过
+type BackupStatus struct {
+	Enabled       bool   `json:"enabled"`
+	Schedule      string `json:"schedule"`
+	NextRunAt     int64  `json:"nextRunAt"`
+	LastRunAt     int64  `json:"lastRunAt"`
+	LastSuccessAt int64  `json:"lastSuccessAt"`
+	LastFile      string `json:"lastFile"`
+	LastError     string `json:"lastError"`
+}
+
+// 备份目录中的备份文件
+type BackupFile struct {
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// 用户的两步验证状态
+type Totp struct {
+	// 待确认或已生效的密钥，未设置时为空
+	Secret  string
+	Enabled bool
+	// 最近一次使用过的验证码的时间步，防止重放
+	LastStep int64
+}
+
+// 登录失败记录，key 为用户名或 IP
+type LoginAttempt struct {
+	Key           string
+	Failures      int
+	LastFailureAt int64
+	// 锁定到该时间，0 表示没有锁定
+	LockedUntil int64
+}
+
+// 服务端保存的密钥
+type Secret struct {
+	Name      string
+	Value     string
+	CreatedAt int64
+	// 过期时间，0 表示永不过期
+	ExpiresAt int64
 }
@@ -2,6 +2,7 @@ import React, { Suspense, useEffect, useState } from 'react';
 import { BrowserRouter as Router, Routes, Route } from 'react-router-dom';
 import { decodeTheme, initTheme } from './utils/theme';
 import { Loading } from './components/Loading';
+import { basePath } from './utils/base';
 
 // 使用 React.lazy 懒加载组件
 const Home = React.lazy(() => import('./pages/Home'));
@@ -13,6 +14,10 @@ const Tools = React.lazy(() => import('./pages/admin/tabs/Tools').then(module =>
 const Catelog = React.lazy(() => import('./pages/admin/tabs/Catelog').then(module => ({ default: module.Catelog })));
 const ApiToken = React.lazy(() => import('./pages/admin/tabs/ApiToken').then(module => ({ default: module.ApiToken })));
 const Setting = React.lazy(() => import('./pages/admin/tabs/Setting').then(module => ({ default: module.Setting })));
+const Users = React.lazy(() => import('./pages/admin/tabs/Users').then(module => ({ default: module.Users })));
+const GuestLinks = React.lazy(() => import('./pages/admin/tabs/GuestLinks').then(module => ({ default: module.GuestLinks })));
+const Trash = React.lazy(() => import('./pages/admin/tabs/Trash').then(module => ({ default: module.Trash })));
+const Audit = React.lazy(() => import('./pages/admin/tabs/Audit').then(module => ({ default: module.Audit })));
 
 // 加载中的占位组件
 const LoadingFallback = () => {
@@ -50,7 +55,7 @@ import { Toaster } from 'react-hot-toast';
 
 function App() {
   return (
-    <Router>
+    <Router basename={basePath}>
       <Suspense fallback={<LoadingFallback />}>
         <Toaster />
         <Routes>
@@ -61,6 +66,10 @@ function App() {
             <Route path="tools" element={<Tools />} />
             <Route path="categories" element={<Catelog />} />
             <Route path="api-token" element={<ApiToken />} />
+            <Route path="users" element={<Users />} />
+            <Route path="guest-links" element={<GuestLinks />} />
+            <Route path="trash" element={<Trash />} />
+            <Route path="audit" element={<Audit />} />
             <Route path="settings" element={<Setting />} />
           </Route>
         </Routes>
@@ -164,14 +164,16 @@ const Content = (props: any) => {
       const localResult = data.tools
         .filter((item: any) => {
           if (currTag === "全部工具") return true;
-          return item.catelog === currTag;
+          const names = data.catelogDescendants?.[currTag];
+          return names ? names.includes(item.catelog) : item.catelog === currTag;
         })
         .filter((item: any) => {
           if (searchString === "") return true;
           return (
             mutiSearch(item.name, searchString) ||
             mutiSearch(item.desc, searchString) ||
-            mutiSearch(item.url, searchString)
+            mutiSearch(item.url, searchString) ||
+            (item.tags || []).some((tag: string) => mutiSearch(tag, searchString))
           );
         });
       return localResult;
@@ -2,6 +2,7 @@ import { useState } from "react";
 import { Button } from "../ui/Button";
 import { Input } from "../ui/Input";
 import { useToast } from "../ui/Toast";
+import { withBase } from "../../utils/base";
 
 interface LockScreenProps {
     onUnlock: () => void;
@@ -19,7 +20,7 @@ const LockScreen = ({ onUnlock }: LockScreenProps) => {
         }
         setLoading(true);
         try {
-            const res = await fetch("/api/guest/verify", {
+            const res = await fetch(withBase("/api/guest/verify"), {
                 method: "POST",
                 headers: {
                     "Content-Type": "application/json",
@@ -1,5 +1,6 @@
 import { useMemo, useState, useEffect, useRef } from "react";
 import clsx from "clsx";
+import { withBase } from "../../utils/base";
 
 interface ToolLogoProps {
     logo?: string;
@@ -12,7 +13,7 @@ interface ToolLogoProps {
 export const getLogoSrc = (logo: string) => {
     if (!logo) return "";
     if (logo.startsWith("data:") || logo.startsWith("http") || logo.startsWith("//")) return logo;
-    return `/api/img?url=${encodeURIComponent(logo)}`;
+    return withBase(`/api/img?url=${encodeURIComponent(logo)}`);
 };
 
 export const ToolLogo = ({ logo, name, className, url, timeout = 2000 }: ToolLogoProps) => {
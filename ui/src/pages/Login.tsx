@@ -1,33 +1,55 @@
 
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
 import { useNavigate } from 'react-router-dom';
-import { login } from '../utils/api';
+import { fetchOIDCConfig, login } from '../utils/api';
+import { withBase } from '../utils/base';
 import { Button } from '../components/ui/Button';
 import { Input } from '../components/ui/Input';
 
 const Login: React.FC = () => {
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
+  const [code, setCode] = useState('');
+  const [totpRequired, setTotpRequired] = useState(false);
   const [isLoading, setIsLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
+  const [oidcEnabled, setOidcEnabled] = useState(false);
   const navigate = useNavigate();
 
+  // 单点登录回调会把 token 或错误信息放在 hash 中跳转回来
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    window.history.replaceState(null, '', window.location.pathname);
+    if (params.get('token')) {
+      localStorage.setItem('_token', params.get('token') as string);
+      navigate('/admin');
+      return;
+    }
+    if (params.get('error')) {
+      setError(params.get('error'));
+    }
+    fetchOIDCConfig().then((data) => setOidcEnabled(!!data.enabled)).catch(() => {});
+  }, [navigate]);
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setIsLoading(true);
     setError(null);
 
     try {
-      const response = await login(username, password);
+      const response = await login(username, password, totpRequired ? code : undefined);
       if (response.success) {
         localStorage.setItem('_token', response.data.token);
         navigate('/admin');
       } else {
         setError(response.message || '登录失败，请检查用户名或密码');
       }
-    } catch (error) {
+    } catch (error: any) {
       console.error('登录失败:', error);
-      setError('登录请求失败，请稍后重试');
+      if (error?.response?.data?.data?.totpRequired) {
+        setTotpRequired(true);
+      }
+      setError(error?.response?.data?.errorMessage || error?.message || '登录请求失败，请稍后重试');
     } finally {
       setIsLoading(false);
     }
@@ -65,6 +87,19 @@ const Login: React.FC = () => {
               required
               disabled={isLoading}
             />
+            {totpRequired && (
+              <Input
+                label="两步验证码"
+                type="text"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                placeholder="请输入验证器中的 6 位数字或恢复码"
+                autoComplete="one-time-code"
+                autoFocus
+                required
+                disabled={isLoading}
+              />
+            )}
           </div>
 
           {error && (
@@ -81,6 +116,17 @@ const Login: React.FC = () => {
             >
               登录
             </Button>
+            {oidcEnabled && (
+              <Button
+                type="button"
+                variant="outline"
+                className="mt-3 w-full"
+                disabled={isLoading}
+                onClick={() => { window.location.href = withBase('/api/oidc/login'); }}
+              >
+                使用单点登录
+              </Button>
+            )}
           </div>
         </form>
 
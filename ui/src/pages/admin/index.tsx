@@ -8,8 +8,13 @@ import {
   GearIcon,
   BackpackIcon,
   TableIcon,
+  PersonIcon,
+  Link2Icon,
+  ReaderIcon,
+  TrashIcon,
 } from '@radix-ui/react-icons';
 import { useOnce } from '../../utils/useOnce';
+import { fetchAdminData, logout } from '../../utils/api';
 
 import DarkSwitch from '../../components/DarkSwitch';
 
@@ -32,6 +37,30 @@ const menuItems: MenuItem[] = [
     label: 'API Token',
     path: '/admin/api-token'
   },
+  {
+    key: 'users',
+    icon: <PersonIcon className="w-5 h-5" />,
+    label: '用户管理',
+    path: '/admin/users'
+  },
+  {
+    key: 'guest-links',
+    icon: <Link2Icon className="w-5 h-5" />,
+    label: '访客链接',
+    path: '/admin/guest-links'
+  },
+  {
+    key: 'trash',
+    icon: <TrashIcon className="w-5 h-5" />,
+    label: '回收站',
+    path: '/admin/trash'
+  },
+  {
+    key: 'audit',
+    icon: <ReaderIcon className="w-5 h-5" />,
+    label: '审计日志',
+    path: '/admin/audit'
+  },
   {
     key: 'settings',
     icon: <GearIcon className="w-5 h-5" />,
@@ -46,8 +75,9 @@ export const AdminPage = () => {
   const [currentKey, setCurrentKey] = useState('tools');
 
   useOnce(() => {
+    // 没有本地 token 时可能是由反向代理完成的认证，请求一次确认，未登录会被拦截器跳转到登录页
     if (!localStorage.getItem('_token')) {
-      navigate('/login');
+      fetchAdminData().catch(() => navigate('/login'));
     }
   }, []);
 
@@ -61,7 +91,12 @@ export const AdminPage = () => {
   }, [location]);
 
   // 处理退出登录
-  const handleLogout = () => {
+  const handleLogout = async () => {
+    try {
+      await logout();
+    } catch (err) {
+      console.error('登出失败:', err);
+    }
     localStorage.removeItem('_token');
     navigate('/');
   };
@@ -9,6 +9,16 @@ import { ConfirmDialog } from "../../../components/ui/ConfirmDialog";
 import { Loading } from "../../../components/Loading";
 import { useToast } from "../../../components/ui/Toast";
 
+const scopeOptions = [
+  { value: "tools:read", label: "读取工具" },
+  { value: "tools:write", label: "编辑工具" },
+  { value: "catelogs:write", label: "编辑分类" },
+  { value: "settings:write", label: "修改设置" },
+  { value: "import", label: "导入" },
+];
+
+const formatTime = (ts?: number) => ts ? new Date(ts * 1000).toLocaleString() : "-";
+
 export const ApiToken = () => {
   const [showAdd, setShowAdd] = useState(false);
   const { store, loading, reload } = useData();
@@ -16,6 +26,7 @@ export const ApiToken = () => {
   const [deleteConfirmOpen, setDeleteConfirmOpen] = useState(false);
   const [deleteTargetId, setDeleteTargetId] = useState<number | null>(null);
   const [formData, setFormData] = useState<any>({});
+  const [createdToken, setCreatedToken] = useState<string | null>(null);
 
   const handleDelete = useCallback(
     async (id: number) => {
@@ -37,8 +48,9 @@ export const ApiToken = () => {
         return;
       }
       try {
-        await fetchAddApiToken(formData);
+        const created = await fetchAddApiToken(formData);
         setShowAdd(false);
+        setCreatedToken(created?.Value ?? null);
         reload();
         success("添加成功");
       } catch (err) {
@@ -59,7 +71,7 @@ export const ApiToken = () => {
       <div className="mb-4 flex items-center justify-between rounded-lg bg-white p-4 shadow-sm dark:bg-gray-800">
         <span className="text-sm text-gray-500 dark:text-gray-400">当前共 {store?.tokens?.length ?? 0} 条</span>
         <div className="flex gap-2">
-          <Button onClick={() => { setFormData({ name: "" }); setShowAdd(true); }}>添加</Button>
+          <Button onClick={() => { setFormData({ name: "", scopes: ["tools:read"], expiresInDays: 0 }); setShowAdd(true); }}>添加</Button>
           <Button variant="outline" onClick={() => reload()}>刷新</Button>
         </div>
       </div>
@@ -76,6 +88,9 @@ export const ApiToken = () => {
                 <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">序号</th>
                 <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">名称</th>
                 <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">值</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">权限</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">过期时间</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">最近使用</th>
                 <th scope="col" className="px-4 py-3 text-right text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">操作</th>
               </tr>
             </thead>
@@ -85,12 +100,12 @@ export const ApiToken = () => {
                   <td className="px-4 py-3 text-sm text-gray-900 dark:text-white">{record.id}</td>
                   <td className="px-4 py-3 text-sm font-medium text-gray-900 dark:text-white">{record.name}</td>
                   <td className="px-4 py-3 text-sm text-gray-500 dark:text-gray-400">
-                    <div className="flex items-center gap-2 max-w-sm">
-                      <span className="truncate font-mono bg-gray-100 px-2 py-0.5 rounded text-xs dark:bg-gray-700 dark:text-gray-300">{record.value}</span>
-                      <button onClick={() => copyToClipboard(record.value)} className="text-gray-400 hover:text-blue-600">
-                        <DocumentDuplicateIcon className="h-4 w-4" />
-                      </button>
-                    </div>
+                    <span className="truncate font-mono bg-gray-100 px-2 py-0.5 rounded text-xs dark:bg-gray-700 dark:text-gray-300">{record.prefix}…</span>
+                  </td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">{(record.scopes || []).join(", ")}</td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">{record.expiresAt ? formatTime(record.expiresAt) : "永不过期"}</td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">
+                    {formatTime(record.lastUsedAt)}{record.lastUsedIp ? ` (${record.lastUsedIp})` : ""}
                   </td>
                   <td className="px-4 py-3 text-right text-sm font-medium">
                     <button onClick={() => {
@@ -117,6 +132,48 @@ export const ApiToken = () => {
             onChange={e => setFormData({ ...formData, name: e.target.value })}
             placeholder="请输入 API Token 名称"
           />
+          <div>
+            <label className="mb-1.5 block text-sm font-medium text-gray-700 dark:text-gray-300">权限范围</label>
+            <div className="flex flex-wrap gap-3">
+              {scopeOptions.map(option => (
+                <label key={option.value} className="flex items-center gap-1 text-sm text-gray-700 dark:text-gray-300">
+                  <input
+                    type="checkbox"
+                    checked={(formData.scopes || []).includes(option.value)}
+                    onChange={e => {
+                      const scopes: string[] = formData.scopes || [];
+                      setFormData({
+                        ...formData,
+                        scopes: e.target.checked ? [...scopes, option.value] : scopes.filter(s => s !== option.value),
+                      });
+                    }}
+                  />
+                  {option.label}
+                </label>
+              ))}
+            </div>
+          </div>
+          <Input
+            label="有效天数（0 表示永不过期）"
+            type="number"
+            min={0}
+            value={formData.expiresInDays ?? 0}
+            onChange={e => setFormData({ ...formData, expiresInDays: Number(e.target.value) || 0 })}
+          />
+        </div>
+      </Modal>
+
+      <Modal isOpen={!!createdToken} onClose={() => setCreatedToken(null)} title="Token 已创建"
+        footer={<Button onClick={() => setCreatedToken(null)}>我已保存</Button>}
+      >
+        <div className="space-y-3">
+          <p className="text-sm text-gray-500 dark:text-gray-400">完整的 Token 只会显示这一次，关闭后无法再次查看，请立即复制保存。</p>
+          <div className="flex items-center gap-2">
+            <span className="flex-1 break-all font-mono bg-gray-100 px-2 py-1 rounded text-xs dark:bg-gray-700 dark:text-gray-300">{createdToken}</span>
+            <button onClick={() => createdToken && copyToClipboard(createdToken)} className="text-gray-400 hover:text-blue-600">
+              <DocumentDuplicateIcon className="h-4 w-4" />
+            </button>
+          </div>
         </div>
       </Modal>
 
@@ -0,0 +1,102 @@
+import { useCallback, useEffect, useState } from 'react';
+import { fetchAudit } from '../../../utils/api';
+import { Button } from "../../../components/ui/Button";
+import { Input } from "../../../components/ui/Input";
+import { Pagination } from "../../../components/ui/Pagination";
+import { Loading } from "../../../components/Loading";
+import { useToast } from "../../../components/ui/Toast";
+
+const formatTime = (ts?: number) => ts ? new Date(ts * 1000).toLocaleString() : "-";
+
+const formatValue = (value: any) => value === null || value === undefined ? "-" : typeof value === "object" ? JSON.stringify(value) : String(value);
+
+export const Audit = () => {
+  const [items, setItems] = useState<any[]>([]);
+  const [total, setTotal] = useState(0);
+  const [page, setPage] = useState(1);
+  const pageSize = 20;
+  const [filters, setFilters] = useState({ actor: "", action: "", targetType: "", targetId: "" });
+  const [query, setQuery] = useState(filters);
+  const [loading, setLoading] = useState(false);
+  const { error } = useToast();
+
+  const reload = useCallback(async () => {
+    setLoading(true);
+    try {
+      const data = await fetchAudit(page, pageSize, query);
+      setItems(data.items || []);
+      setTotal(data.total || 0);
+    } catch (err: any) {
+      error(err?.message || "获取审计日志失败");
+    } finally {
+      setLoading(false);
+    }
+  }, [page, query]);
+
+  useEffect(() => {
+    reload();
+  }, [reload]);
+
+  return (
+    <div className="h-full flex flex-col p-4">
+      <div className="mb-4 flex flex-wrap items-end gap-2 rounded-lg bg-white p-4 shadow-sm dark:bg-gray-800">
+        <div className="w-36"><Input placeholder="操作者" value={filters.actor} onChange={e => setFilters({ ...filters, actor: e.target.value })} /></div>
+        <div className="w-36"><Input placeholder="操作，如 tool.delete" value={filters.action} onChange={e => setFilters({ ...filters, action: e.target.value })} /></div>
+        <div className="w-36"><Input placeholder="对象类型，如 tool" value={filters.targetType} onChange={e => setFilters({ ...filters, targetType: e.target.value })} /></div>
+        <div className="w-36"><Input placeholder="对象 id" value={filters.targetId} onChange={e => setFilters({ ...filters, targetId: e.target.value })} /></div>
+        <Button onClick={() => { setPage(1); setQuery(filters); }}>查询</Button>
+        <Button variant="outline" onClick={() => reload()}>刷新</Button>
+        <span className="ml-auto text-sm text-gray-500 dark:text-gray-400">当前共 {total} 条</span>
+      </div>
+
+      <div className="flex-1 overflow-auto rounded-lg bg-white shadow-sm dark:bg-gray-800">
+        {loading ? (
+          <div className="flex h-full items-center justify-center">
+            <Loading />
+          </div>
+        ) : (
+          <table className="min-w-full divide-y divide-gray-200 dark:divide-gray-700">
+            <thead className="bg-gray-50 dark:bg-gray-700/50 sticky top-0 z-10">
+              <tr>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">时间</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">操作者</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">操作</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">对象</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">变化</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">IP</th>
+              </tr>
+            </thead>
+            <tbody className="divide-y divide-gray-200 bg-white dark:divide-gray-700 dark:bg-gray-800">
+              {items.map((record: any) => (
+                <tr key={record.id} className="hover:bg-gray-50 dark:hover:bg-gray-800 align-top">
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400 whitespace-nowrap">{formatTime(record.createdAt)}</td>
+                  <td className="px-4 py-3 text-sm text-gray-900 dark:text-white whitespace-nowrap">
+                    {record.actor}
+                    {record.actorType === "token" && <span className="ml-1 rounded bg-gray-100 px-1.5 py-0.5 text-xs text-gray-500 dark:bg-gray-700 dark:text-gray-300">Token</span>}
+                  </td>
+                  <td className="px-4 py-3 text-xs font-mono text-gray-700 dark:text-gray-300">{record.action}</td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">{record.targetType}{record.targetId ? ` #${record.targetId}` : ""}</td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">
+                    {Object.entries(record.changes || {}).map(([key, change]: [string, any]) => (
+                      <div key={key} className="break-all">
+                        <span className="font-medium text-gray-700 dark:text-gray-300">{key}</span>: {formatValue(change.before)} → {formatValue(change.after)}
+                      </div>
+                    ))}
+                  </td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">{record.ip}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        )}
+      </div>
+
+      <Pagination
+        currentPage={page}
+        pageSize={pageSize}
+        total={total}
+        onPageChange={(p) => setPage(p)}
+      />
+    </div>
+  );
+};
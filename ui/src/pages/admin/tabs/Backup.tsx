@@ -0,0 +1,120 @@
+import { useCallback, useEffect, useState } from "react";
+import { fetchBackup, fetchBackupStatus, fetchRestore, fetchRunBackup } from "../../../utils/api";
+import { withBase } from "../../../utils/base";
+import { Button } from "../../../components/ui/Button";
+import { ConfirmDialog } from "../../../components/ui/ConfirmDialog";
+import toast from "react-hot-toast";
+
+const formatTime = (seconds: number) => new Date(seconds * 1000).toLocaleString();
+
+// 数据库备份下载和从备份恢复，只有 owner 可以使用
+export const Backup = () => {
+  const [requestLoading, setRequestLoading] = useState(false);
+  const [restoreFile, setRestoreFile] = useState<File | null>(null);
+  const [status, setStatus] = useState<any>({});
+  const [files, setFiles] = useState<any[]>([]);
+
+  const loadStatus = useCallback(async () => {
+    try {
+      const data = await fetchBackupStatus();
+      setStatus(data.status || {});
+      setFiles(data.files || []);
+    } catch (err: any) {
+      toast.error(err.message || "获取备份状态失败");
+    }
+  }, []);
+
+  useEffect(() => {
+    loadStatus();
+  }, [loadStatus]);
+
+  const handleRunBackup = useCallback(async () => {
+    setRequestLoading(true);
+    try {
+      await fetchRunBackup();
+      toast.success("备份成功");
+    } catch (err: any) {
+      toast.error(err.message || "备份失败!");
+    } finally {
+      setRequestLoading(false);
+      loadStatus();
+    }
+  }, [loadStatus]);
+
+  const handleBackup = useCallback(async () => {
+    setRequestLoading(true);
+    try {
+      const blob = await fetchBackup();
+      const url = URL.createObjectURL(blob);
+      const a = document.createElement("a");
+      a.href = url;
+      a.download = "nav.db";
+      document.documentElement.appendChild(a);
+      a.click();
+      document.documentElement.removeChild(a);
+      URL.revokeObjectURL(url);
+    } catch (err: any) {
+      toast.error(err.message || "备份失败!");
+    } finally {
+      setRequestLoading(false);
+    }
+  }, []);
+
+  const handleRestore = useCallback(async () => {
+    if (!restoreFile) return;
+    setRequestLoading(true);
+    try {
+      await fetchRestore(restoreFile);
+      toast.success("恢复成功，请重新登录");
+      window.localStorage.removeItem("_token");
+      window.location.href = withBase("/login");
+    } catch (err: any) {
+      toast.error(err.message || "恢复失败!");
+    } finally {
+      setRequestLoading(false);
+      setRestoreFile(null);
+    }
+  }, [restoreFile]);
+
+  return (
+    <div className="rounded-lg bg-white p-6 shadow-sm dark:bg-gray-800">
+      <h2 className="mb-6 text-lg font-medium text-gray-900 dark:text-white border-b pb-2 border-gray-100 dark:border-gray-700">备份与恢复</h2>
+      <div className="space-y-4 max-w-lg">
+        <p className="text-sm text-gray-500 dark:text-gray-400">
+          下载的备份是运行中数据库的一致快照。恢复会用上传的备份替换全部数据，替换前当前数据库会自动备份到数据目录的 backups 文件夹。
+        </p>
+        <div className="text-sm text-gray-700 dark:text-gray-300 space-y-1">
+          <div>自动备份：{status.enabled ? `已开启（${status.schedule}）` : "未开启"}</div>
+          {status.enabled && status.nextRunAt > 0 && <div>下次备份：{formatTime(status.nextRunAt)}</div>}
+          <div>上次成功：{status.lastSuccessAt > 0 ? `${formatTime(status.lastSuccessAt)} ${status.lastFile || ""}` : "无"}</div>
+          {status.lastError && <div className="text-red-600">上次失败：{status.lastError}</div>}
+          <div>备份目录中共有 {files.length} 份备份</div>
+        </div>
+        <div className="flex gap-2">
+          <Button variant="outline" onClick={handleBackup} isLoading={requestLoading}>下载备份</Button>
+          <Button variant="outline" onClick={handleRunBackup} isLoading={requestLoading}>立即备份</Button>
+          <label className="inline-flex cursor-pointer items-center rounded-md border border-gray-300 px-4 py-2 text-sm font-medium text-gray-700 hover:bg-gray-50 dark:border-gray-600 dark:text-gray-200 dark:hover:bg-gray-700">
+            从备份恢复
+            <input
+              type="file"
+              accept=".db"
+              className="hidden"
+              onChange={e => {
+                setRestoreFile(e.target.files?.[0] || null);
+                e.target.value = "";
+              }}
+            />
+          </label>
+        </div>
+      </div>
+      <ConfirmDialog
+        isOpen={!!restoreFile}
+        onClose={() => setRestoreFile(null)}
+        onConfirm={handleRestore}
+        title="从备份恢复"
+        description={`确定用 ${restoreFile?.name || ""} 替换当前所有数据吗？恢复后需要重新登录。`}
+        isDestructive
+      />
+    </div>
+  );
+};
@@ -20,6 +20,7 @@ import { CSS } from "@dnd-kit/utilities";
 import {
   fetchAddCateLog,
   fetchDeleteCatelog,
+  fetchMoveCatelog,
   fetchUpdateCateLog,
   fetchUpdateCatelogsSort,
 } from "../../../utils/api";
@@ -27,18 +28,43 @@ import { useData } from "../hooks/useData";
 import { Button } from "../../../components/ui/Button";
 import { Input } from "../../../components/ui/Input";
 import { Switch } from "../../../components/ui/Switch";
+import { Select } from "../../../components/ui/Select";
 import { Modal } from "../../../components/ui/Modal";
-import { ConfirmDialog } from "../../../components/ui/ConfirmDialog";
 import { Loading } from "../../../components/Loading";
 
 interface DataType {
   id: number;
   name: string;
+  parentId: number;
   sort: number;
   hide: boolean;
   [key: string]: any;
 }
 
+// 分类的层级，顶级分类为 0
+const catelogDepth = (record: DataType, byId: Map<number, DataType>) => {
+  let depth = 0;
+  let parent = byId.get(record.parentId);
+  while (parent && depth < byId.size) {
+    depth++;
+    parent = byId.get(parent.parentId);
+  }
+  return depth;
+};
+
+// 分类自己和它的所有子分类的 id
+const descendantIds = (id: number, list: DataType[]) => {
+  const ids = new Set<number>([id]);
+  let size = 0;
+  while (ids.size !== size) {
+    size = ids.size;
+    list.forEach((item) => {
+      if (ids.has(item.parentId)) ids.add(item.id);
+    });
+  }
+  return ids;
+};
+
 // Draggable Row Component
 interface SortableRowProps {
   id: string;
@@ -87,8 +113,9 @@ export const Catelog = () => {
   const [requestLoading, setRequestLoading] = useState(false);
 
   const [formData, setFormData] = useState<any>({});
-  const [deleteConfirmOpen, setDeleteConfirmOpen] = useState(false);
-  const [deleteTargetId, setDeleteTargetId] = useState<number | null>(null);
+  const [deleteTarget, setDeleteTarget] = useState<DataType | null>(null);
+  const [deleteMode, setDeleteMode] = useState<"move" | "cascade">("move");
+  const [moveTo, setMoveTo] = useState("");
   const [dataSource, setDataSource] = useState<DataType[]>([]);
 
   const sensors = useSensors(
@@ -104,6 +131,16 @@ export const Catelog = () => {
     }
   }, [store?.catelogs]);
 
+  const byId = new Map(dataSource.map((item) => [item.id, item] as [number, DataType]));
+  // 上级分类的选项，不能选择自己或自己的子分类
+  const excludedParents = formData.id ? descendantIds(formData.id, dataSource) : new Set<number>();
+  const parentOptions = [
+    { value: "0", label: "无（顶级分类）" },
+    ...dataSource
+      .filter((item) => !excludedParents.has(item.id))
+      .map((item) => ({ value: String(item.id), label: `${"　".repeat(catelogDepth(item, byId))}${item.name}` })),
+  ];
+
   const handleDragEnd = async (event: DragEndEvent) => {
     const { active, over } = event;
     if (active.id !== over?.id) {
@@ -124,16 +161,40 @@ export const Catelog = () => {
     }
   };
 
+  // 分类下还有工具时，需要选择移动到其他分类或一起移入回收站
+  const deleteToolCount = (store?.tools || []).filter((tool: any) => tool.catelogId === deleteTarget?.id).length;
+  const moveOptions = dataSource
+    .filter((item) => item.id !== deleteTarget?.id)
+    .map((item) => ({ value: String(item.id), label: item.name }));
+
+  const openDelete = (record: DataType) => {
+    setDeleteTarget(record);
+    setDeleteMode("move");
+    setMoveTo("");
+  };
+
   const handleDelete = useCallback(
-    async (id: number) => {
+    async () => {
+      if (!deleteTarget) return;
+      if (deleteToolCount > 0 && deleteMode === "move" && !moveTo) {
+        alert("请选择要移动到的分类");
+        return;
+      }
       try {
-        await fetchDeleteCatelog(id);
+        if (deleteToolCount === 0) {
+          await fetchDeleteCatelog(deleteTarget.id);
+        } else if (deleteMode === "move") {
+          await fetchDeleteCatelog(deleteTarget.id, "move", Number(moveTo));
+        } else {
+          await fetchDeleteCatelog(deleteTarget.id, "cascade");
+        }
+        setDeleteTarget(null);
         reload();
-      } catch (err) {
-        alert("删除分类失败!");
+      } catch (err: any) {
+        alert(err.message || "删除分类失败!");
       }
     },
-    [reload]
+    [reload, deleteTarget, deleteToolCount, deleteMode, moveTo]
   );
 
   const handleCreate = useCallback(
@@ -158,19 +219,23 @@ export const Catelog = () => {
       setRequestLoading(true);
       try {
         await fetchUpdateCateLog(formData);
+        const before = dataSource.find((item) => item.id === formData.id);
+        if (before && (before.parentId || 0) !== (formData.parentId || 0)) {
+          await fetchMoveCatelog(formData.id, formData.parentId || 0);
+        }
         setShowEdit(false);
         reload();
-      } catch (err) {
-        alert("更新失败!");
+      } catch (err: any) {
+        alert(err.message || "更新失败!");
       } finally {
         setRequestLoading(false);
       }
     },
-    [formData, reload]
+    [formData, reload, dataSource]
   );
 
   const openAdd = () => {
-    setFormData({ sort: 1, hide: false, name: "" });
+    setFormData({ sort: 1, hide: false, name: "", parentId: 0 });
     setShowAdd(true);
   }
 
@@ -221,7 +286,9 @@ export const Catelog = () => {
                   {dataSource.map((record) => (
                     <SortableRow key={record.id} id={record.id.toString()}>
                       <td className="px-4 py-3 text-sm text-gray-900 dark:text-white">{record.id}</td>
-                      <td className="px-4 py-3 text-sm font-medium text-gray-900 dark:text-white">{record.name}</td>
+                      <td className="px-4 py-3 text-sm font-medium text-gray-900 dark:text-white">
+                        <span style={{ paddingLeft: `${catelogDepth(record, byId) * 1.5}rem` }}>{record.name}</span>
+                      </td>
                       <td className="px-4 py-3 text-sm text-gray-500 dark:text-gray-400">{record.sort}</td>
                       <td className="px-4 py-3 text-sm text-gray-500 dark:text-gray-400">{record.hide ? "是" : "否"}</td>
                       <td className="px-4 py-3 text-right text-sm font-medium">
@@ -229,10 +296,7 @@ export const Catelog = () => {
                           <button onClick={() => openEdit(record)} className="text-blue-600 hover:text-blue-900 dark:text-blue-400 dark:hover:text-blue-300">
                             <PencilSquareIcon className="h-5 w-5" />
                           </button>
-                          <button onClick={() => {
-                            setDeleteTargetId(record.id);
-                            setDeleteConfirmOpen(true);
-                          }} className="text-red-600 hover:text-red-900 dark:text-red-400 dark:hover:text-red-300">
+                          <button onClick={() => openDelete(record)} className="text-red-600 hover:text-red-900 dark:text-red-400 dark:hover:text-red-300">
                             <TrashIcon className="h-5 w-5" />
                           </button>
                         </div>
@@ -257,6 +321,12 @@ export const Catelog = () => {
             onChange={e => setFormData({ ...formData, name: e.target.value })}
             placeholder="请输入分类名称"
           />
+          <Select
+            label="上级分类"
+            value={String(formData.parentId || 0)}
+            onChange={(val) => setFormData({ ...formData, parentId: Number(val) })}
+            options={parentOptions}
+          />
           <Input
             label="排序"
             type="number"
@@ -283,6 +353,12 @@ export const Catelog = () => {
             onChange={e => setFormData({ ...formData, name: e.target.value })}
             placeholder="请输入分类名称"
           />
+          <Select
+            label="上级分类"
+            value={String(formData.parentId || 0)}
+            onChange={(val) => setFormData({ ...formData, parentId: Number(val) })}
+            options={parentOptions}
+          />
           <Input
             label="排序"
             type="number"
@@ -297,16 +373,31 @@ export const Catelog = () => {
         </div>
       </Modal>
 
-      <ConfirmDialog
-        isOpen={deleteConfirmOpen}
-        onClose={() => setDeleteConfirmOpen(false)}
-        onConfirm={() => {
-          if (deleteTargetId) handleDelete(deleteTargetId);
-        }}
-        title="确认删除"
-        description="确定要删除这个分类吗？"
-        isDestructive
-      />
+      {/* Delete Modal */}
+      <Modal isOpen={!!deleteTarget} onClose={() => setDeleteTarget(null)} title="删除分类"
+        footer={<><Button variant="secondary" onClick={() => setDeleteTarget(null)}>取消</Button><Button variant="danger" onClick={handleDelete}>删除</Button></>}
+      >
+        <div className="space-y-4 text-sm text-gray-700 dark:text-gray-300">
+          {deleteToolCount === 0 ? (
+            <p>确定要删除分类「{deleteTarget?.name}」吗？删除后可以在回收站中恢复。</p>
+          ) : (
+            <>
+              <p>分类「{deleteTarget?.name}」下还有 {deleteToolCount} 个工具，请选择如何处理：</p>
+              <label className="flex items-center gap-2">
+                <input type="radio" checked={deleteMode === "move"} onChange={() => setDeleteMode("move")} />
+                移动到其他分类
+              </label>
+              {deleteMode === "move" && (
+                <Select value={moveTo} onChange={setMoveTo} options={moveOptions} placeholder="请选择分类" />
+              )}
+              <label className="flex items-center gap-2">
+                <input type="radio" checked={deleteMode === "cascade"} onChange={() => setDeleteMode("cascade")} />
+                和分类一起移入回收站
+              </label>
+            </>
+          )}
+        </div>
+      </Modal>
     </div>
   );
 };
@@ -0,0 +1,198 @@
+import { useCallback, useEffect, useState } from 'react';
+import { TrashIcon, DocumentDuplicateIcon } from "@heroicons/react/24/outline";
+import { fetchAddGuestLink, fetchDeleteGuestLink, fetchGuestLinks } from '../../../utils/api';
+import { useData } from '../hooks/useData';
+import { Button } from "../../../components/ui/Button";
+import { Input } from "../../../components/ui/Input";
+import { Modal } from "../../../components/ui/Modal";
+import { ConfirmDialog } from "../../../components/ui/ConfirmDialog";
+import { Loading } from "../../../components/Loading";
+import { useToast } from "../../../components/ui/Toast";
+
+const formatTime = (ts?: number) => ts ? new Date(ts * 1000).toLocaleString() : "-";
+
+export const GuestLinks = () => {
+  const { store } = useData();
+  const [links, setLinks] = useState<any[]>([]);
+  const [loading, setLoading] = useState(false);
+  const [showAdd, setShowAdd] = useState(false);
+  const [formData, setFormData] = useState<any>({});
+  const [createdLink, setCreatedLink] = useState<string | null>(null);
+  const [deleteConfirmOpen, setDeleteConfirmOpen] = useState(false);
+  const [deleteTargetId, setDeleteTargetId] = useState<string | null>(null);
+  const { success, error } = useToast();
+
+  const reload = useCallback(async () => {
+    setLoading(true);
+    try {
+      setLinks(await fetchGuestLinks());
+    } catch (err: any) {
+      error(err?.message || "获取访客链接失败");
+    } finally {
+      setLoading(false);
+    }
+  }, []);
+
+  useEffect(() => {
+    reload();
+  }, [reload]);
+
+  const handleCreate = useCallback(async () => {
+    try {
+      const created = await fetchAddGuestLink(formData);
+      setShowAdd(false);
+      setCreatedLink(created?.path ? window.location.origin + created.path : null);
+      reload();
+      success("创建成功");
+    } catch (err: any) {
+      error(err?.message || "创建失败!");
+    }
+  }, [formData, reload]);
+
+  const handleDelete = useCallback(async (id: string) => {
+    try {
+      await fetchDeleteGuestLink(id);
+      reload();
+      success("删除成功");
+    } catch (err: any) {
+      error(err?.message || "删除失败!");
+    }
+  }, [reload]);
+
+  const catelogName = (id: number) => store?.catelogs?.find((item: any) => item.id === id)?.name ?? id;
+
+  const copyToClipboard = (text: string) => {
+    navigator.clipboard.writeText(text).then(() => {
+      success("已复制到剪贴板");
+    });
+  };
+
+  return (
+    <div className="h-full flex flex-col p-4">
+      <div className="mb-4 flex items-center justify-between rounded-lg bg-white p-4 shadow-sm dark:bg-gray-800">
+        <span className="text-sm text-gray-500 dark:text-gray-400">当前共 {links.length} 条</span>
+        <div className="flex gap-2">
+          <Button onClick={() => { setFormData({ name: "", expiresInHours: 168, maxUses: 0, catelogIds: [] }); setShowAdd(true); }}>添加</Button>
+          <Button variant="outline" onClick={() => reload()}>刷新</Button>
+        </div>
+      </div>
+
+      <div className="flex-1 overflow-auto rounded-lg bg-white shadow-sm dark:bg-gray-800">
+        {loading ? (
+          <div className="flex h-full items-center justify-center">
+            <Loading />
+          </div>
+        ) : (
+          <table className="min-w-full divide-y divide-gray-200 dark:divide-gray-700">
+            <thead className="bg-gray-50 dark:bg-gray-700/50 sticky top-0 z-10">
+              <tr>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">名称</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">链接</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">分类</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">使用次数</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">过期时间</th>
+                <th scope="col" className="px-4 py-3 text-right text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">操作</th>
+              </tr>
+            </thead>
+            <tbody className="divide-y divide-gray-200 bg-white dark:divide-gray-700 dark:bg-gray-800">
+              {links.map((record: any) => (
+                <tr key={record.id} className="hover:bg-gray-50 dark:hover:bg-gray-800">
+                  <td className="px-4 py-3 text-sm font-medium text-gray-900 dark:text-white">{record.name}</td>
+                  <td className="px-4 py-3 text-sm text-gray-500 dark:text-gray-400">
+                    <span className="truncate font-mono bg-gray-100 px-2 py-0.5 rounded text-xs dark:bg-gray-700 dark:text-gray-300">/g/{record.prefix}…</span>
+                  </td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">
+                    {record.catelogIds?.length ? record.catelogIds.map(catelogName).join(", ") : "全部"}
+                  </td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">{record.uses} / {record.maxUses || "不限"}</td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">{formatTime(record.expiresAt)}</td>
+                  <td className="px-4 py-3 text-right text-sm font-medium">
+                    <button onClick={() => {
+                      setDeleteTargetId(record.id);
+                      setDeleteConfirmOpen(true);
+                    }} className="text-red-600 hover:text-red-900 dark:text-red-400 dark:hover:text-red-300">
+                      <TrashIcon className="h-5 w-5" />
+                    </button>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        )}
+      </div>
+
+      <Modal isOpen={showAdd} onClose={() => setShowAdd(false)} title="新建访客链接"
+        footer={<><Button variant="secondary" onClick={() => setShowAdd(false)}>取消</Button><Button onClick={handleCreate}>确定</Button></>}
+      >
+        <div className="space-y-4">
+          <Input
+            label="名称"
+            value={formData.name}
+            onChange={e => setFormData({ ...formData, name: e.target.value })}
+            placeholder="例如：外包同事"
+          />
+          <Input
+            label="有效小时数"
+            type="number"
+            min={1}
+            value={formData.expiresInHours ?? 168}
+            onChange={e => setFormData({ ...formData, expiresInHours: Number(e.target.value) || 0 })}
+          />
+          <Input
+            label="最大使用次数（0 表示不限制）"
+            type="number"
+            min={0}
+            value={formData.maxUses ?? 0}
+            onChange={e => setFormData({ ...formData, maxUses: Number(e.target.value) || 0 })}
+          />
+          <div>
+            <label className="mb-1.5 block text-sm font-medium text-gray-700 dark:text-gray-300">允许访问的分类（不选表示全部）</label>
+            <div className="flex flex-wrap gap-3">
+              {store?.catelogs?.map((item: any) => (
+                <label key={item.id} className="flex items-center gap-1 text-sm text-gray-700 dark:text-gray-300">
+                  <input
+                    type="checkbox"
+                    checked={(formData.catelogIds || []).includes(item.id)}
+                    onChange={e => {
+                      const ids: number[] = formData.catelogIds || [];
+                      setFormData({
+                        ...formData,
+                        catelogIds: e.target.checked ? [...ids, item.id] : ids.filter(id => id !== item.id),
+                      });
+                    }}
+                  />
+                  {item.name}
+                </label>
+              ))}
+            </div>
+          </div>
+        </div>
+      </Modal>
+
+      <Modal isOpen={!!createdLink} onClose={() => setCreatedLink(null)} title="访客链接已创建"
+        footer={<Button onClick={() => setCreatedLink(null)}>我已保存</Button>}
+      >
+        <div className="space-y-3">
+          <p className="text-sm text-gray-500 dark:text-gray-400">完整链接只会显示这一次，关闭后无法再次查看，请立即复制保存。</p>
+          <div className="flex items-center gap-2">
+            <span className="flex-1 break-all font-mono bg-gray-100 px-2 py-1 rounded text-xs dark:bg-gray-700 dark:text-gray-300">{createdLink}</span>
+            <button onClick={() => createdLink && copyToClipboard(createdLink)} className="text-gray-400 hover:text-blue-600">
+              <DocumentDuplicateIcon className="h-4 w-4" />
+            </button>
+          </div>
+        </div>
+      </Modal>
+
+      <ConfirmDialog
+        isOpen={deleteConfirmOpen}
+        onClose={() => setDeleteConfirmOpen(false)}
+        onConfirm={() => {
+          if (deleteTargetId) handleDelete(deleteTargetId);
+        }}
+        title="确认删除"
+        description="删除后通过该链接进入的访客将无法继续访问，确定要删除吗？"
+        isDestructive
+      />
+    </div>
+  );
+};
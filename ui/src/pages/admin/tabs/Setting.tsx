@@ -1,11 +1,13 @@
 import { useCallback, useEffect, useState } from "react";
-import { fetchUpdateSetting, fetchUpdateUser } from "../../../utils/api";
+import { fetchRotateGuestSecret, fetchUpdateSetting, fetchUpdateUser } from "../../../utils/api";
 import { useData } from "../hooks/useData";
 import { Button } from "../../../components/ui/Button";
 import { Input } from "../../../components/ui/Input";
 import { Select } from "../../../components/ui/Select";
 import { Switch } from "../../../components/ui/Switch";
 import { Loading } from "../../../components/Loading";
+import { TwoFactor } from "./TwoFactor";
+import { Backup } from "./Backup";
 
 import toast from "react-hot-toast";
 
@@ -56,6 +58,21 @@ export const Setting = () => {
     [settingData, reload]
   );
 
+  const handleRotateGuestSecret = useCallback(
+    async () => {
+      setRequestLoading(true);
+      try {
+        await fetchRotateGuestSecret();
+        toast.success("已撤销所有访客会话");
+      } catch (err: any) {
+        toast.error(err.message || "操作失败!");
+      } finally {
+        setRequestLoading(false);
+      }
+    },
+    []
+  );
+
   if (loading) return <Loading />;
 
   return (
@@ -81,6 +98,8 @@ export const Setting = () => {
         </div>
       </div>
 
+      <TwoFactor />
+
       <div className="rounded-lg bg-white p-6 shadow-sm dark:bg-gray-800">
         <h2 className="mb-6 text-lg font-medium text-gray-900 dark:text-white border-b pb-2 border-gray-100 dark:border-gray-700">修改网站信息</h2>
         <div className="space-y-5 max-w-2xl">
@@ -151,7 +170,10 @@ export const Setting = () => {
             placeholder="设置后，访问首页需输入密码（留空则不限制）"
             type="password"
           />
-          <p className="text-xs text-gray-500 -mt-3">若设置为 "********" 表示密码未变动</p>
+          <p className="text-xs text-gray-500 -mt-3">若设置为 "********" 表示密码未变动，修改密码后已验证的访客需要重新输入</p>
+          <div>
+            <Button variant="outline" onClick={handleRotateGuestSecret} isLoading={requestLoading}>撤销所有访客会话</Button>
+          </div>
         </div>
 
         <div className="space-y-4 pt-2 border-t border-gray-100 dark:border-gray-700">
@@ -185,6 +207,8 @@ export const Setting = () => {
           <Button onClick={handleUpdateWebSite} isLoading={requestLoading}>提交修改</Button>
         </div>
       </div>
+
+      <Backup />
     </div>
   );
 };
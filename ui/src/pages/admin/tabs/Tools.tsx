@@ -17,7 +17,7 @@ import {
   verticalListSortingStrategy,
 } from "@dnd-kit/sortable";
 import { CSS } from "@dnd-kit/utilities";
-import { Bars3Icon, PencilSquareIcon, TrashIcon, CloudArrowUpIcon, LinkIcon, GlobeAltIcon } from "@heroicons/react/24/outline";
+import { Bars3Icon, PencilSquareIcon, TrashIcon, CloudArrowUpIcon, LinkIcon, GlobeAltIcon, ClockIcon } from "@heroicons/react/24/outline";
 import { getOptions, mutiSearch } from "../../../utils/admin";
 import {
   fetchAddTool,
@@ -28,6 +28,9 @@ import {
   fetchUpdateTool,
   fetchUpdateToolsSort,
   fetchToolsPage,
+  fetchToolRevisions,
+  fetchToolRevisionDiff,
+  fetchRollbackTool,
 } from "../../../utils/api";
 import { useData } from "../hooks/useData";
 import { Button } from "../../../components/ui/Button";
@@ -40,6 +43,7 @@ import { Loading } from "../../../components/Loading";
 import { useToast } from "../../../components/ui/Toast";
 import { ToolLogo } from "../../../components/ToolLogo";
 import { Pagination } from "../../../components/ui/Pagination";
+import { withBase } from "../../../utils/base";
 
 interface DataType {
   id: number;
@@ -255,7 +259,7 @@ const ToolModal = ({
               <span className="text-xs text-gray-500">预览:</span>
               {formData.logo ? (
                 <img
-                  src={(formData.logo.startsWith("data:") || formData.logo.startsWith("http")) ? formData.logo : `/api/img?url=${encodeURIComponent(formData.logo)}`}
+                  src={(formData.logo.startsWith("data:") || formData.logo.startsWith("http")) ? formData.logo : withBase(`/api/img?url=${encodeURIComponent(formData.logo)}`)}
                   alt="Preview"
                   className="h-8 w-8 rounded object-contain border bg-white"
                   onError={(e) => { }}
@@ -274,6 +278,13 @@ const ToolModal = ({
             placeholder="选择分类"
           />
         </FormItem>
+        <FormItem label="标签">
+          <Input
+            value={formData.tagsText || ""}
+            onChange={e => setFormData({ ...formData, tagsText: e.target.value })}
+            placeholder="多个标签用逗号分隔，例如 prod, oncall"
+          />
+        </FormItem>
         <FormItem label="描述" className="items-start">
           <Input
             textarea
@@ -297,6 +308,147 @@ const ToolModal = ({
   );
 };
 
+const revisionActions: Record<string, string> = {
+  init: "初始版本",
+  create: "新建",
+  update: "修改",
+  sort: "排序",
+  import: "导入",
+  delete: "删除",
+  restore: "从回收站恢复",
+  rollback: "回滚",
+};
+
+const revisionFields: Record<string, string> = {
+  name: "名称",
+  url: "网址",
+  logo: "图标",
+  catelog: "分类",
+  desc: "描述",
+  sort: "排序",
+  hide: "隐藏",
+  deletedAt: "删除时间",
+};
+
+const formatTime = (ts?: number) => ts ? new Date(ts * 1000).toLocaleString() : "-";
+
+const formatValue = (value: any) => {
+  if (value === undefined || value === null || value === "") return "-";
+  if (typeof value === "boolean") return value ? "是" : "否";
+  return String(value);
+};
+
+// 历史版本：选择一个版本查看它与当前内容的差异，可以回滚到该版本
+interface ToolHistoryModalProps {
+  tool: DataType | null;
+  onClose: () => void;
+  onRollback: () => void;
+}
+
+const ToolHistoryModal = ({ tool, onClose, onRollback }: ToolHistoryModalProps) => {
+  const { success, error } = useToast();
+  const [revisions, setRevisions] = useState<any[]>([]);
+  const [selected, setSelected] = useState<any>(null);
+  const [changes, setChanges] = useState<Record<string, any>>({});
+  const [loading, setLoading] = useState(false);
+
+  useEffect(() => {
+    setRevisions([]);
+    setSelected(null);
+    setChanges({});
+    if (!tool) return;
+    fetchToolRevisions(tool.id).then(setRevisions).catch(() => error("加载历史版本失败"));
+  }, [tool]);
+
+  const select = async (revision: any) => {
+    setSelected(revision);
+    setChanges({});
+    if (!tool || revisions.length === 0 || revision.id === revisions[0].id) return;
+    try {
+      const res = await fetchToolRevisionDiff(tool.id, revision.id, revisions[0].id);
+      setChanges(res.changes || {});
+    } catch (e) {
+      error("加载差异失败");
+    }
+  };
+
+  const rollback = async () => {
+    if (!tool || !selected) return;
+    setLoading(true);
+    try {
+      await fetchRollbackTool(tool.id, selected.id);
+      success("已恢复到该版本");
+      onRollback();
+      onClose();
+    } catch (e: any) {
+      error(e.message || "恢复失败");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const isLatest = selected && revisions.length > 0 && selected.id === revisions[0].id;
+
+  return (
+    <Modal isOpen={!!tool} onClose={onClose} title={`历史版本 - ${tool?.name || ""}`} panelClassName="max-w-3xl"
+      footer={
+        <div className="flex justify-end gap-2">
+          <Button variant="secondary" onClick={onClose}>关闭</Button>
+          <Button onClick={rollback} isLoading={loading} disabled={!selected || isLatest}>恢复到此版本</Button>
+        </div>
+      }
+    >
+      <div className="flex gap-4 max-h-[60vh]">
+        <ul className="w-1/3 overflow-auto divide-y divide-gray-200 dark:divide-gray-700 text-sm">
+          {revisions.map((revision) => (
+            <li key={revision.id}>
+              <button
+                onClick={() => select(revision)}
+                className={clsx("w-full text-left px-2 py-2 hover:bg-gray-50 dark:hover:bg-gray-700/50",
+                  selected?.id === revision.id && "bg-blue-50 dark:bg-blue-900/30")}
+              >
+                <div className="text-gray-900 dark:text-white">{revisionActions[revision.action] || revision.action}</div>
+                <div className="text-xs text-gray-500 dark:text-gray-400">{formatTime(revision.createdAt)} · {revision.actor}</div>
+              </button>
+            </li>
+          ))}
+        </ul>
+        <div className="flex-1 overflow-auto text-sm">
+          {!selected && <div className="text-gray-500 dark:text-gray-400">选择一个版本查看与当前内容的差异</div>}
+          {isLatest && <div className="text-gray-500 dark:text-gray-400">这是当前版本</div>}
+          {selected && !isLatest && Object.keys(changes).length === 0 && (
+            <div className="text-gray-500 dark:text-gray-400">与当前内容相同</div>
+          )}
+          {selected && !isLatest && Object.keys(changes).length > 0 && (
+            <table className="min-w-full divide-y divide-gray-200 dark:divide-gray-700">
+              <thead>
+                <tr>
+                  <th className={styles.th}>字段</th>
+                  <th className={styles.th}>该版本</th>
+                  <th className={styles.th}>当前</th>
+                </tr>
+              </thead>
+              <tbody className="divide-y divide-gray-200 dark:divide-gray-700">
+                {Object.entries(changes).map(([field, change]) => (
+                  <tr key={field}>
+                    <td className="px-4 py-2 text-gray-500 dark:text-gray-400 whitespace-nowrap">{revisionFields[field] || field}</td>
+                    <td className="px-4 py-2 text-red-600 dark:text-red-400 break-all">
+                      {field === "deletedAt" ? formatTime(change.before) : formatValue(change.before)}
+                    </td>
+                    <td className="px-4 py-2 text-green-600 dark:text-green-400 break-all">
+                      {field === "deletedAt" ? formatTime(change.after) : formatValue(change.after)}
+                    </td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          )}
+        </div>
+      </div>
+    </Modal>
+  );
+};
+
 export const Tools = () => {
   const { store, loading, reload } = useData();
   const { success, error } = useToast();
@@ -305,6 +457,8 @@ export const Tools = () => {
   const [requestLoading, setRequestLoading] = useState(false);
   const [searchString, setSearchString] = useState("");
   const [catelogName, setCatelogName] = useState("");
+  const [tagFilter, setTagFilter] = useState("");
+  const [tagMatch, setTagMatch] = useState("any");
   const [dataSource, setDataSource] = useState<DataType[]>([]);
   const [selectedIds, setSelectedIds] = useState<number[]>([]);
   const [page, setPage] = useState(1);
@@ -316,6 +470,7 @@ export const Tools = () => {
   const [deleteConfirmOpen, setDeleteConfirmOpen] = useState(false);
   const [bulkDeleteConfirmOpen, setBulkDeleteConfirmOpen] = useState(false);
   const [deleteTargetId, setDeleteTargetId] = useState<number | null>(null);
+  const [historyTool, setHistoryTool] = useState<DataType | null>(null);
 
   const sensors = useSensors(
     useSensor(PointerSensor),
@@ -327,7 +482,7 @@ export const Tools = () => {
   const loadData = useCallback(async () => {
     setRequestLoading(true);
     try {
-      const res = await fetchToolsPage(page, pageSize, searchString, catelogName);
+      const res = await fetchToolsPage(page, pageSize, searchString, catelogName, tagFilter, tagMatch);
       setDataSource(res.items || []);
       setTotal(res.total || 0);
     } catch (e) {
@@ -335,7 +490,7 @@ export const Tools = () => {
     } finally {
       setRequestLoading(false);
     }
-  }, [page, pageSize, searchString, catelogName]);
+  }, [page, pageSize, searchString, catelogName, tagFilter, tagMatch]);
 
   useEffect(() => {
     // Debounce for search
@@ -374,7 +529,8 @@ export const Tools = () => {
       url: "",
       logo: "",
       catelog: "",
-      desc: ""
+      desc: "",
+      tagsText: ""
     });
   };
 
@@ -384,7 +540,7 @@ export const Tools = () => {
   };
 
   const openEdit = (record: any) => {
-    setFormData({ ...record });
+    setFormData({ ...record, tagsText: (record.tags || []).join(", ") });
     setShowEdit(true);
   };
 
@@ -397,10 +553,15 @@ export const Tools = () => {
 
     setRequestLoading(true);
     try {
+      // 分类按 id 提交，名称只用于兼容
+      const catelog = (store?.catelogs || []).find((item: any) => item.name === formData.catelog);
+      const { tagsText, ...rest } = formData;
+      const tags = (tagsText || "").split(/[,，]/).map((tag: string) => tag.trim()).filter(Boolean);
+      const payload = { ...rest, catelogId: catelog?.id || 0, tags };
       if (isEdit) {
-        await fetchUpdateTool(formData);
+        await fetchUpdateTool(payload);
       } else {
-        await fetchAddTool(formData);
+        await fetchAddTool(payload);
       }
       loadData();
       reload(); // Update global store if needed (e.g. for counts elsewhere)
@@ -499,6 +660,20 @@ export const Tools = () => {
               placeholder="筛选分类"
             />
           </div>
+          <div className="w-40">
+            <Input
+              placeholder="标签，逗号分隔"
+              value={tagFilter}
+              onChange={e => setTagFilter(e.target.value)}
+            />
+          </div>
+          <div className="w-32">
+            <Select
+              value={tagMatch}
+              options={[{ label: "任意标签", value: "any" }, { label: "全部标签", value: "all" }]}
+              onChange={setTagMatch}
+            />
+          </div>
           <div className="w-48">
             <Input
               placeholder="搜索..."
@@ -581,6 +756,13 @@ export const Tools = () => {
                       </td>
                       <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-500 dark:text-gray-400">
                         {record.catelog}
+                        {(record.tags || []).length > 0 && (
+                          <div className="mt-1 flex flex-wrap gap-1">
+                            {record.tags.map((tag: string) => (
+                              <span key={tag} className="rounded bg-gray-100 px-1.5 py-0.5 text-xs text-gray-600 dark:bg-gray-700 dark:text-gray-300">{tag}</span>
+                            ))}
+                          </div>
+                        )}
                       </td>
                       <td className="px-4 py-3 whitespace-nowrap text-sm text-gray-500 dark:text-gray-400 max-w-xs truncate" title={record.url}>
                         {record.url}
@@ -593,6 +775,9 @@ export const Tools = () => {
                           <button onClick={() => openEdit(record)} className={clsx("van-tools-action-btn", styles.actionBtn, "text-blue-600 hover:text-blue-900 dark:text-blue-400 dark:hover:text-blue-300")}>
                             <PencilSquareIcon className="h-5 w-5" />
                           </button>
+                          <button onClick={() => setHistoryTool(record)} title="历史版本" className={clsx("van-tools-action-btn", styles.actionBtn, "text-gray-600 hover:text-gray-900 dark:text-gray-400 dark:hover:text-gray-300")}>
+                            <ClockIcon className="h-5 w-5" />
+                          </button>
                           <button onClick={() => {
                             setDeleteTargetId(record.id);
                             setDeleteConfirmOpen(true);
@@ -641,6 +826,15 @@ export const Tools = () => {
         categoryOptions={categoryOptions}
       />
 
+      <ToolHistoryModal
+        tool={historyTool}
+        onClose={() => setHistoryTool(null)}
+        onRollback={() => {
+          loadData();
+          reload();
+        }}
+      />
+
       <ConfirmDialog
         isOpen={deleteConfirmOpen}
         onClose={() => setDeleteConfirmOpen(false)}
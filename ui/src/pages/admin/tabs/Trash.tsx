@@ -0,0 +1,142 @@
+import { useCallback, useEffect, useState } from 'react';
+import { ArrowUturnLeftIcon, TrashIcon } from "@heroicons/react/24/outline";
+import { fetchEmptyTrash, fetchPurgeTrashed, fetchRestoreTrashed, fetchTrash } from '../../../utils/api';
+import { Button } from "../../../components/ui/Button";
+import { ConfirmDialog } from "../../../components/ui/ConfirmDialog";
+import { Loading } from "../../../components/Loading";
+import { useToast } from "../../../components/ui/Toast";
+
+const formatTime = (ts?: number) => ts ? new Date(ts * 1000).toLocaleString() : "-";
+
+type TrashType = 'tool' | 'catelog';
+
+// 回收站：删除的工具和分类，可以恢复或彻底删除
+export const Trash = () => {
+  const [items, setItems] = useState<any[]>([]);
+  const [loading, setLoading] = useState(false);
+  const [purgeTarget, setPurgeTarget] = useState<{ type: TrashType, id: number } | null>(null);
+  const [emptyConfirmOpen, setEmptyConfirmOpen] = useState(false);
+  const { success, error } = useToast();
+
+  const reload = useCallback(async () => {
+    setLoading(true);
+    try {
+      const data = await fetchTrash();
+      const list = [
+        ...(data.catelogs || []).map((item: any) => ({ ...item, type: 'catelog' })),
+        ...(data.tools || []).map((item: any) => ({ ...item, type: 'tool' })),
+      ];
+      list.sort((a, b) => b.deletedAt - a.deletedAt);
+      setItems(list);
+    } catch (err: any) {
+      error(err?.message || "获取回收站失败");
+    } finally {
+      setLoading(false);
+    }
+  }, []);
+
+  useEffect(() => {
+    reload();
+  }, [reload]);
+
+  const handleRestore = useCallback(async (type: TrashType, id: number) => {
+    try {
+      await fetchRestoreTrashed(type, id);
+      reload();
+      success("恢复成功");
+    } catch (err: any) {
+      error(err?.message || "恢复失败!");
+    }
+  }, [reload]);
+
+  const handlePurge = useCallback(async (type: TrashType, id: number) => {
+    try {
+      await fetchPurgeTrashed(type, id);
+      reload();
+      success("已彻底删除");
+    } catch (err: any) {
+      error(err?.message || "删除失败!");
+    }
+  }, [reload]);
+
+  const handleEmpty = useCallback(async () => {
+    try {
+      await fetchEmptyTrash();
+      reload();
+      success("已清空回收站");
+    } catch (err: any) {
+      error(err?.message || "清空失败!");
+    }
+  }, [reload]);
+
+  return (
+    <div className="h-full flex flex-col p-4">
+      <div className="mb-4 flex items-center justify-between rounded-lg bg-white p-4 shadow-sm dark:bg-gray-800">
+        <span className="text-sm text-gray-500 dark:text-gray-400">当前共 {items.length} 项，删除分类时其中的工具会一起移入回收站</span>
+        <div className="flex gap-2">
+          <Button variant="outline" onClick={() => reload()}>刷新</Button>
+          <Button variant="outline" onClick={() => setEmptyConfirmOpen(true)} disabled={items.length === 0}>清空回收站</Button>
+        </div>
+      </div>
+
+      <div className="flex-1 overflow-auto rounded-lg bg-white shadow-sm dark:bg-gray-800">
+        {loading ? (
+          <div className="flex h-full items-center justify-center">
+            <Loading />
+          </div>
+        ) : (
+          <table className="min-w-full divide-y divide-gray-200 dark:divide-gray-700">
+            <thead className="bg-gray-50 dark:bg-gray-700/50 sticky top-0 z-10">
+              <tr>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">类型</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">名称</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">分类</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">删除时间</th>
+                <th scope="col" className="px-4 py-3 text-right text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">操作</th>
+              </tr>
+            </thead>
+            <tbody className="divide-y divide-gray-200 bg-white dark:divide-gray-700 dark:bg-gray-800">
+              {items.map((record: any) => (
+                <tr key={`${record.type}-${record.id}`} className="hover:bg-gray-50 dark:hover:bg-gray-800">
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">{record.type === 'tool' ? '工具' : '分类'}</td>
+                  <td className="px-4 py-3 text-sm font-medium text-gray-900 dark:text-white">{record.name}</td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">{record.type === 'tool' ? record.catelog : '-'}</td>
+                  <td className="px-4 py-3 text-xs text-gray-500 dark:text-gray-400">{formatTime(record.deletedAt)}</td>
+                  <td className="px-4 py-3 text-right text-sm font-medium">
+                    <div className="flex justify-end gap-2">
+                      <button onClick={() => handleRestore(record.type, record.id)} title="恢复" className="text-blue-600 hover:text-blue-900 dark:text-blue-400 dark:hover:text-blue-300">
+                        <ArrowUturnLeftIcon className="h-5 w-5" />
+                      </button>
+                      <button onClick={() => setPurgeTarget({ type: record.type, id: record.id })} title="彻底删除" className="text-red-600 hover:text-red-900 dark:text-red-400 dark:hover:text-red-300">
+                        <TrashIcon className="h-5 w-5" />
+                      </button>
+                    </div>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        )}
+      </div>
+
+      <ConfirmDialog
+        isOpen={!!purgeTarget}
+        onClose={() => setPurgeTarget(null)}
+        onConfirm={() => {
+          if (purgeTarget) handlePurge(purgeTarget.type, purgeTarget.id);
+        }}
+        title="彻底删除"
+        description="彻底删除后无法恢复，删除分类时与它一起删除的工具也会被彻底删除，确定吗？"
+        isDestructive
+      />
+      <ConfirmDialog
+        isOpen={emptyConfirmOpen}
+        onClose={() => setEmptyConfirmOpen(false)}
+        onConfirm={handleEmpty}
+        title="清空回收站"
+        description="回收站中的所有内容都会被彻底删除，确定吗？"
+        isDestructive
+      />
+    </div>
+  );
+};
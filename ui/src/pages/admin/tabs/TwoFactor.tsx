@@ -0,0 +1,152 @@
+import { useCallback, useEffect, useState } from "react";
+import {
+  fetchRegenerateRecoveryCodes,
+  fetchTotpDisable,
+  fetchTotpEnable,
+  fetchTotpSetup,
+  fetchTotpStatus,
+} from "../../../utils/api";
+import { Button } from "../../../components/ui/Button";
+import { Input } from "../../../components/ui/Input";
+import toast from "react-hot-toast";
+
+// 两步验证设置：开启、关闭、重新生成恢复码
+export const TwoFactor = () => {
+  const [status, setStatus] = useState<any>({});
+  const [setup, setSetup] = useState<any>(null);
+  const [code, setCode] = useState("");
+  const [password, setPassword] = useState("");
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+  const [requestLoading, setRequestLoading] = useState(false);
+
+  const loadStatus = useCallback(async () => {
+    try {
+      setStatus(await fetchTotpStatus());
+    } catch (err: any) {
+      toast.error(err.message || "获取两步验证状态失败");
+    }
+  }, []);
+
+  useEffect(() => {
+    loadStatus();
+  }, [loadStatus]);
+
+  const run = useCallback(
+    async (action: () => Promise<void>) => {
+      setRequestLoading(true);
+      try {
+        await action();
+      } catch (err: any) {
+        toast.error(err.message || "操作失败!");
+      } finally {
+        setRequestLoading(false);
+      }
+    },
+    []
+  );
+
+  const handleSetup = () =>
+    run(async () => {
+      setSetup(await fetchTotpSetup());
+      setRecoveryCodes([]);
+      setCode("");
+    });
+
+  const handleEnable = () =>
+    run(async () => {
+      const data = await fetchTotpEnable(code);
+      setRecoveryCodes(data.recoveryCodes || []);
+      setSetup(null);
+      setCode("");
+      toast.success("已开启两步验证");
+      await loadStatus();
+    });
+
+  const handleDisable = () =>
+    run(async () => {
+      await fetchTotpDisable(password, code);
+      setPassword("");
+      setCode("");
+      setRecoveryCodes([]);
+      toast.success("已关闭两步验证");
+      await loadStatus();
+    });
+
+  const handleRegenerate = () =>
+    run(async () => {
+      const data = await fetchRegenerateRecoveryCodes(code);
+      setRecoveryCodes(data.recoveryCodes || []);
+      setCode("");
+      toast.success("已重新生成恢复码");
+      await loadStatus();
+    });
+
+  return (
+    <div className="rounded-lg bg-white p-6 shadow-sm dark:bg-gray-800">
+      <h2 className="mb-6 text-lg font-medium text-gray-900 dark:text-white border-b pb-2 border-gray-100 dark:border-gray-700">两步验证</h2>
+      <div className="space-y-4 max-w-lg">
+        <p className="text-sm text-gray-500">
+          {status.enabled
+            ? `已开启，剩余 ${status.recoveryCodesLeft ?? 0} 个恢复码。`
+            : "开启后登录时除密码外还需要输入验证器 App 中的动态验证码。"}
+        </p>
+
+        {recoveryCodes.length > 0 && (
+          <div className="rounded-md bg-yellow-50 p-4 dark:bg-yellow-900/20">
+            <p className="mb-2 text-sm text-yellow-700 dark:text-yellow-400">
+              请妥善保存以下恢复码，每个只能使用一次，关闭此页面后将无法再次查看：
+            </p>
+            <div className="grid grid-cols-2 gap-1 font-mono text-sm text-gray-900 dark:text-white">
+              {recoveryCodes.map((item) => (
+                <span key={item}>{item}</span>
+              ))}
+            </div>
+          </div>
+        )}
+
+        {!status.enabled && !setup && (
+          <Button onClick={handleSetup} isLoading={requestLoading}>开启两步验证</Button>
+        )}
+
+        {!status.enabled && setup && (
+          <div className="space-y-4">
+            <p className="text-sm text-gray-500">使用验证器 App 扫描二维码，或手动输入密钥：</p>
+            <img src={setup.qrcode} alt="TOTP QR Code" className="h-48 w-48" />
+            <p className="break-all font-mono text-sm text-gray-900 dark:text-white">{setup.secret}</p>
+            <Input
+              label="验证码"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              placeholder="请输入验证器中的 6 位数字"
+              autoComplete="one-time-code"
+            />
+            <Button onClick={handleEnable} isLoading={requestLoading}>确认开启</Button>
+          </div>
+        )}
+
+        {status.enabled && (
+          <div className="space-y-4">
+            <Input
+              label="验证码"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              placeholder="验证器中的 6 位数字或恢复码"
+              autoComplete="one-time-code"
+            />
+            <Input
+              label="密码"
+              type="password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              placeholder="关闭两步验证时需要输入当前密码"
+            />
+            <div className="flex gap-2">
+              <Button variant="outline" onClick={handleRegenerate} isLoading={requestLoading}>重新生成恢复码</Button>
+              <Button variant="danger" onClick={handleDisable} isLoading={requestLoading}>关闭两步验证</Button>
+            </div>
+          </div>
+        )}
+      </div>
+    </div>
+  );
+};
@@ -0,0 +1,176 @@
+import { useCallback, useEffect, useState } from 'react';
+import { TrashIcon, PencilSquareIcon } from "@heroicons/react/24/outline";
+import { fetchAddUser, fetchAdminDisableTotp, fetchDeleteUser, fetchUpdateAdminUser, fetchUsers } from '../../../utils/api';
+import { Button } from "../../../components/ui/Button";
+import { Input } from "../../../components/ui/Input";
+import { Select } from "../../../components/ui/Select";
+import { Modal } from "../../../components/ui/Modal";
+import { ConfirmDialog } from "../../../components/ui/ConfirmDialog";
+import { Loading } from "../../../components/Loading";
+import { useToast } from "../../../components/ui/Toast";
+
+const roleOptions = [
+  { value: "owner", label: "所有者（管理用户、设置和 Token）" },
+  { value: "editor", label: "编辑者（管理工具和分类）" },
+  { value: "viewer", label: "访客（只能查看隐藏内容）" },
+];
+
+export const Users = () => {
+  const [users, setUsers] = useState<any[]>([]);
+  const [loading, setLoading] = useState(false);
+  const [showEdit, setShowEdit] = useState(false);
+  const [formData, setFormData] = useState<any>({});
+  const [deleteConfirmOpen, setDeleteConfirmOpen] = useState(false);
+  const [deleteTargetId, setDeleteTargetId] = useState<number | null>(null);
+  const { success, error } = useToast();
+
+  const reload = useCallback(async () => {
+    setLoading(true);
+    try {
+      setUsers(await fetchUsers());
+    } catch (err: any) {
+      error(err?.message || "获取用户失败");
+    } finally {
+      setLoading(false);
+    }
+  }, []);
+
+  useEffect(() => {
+    reload();
+  }, [reload]);
+
+  const handleSave = useCallback(async () => {
+    if (!formData.name) {
+      error("请填写用户名");
+      return;
+    }
+    if (!formData.id && !formData.password) {
+      error("请填写密码");
+      return;
+    }
+    try {
+      if (formData.id) {
+        await fetchUpdateAdminUser(formData);
+      } else {
+        await fetchAddUser(formData);
+      }
+      setShowEdit(false);
+      reload();
+      success("保存成功");
+    } catch (err: any) {
+      error(err?.message || "保存失败!");
+    }
+  }, [formData, reload]);
+
+  const handleDelete = useCallback(async (id: number) => {
+    try {
+      await fetchDeleteUser(id);
+      reload();
+      success("删除成功");
+    } catch (err: any) {
+      error(err?.message || "删除失败!");
+    }
+  }, [reload]);
+
+  const handleDisableTotp = useCallback(async (id: number) => {
+    try {
+      await fetchAdminDisableTotp(id);
+      reload();
+      success("已关闭两步验证");
+    } catch (err: any) {
+      error(err?.message || "操作失败!");
+    }
+  }, [reload]);
+
+  return (
+    <div className="h-full flex flex-col p-4">
+      <div className="mb-4 flex items-center justify-between rounded-lg bg-white p-4 shadow-sm dark:bg-gray-800">
+        <span className="text-sm text-gray-500 dark:text-gray-400">当前共 {users.length} 个用户</span>
+        <div className="flex gap-2">
+          <Button onClick={() => { setFormData({ name: "", password: "", role: "editor" }); setShowEdit(true); }}>添加</Button>
+          <Button variant="outline" onClick={() => reload()}>刷新</Button>
+        </div>
+      </div>
+
+      <div className="flex-1 overflow-auto rounded-lg bg-white shadow-sm dark:bg-gray-800">
+        {loading ? (
+          <div className="flex h-full items-center justify-center">
+            <Loading />
+          </div>
+        ) : (
+          <table className="min-w-full divide-y divide-gray-200 dark:divide-gray-700">
+            <thead className="bg-gray-50 dark:bg-gray-700/50 sticky top-0 z-10">
+              <tr>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">用户名</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">角色</th>
+                <th scope="col" className="px-4 py-3 text-left text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">两步验证</th>
+                <th scope="col" className="px-4 py-3 text-right text-xs font-medium uppercase tracking-wider text-gray-500 dark:text-gray-400">操作</th>
+              </tr>
+            </thead>
+            <tbody className="divide-y divide-gray-200 bg-white dark:divide-gray-700 dark:bg-gray-800">
+              {users.map((record: any) => (
+                <tr key={record.id} className="hover:bg-gray-50 dark:hover:bg-gray-800">
+                  <td className="px-4 py-3 text-sm font-medium text-gray-900 dark:text-white">{record.name}</td>
+                  <td className="px-4 py-3 text-sm text-gray-500 dark:text-gray-400">{record.role}</td>
+                  <td className="px-4 py-3 text-sm text-gray-500 dark:text-gray-400">
+                    {record.totpEnabled ? (
+                      <span className="flex items-center gap-2">
+                        已开启
+                        <button onClick={() => handleDisableTotp(record.id)} className="text-xs text-red-600 hover:text-red-900 dark:text-red-400">关闭</button>
+                      </span>
+                    ) : "未开启"}
+                  </td>
+                  <td className="px-4 py-3 text-right text-sm font-medium">
+                    <div className="flex justify-end gap-3">
+                      <button onClick={() => { setFormData({ ...record, password: "" }); setShowEdit(true); }} className="text-blue-600 hover:text-blue-900 dark:text-blue-400 dark:hover:text-blue-300">
+                        <PencilSquareIcon className="h-5 w-5" />
+                      </button>
+                      <button onClick={() => { setDeleteTargetId(record.id); setDeleteConfirmOpen(true); }} className="text-red-600 hover:text-red-900 dark:text-red-400 dark:hover:text-red-300">
+                        <TrashIcon className="h-5 w-5" />
+                      </button>
+                    </div>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        )}
+      </div>
+
+      <Modal isOpen={showEdit} onClose={() => setShowEdit(false)} title={formData.id ? "编辑用户" : "添加用户"}
+        footer={<><Button variant="secondary" onClick={() => setShowEdit(false)}>取消</Button><Button onClick={handleSave}>确定</Button></>}
+      >
+        <div className="space-y-4">
+          <Input
+            label="用户名"
+            value={formData.name || ''}
+            onChange={e => setFormData({ ...formData, name: e.target.value })}
+          />
+          <Input
+            label={formData.id ? "密码（留空表示不修改）" : "密码"}
+            type="password"
+            value={formData.password || ''}
+            onChange={e => setFormData({ ...formData, password: e.target.value })}
+          />
+          <Select
+            label="角色"
+            value={formData.role}
+            onChange={role => setFormData({ ...formData, role })}
+            options={roleOptions}
+          />
+        </div>
+      </Modal>
+
+      <ConfirmDialog
+        isOpen={deleteConfirmOpen}
+        onClose={() => setDeleteConfirmOpen(false)}
+        onConfirm={() => {
+          if (deleteTargetId) handleDelete(deleteTargetId);
+        }}
+        title="确认删除"
+        description="确定要删除这个用户吗？该用户的所有会话会被立即注销。"
+        isDestructive
+      />
+    </div>
+  );
+};
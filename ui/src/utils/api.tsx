@@ -1,5 +1,9 @@
 import axios from "axios";
 import { getJumpTarget, initServerJumpTargetConfig } from "./setting";
+import { basePath, withBase } from "./base";
+
+// 接口地址都以 /api 开头，部署在子路径下时统一加上前缀
+axios.defaults.baseURL = basePath;
 
 axios.interceptors.request.use(
     (config) => {
@@ -17,7 +21,10 @@ axios.interceptors.request.use(
 axios.interceptors.response.use(
     (response) => {
         if (response.data && response.data.success === false) {
-            return Promise.reject(new Error(response.data.errorMessage || "Unknown error"));
+            // 保留原始响应，调用方可以读取 data 中的附加信息（例如 totpRequired）
+            const error: any = new Error(response.data.errorMessage || "Unknown error");
+            error.response = response;
+            return Promise.reject(error);
         }
         return response;
     },
@@ -25,7 +32,7 @@ axios.interceptors.response.use(
         if (error.response?.status === 401) {
             // Clear token and redirect to login
             window.localStorage.removeItem("_token");
-            window.location.href = "/login";
+            window.location.href = withBase("/login");
         }
         return Promise.reject(error);
     }
@@ -78,20 +85,41 @@ export const FetchList = async () => {
     })
 
     data.catelogs = catelogs;
+    // 每个分类及其所有子分类的名称，选中上级分类时同时显示子分类中的工具
+    const descendants = {};
+    const collect = (node) => {
+        const names = [node.name];
+        (node.children || []).forEach(child => names.push(...collect(child)));
+        descendants[node.name] = names;
+        return names;
+    };
+    (data.catelogTree || []).forEach(collect);
+    data.catelogDescendants = descendants;
     return data;
 };
 
 
 
 
-export const login = async (username: string, password: string) => {
+export const login = async (username: string, password: string, code?: string) => {
     const { data } = await axios.post("/api/login", {
         name: username,
         password,
+        code,
     });
     return data;
 };
 
+export const fetchOIDCConfig = async () => {
+    const { data } = await axios.get("/api/oidc/config");
+    return data?.data || {};
+};
+
+export const logout = async () => {
+    const { data } = await axios.get("/api/logout");
+    return data;
+};
+
 export const fetchAdminData: () => Promise<any> = async () => {
     const { data } = await axios.get("/api/admin/all");
     return data?.data || {};
@@ -117,6 +145,19 @@ export const fetchUpdateTool = async (payload: any) => {
     const { data } = await axios.put(`/api/admin/tool/${payload.id}`, payload);
     return data?.data || {};
 };
+// 工具的历史版本
+export const fetchToolRevisions = async (id: number) => {
+    const { data } = await axios.get(`/api/admin/tool/${id}/revisions`);
+    return data?.data || [];
+};
+export const fetchToolRevisionDiff = async (id: number, from: number, to: number) => {
+    const { data } = await axios.get(`/api/admin/tool/${id}/revisions/diff`, { params: { from, to } });
+    return data?.data || {};
+};
+export const fetchRollbackTool = async (id: number, revision: number) => {
+    const { data } = await axios.post(`/api/admin/tool/${id}/revisions/${revision}/restore`);
+    return data?.data || {};
+};
 export const fetchAddTool = async (payload: any) => {
     const { data } = await axios.post(`/api/admin/tool`, payload);
     return data?.data || {};
@@ -130,8 +171,14 @@ export const fetchUpdateCateLog = async (payload: any) => {
     const { data } = await axios.put(`/api/admin/catelog/${payload.id}`, payload);
     return data?.data || {};
 };
-export const fetchDeleteCatelog = async (id: number) => {
-    const { data } = await axios.delete(`/api/admin/catelog/${id}`);
+// parentId 为 0 表示移动为顶级分类
+export const fetchMoveCatelog = async (id: number, parentId: number) => {
+    const { data } = await axios.put(`/api/admin/catelog/${id}/move`, { parentId });
+    return data?.data || {};
+};
+// 分类下还有工具时需要指定 mode：move 移动到 target 分类，cascade 一起移入回收站
+export const fetchDeleteCatelog = async (id: number, mode?: "move" | "cascade", target?: number) => {
+    const { data } = await axios.delete(`/api/admin/catelog/${id}`, { params: { mode, target } });
     return data?.data || {};
 };
 
@@ -140,11 +187,121 @@ export const fetchUpdateSetting = async (payload: any) => {
     return data?.data || {};
 };
 
+export const fetchRotateGuestSecret = async () => {
+    const { data } = await axios.post(`/api/admin/guest/rotate`);
+    return data?.data || {};
+};
+
+export const fetchGuestLinks = async () => {
+    const { data } = await axios.get(`/api/admin/guestLinks`);
+    return data?.data || [];
+};
+export const fetchAddGuestLink = async (payload: any) => {
+    const { data } = await axios.post(`/api/admin/guestLinks`, payload);
+    return data?.data || {};
+};
+export const fetchDeleteGuestLink = async (id: string) => {
+    const { data } = await axios.delete(`/api/admin/guestLinks/${id}`);
+    return data?.data || {};
+};
+
+export const fetchAudit = async (page: number, size: number, filters: Record<string, string> = {}) => {
+    const params = new URLSearchParams();
+    params.append('page', page.toString());
+    params.append('size', size.toString());
+    Object.entries(filters).forEach(([key, value]) => {
+        if (value) params.append(key, value);
+    });
+    const { data } = await axios.get(`/api/admin/audit?${params.toString()}`);
+    return data?.data || {};
+};
+
+// 回收站
+export const fetchTrash = async () => {
+    const { data } = await axios.get(`/api/admin/trash`);
+    return data?.data || {};
+};
+export const fetchRestoreTrashed = async (type: 'tool' | 'catelog', id: number) => {
+    const { data } = await axios.post(`/api/admin/trash/${type}/${id}/restore`);
+    return data?.data || {};
+};
+export const fetchPurgeTrashed = async (type: 'tool' | 'catelog', id: number) => {
+    const { data } = await axios.delete(`/api/admin/trash/${type}/${id}`);
+    return data?.data || {};
+};
+export const fetchEmptyTrash = async () => {
+    const { data } = await axios.delete(`/api/admin/trash`);
+    return data?.data || {};
+};
+
+// 数据库备份和恢复
+export const fetchBackup = async () => {
+    const { data } = await axios.get(`/api/admin/backup`, { responseType: 'blob' });
+    return data as Blob;
+};
+export const fetchBackupStatus = async () => {
+    const { data } = await axios.get(`/api/admin/backup/status`);
+    return data?.data || {};
+};
+export const fetchRunBackup = async () => {
+    const { data } = await axios.post(`/api/admin/backup/run`);
+    return data?.data || {};
+};
+export const fetchRestore = async (file: File) => {
+    const form = new FormData();
+    form.append('file', file);
+    const { data } = await axios.post(`/api/admin/restore`, form);
+    return data?.data || {};
+};
+
 export const fetchUpdateUser = async (payload: any) => {
     const { data } = await axios.put(`/api/admin/user`, payload);
     return data?.data || {};
 };
 
+export const fetchUsers = async () => {
+    const { data } = await axios.get(`/api/admin/users`);
+    return data?.data || [];
+};
+export const fetchAddUser = async (payload: any) => {
+    const { data } = await axios.post(`/api/admin/users`, payload);
+    return data?.data || {};
+};
+export const fetchUpdateAdminUser = async (payload: any) => {
+    const { data } = await axios.put(`/api/admin/users/${payload.id}`, payload);
+    return data?.data || {};
+};
+export const fetchDeleteUser = async (id: number) => {
+    const { data } = await axios.delete(`/api/admin/users/${id}`);
+    return data?.data || {};
+};
+export const fetchAdminDisableTotp = async (id: number) => {
+    const { data } = await axios.delete(`/api/admin/users/${id}/totp`);
+    return data?.data || {};
+};
+
+// 两步验证
+export const fetchTotpStatus = async () => {
+    const { data } = await axios.get(`/api/admin/totp`);
+    return data?.data || {};
+};
+export const fetchTotpSetup = async () => {
+    const { data } = await axios.post(`/api/admin/totp/setup`);
+    return data?.data || {};
+};
+export const fetchTotpEnable = async (code: string) => {
+    const { data } = await axios.post(`/api/admin/totp/enable`, { code });
+    return data?.data || {};
+};
+export const fetchTotpDisable = async (password: string, code: string) => {
+    const { data } = await axios.post(`/api/admin/totp/disable`, { password, code });
+    return data?.data || {};
+};
+export const fetchRegenerateRecoveryCodes = async (code: string) => {
+    const { data } = await axios.post(`/api/admin/totp/recoveryCodes`, { code });
+    return data?.data || {};
+};
+
 export const fetchAddApiToken = async (payload: any) => {
     const { data } = await axios.post(`/api/admin/apiToken`, payload);
     return data?.data || {};
@@ -163,13 +320,36 @@ export const fetchUpdateCatelogsSort = async (updates: { id: number; sort: numbe
     return data?.data || {};
 };
 
-export const fetchToolsPage = async (page: number, size: number, q?: string, catelog?: string) => {
+// tags 为逗号分隔的标签名称，match 为 all 时要求包含全部标签
+export const fetchToolsPage = async (page: number, size: number, q?: string, catelog?: string, tags?: string, match?: string) => {
     const params = new URLSearchParams();
     params.append('page', page.toString());
     params.append('size', size.toString());
     if (q) params.append('q', q);
     if (catelog) params.append('catelog', catelog);
+    if (tags) params.append('tags', tags.replace(/，/g, ','));
+    if (tags && match === 'all') params.append('match', 'all');
 
     const { data } = await axios.get(`/api/admin/tools?${params.toString()}`);
     return data?.data || {};
-};
\ No newline at end of file
+};
+
+export const fetchTags = async () => {
+    const { data } = await axios.get(`/api/admin/tags`);
+    return data?.data || [];
+};
+
+export const fetchAddTag = async (name: string) => {
+    const { data } = await axios.post(`/api/admin/tag`, { name });
+    return data?.data || {};
+};
+
+export const fetchUpdateTag = async (id: number, name: string) => {
+    const { data } = await axios.put(`/api/admin/tag/${id}`, { name });
+    return data?.data || {};
+};
+
+export const fetchDeleteTag = async (id: number) => {
+    const { data } = await axios.delete(`/api/admin/tag/${id}`);
+    return data?.data || {};
+};
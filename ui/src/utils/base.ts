@@ -0,0 +1,4 @@
+// 服务端在首页中注入的路径前缀，部署在子路径下时形如 /nav，根路径部署时为空
+export const basePath: string = (window as any).__NAV_BASE__ || "";
+
+export const withBase = (path: string) => basePath + path;
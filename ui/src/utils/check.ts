@@ -1,10 +1,12 @@
+import { withBase } from './base';
+
 export const isLogin = () => {
   return localStorage.getItem('_token') ? true : false
 }
 
 export const getLogoUrl = (url: string) => {
   if (url.startsWith('http')) {
-    return `/api/img?url=${url}`
+    return withBase(`/api/img?url=${url}`)
   } else {
     return url;
   }
@@ -4,6 +4,8 @@ import path from 'path'
 
 // https://vitejs.dev/config/
 export default defineConfig({
+    // 使用相对路径引用静态资源，配合服务端注入的 <base> 支持部署在子路径下
+    base: './',
     plugins: [react()],
     resolve: {
         alias: {
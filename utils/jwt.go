@@ -3,9 +3,10 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"sync"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 	"github.com/mereith/nav/logger"
 	"github.com/mereith/nav/types"
@@ -20,49 +21,71 @@ func RandomJWTKey() string {
 	return hex.EncodeToString(bytes)
 }
 
-// JTW 密钥
-var jwtSecret = []byte("boy_next_door")
+var (
+	// JTW 密钥，启动时由 service.InitJWTSecret 从数据库或配置中加载
+	jwtSecret []byte
+	// 轮换后的旧密钥，在宽限期内仍可用于校验
+	jwtPreviousSecret    []byte
+	jwtPreviousExpiresAt time.Time
+	// 轮换密钥和恢复数据库时会在请求处理过程中替换密钥
+	jwtSecretMutex sync.RWMutex
+)
 
-func init() {
-	jwtSecret = []byte(RandomJWTKey())
-	logger.LogInfo("jwtSecret Setted: %s", jwtSecret)
+// 设置当前使用的 JWT 密钥
+func SetJWTSecret(secret string) {
+	jwtSecretMutex.Lock()
+	jwtSecret = []byte(secret)
+	jwtSecretMutex.Unlock()
 }
 
-// 签名一个 JTW
-func SignJWT(user types.User) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"name": user.Name,
-		"id":   user.Id,
-		"exp":  time.Now().Add(time.Hour * 24 * 30).Unix(),
-	})
-	tokenString, err := token.SignedString([]byte(jwtSecret))
-	return tokenString, err
+// 设置轮换前的旧密钥及其失效时间，secret 为空表示没有旧密钥
+func SetPreviousJWTSecret(secret string, expiresAt time.Time) {
+	jwtSecretMutex.Lock()
+	defer jwtSecretMutex.Unlock()
+	if secret == "" {
+		jwtPreviousSecret = nil
+		jwtPreviousExpiresAt = time.Time{}
+		return
+	}
+	jwtPreviousSecret = []byte(secret)
+	jwtPreviousExpiresAt = expiresAt
 }
 
-// 签名一个 JTW
-func SignJWTForAPI(tokenName string, tokenId int) (string, error) {
+// 签名一个 JTW，jti 对应 nav_session 中的会话 id
+func SignJWT(user types.User, jti string, expiresAt time.Time) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"name": tokenName,
-		"id":   tokenId,
-		"exp":  time.Now().Add(time.Hour * 24 * 365 * 100).Unix(),
+		"name": user.Name,
+		"id":   user.Id,
+		"jti":  jti,
+		"iat":  time.Now().Unix(),
+		"exp":  expiresAt.Unix(),
 	})
-	tokenString, err := token.SignedString([]byte(jwtSecret))
-	return tokenString, err
+	jwtSecretMutex.RLock()
+	secret := jwtSecret
+	jwtSecretMutex.RUnlock()
+	return token.SignedString(secret)
 }
 
-// 解密一个 JTW
+// 解密一个 JTW，当前密钥校验失败时尝试宽限期内的旧密钥
 func ParseJWT(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (i interface{}, e error) {
-		return jwtSecret, nil
-	})
+	jwtSecretMutex.RLock()
+	secret, previous, previousExpiresAt := jwtSecret, jwtPreviousSecret, jwtPreviousExpiresAt
+	jwtSecretMutex.RUnlock()
+	token, err := parseJWTWithSecret(tokenString, secret)
+	if err == nil || previous == nil || time.Now().After(previousExpiresAt) {
+		return token, err
+	}
+	if vErr, ok := err.(*jwt.ValidationError); ok && vErr.Errors&jwt.ValidationErrorSignatureInvalid != 0 {
+		return parseJWTWithSecret(tokenString, previous)
+	}
 	return token, err
 }
 
-func IsLogin(c *gin.Context) bool {
-	rawToken := c.Request.Header.Get("Authorization")
-	if rawToken == "" {
-		return false
-	}
-	token, err := ParseJWT(rawToken)
-	return err == nil && token.Valid
+func parseJWTWithSecret(tokenString string, secret []byte) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (i interface{}, e error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return secret, nil
+	})
 }
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mereith/nav/types"
+)
+
+func TestParseJWTPreviousSecret(t *testing.T) {
+	user := types.User{Id: 1, Name: "admin"}
+	SetJWTSecret("old")
+	SetPreviousJWTSecret("", time.Time{})
+	token, err := SignJWT(user, "jti", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		previous string
+		expires  time.Time
+		want     bool
+	}{
+		{"宽限期内旧密钥有效", "old", time.Now().Add(time.Minute), true},
+		{"宽限期结束后旧密钥失效", "old", time.Now().Add(-time.Minute), false},
+		{"没有旧密钥", "", time.Time{}, false},
+		{"旧密钥不匹配", "other", time.Now().Add(time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetJWTSecret("new")
+			SetPreviousJWTSecret(tt.previous, tt.expires)
+			_, err := ParseJWT(token)
+			if got := err == nil; got != tt.want {
+				t.Errorf("ParseJWT() err = %v, want valid = %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// 轮换密钥的同时签发和校验 token，配合 -race 检查数据竞争
+func TestJWTSecretConcurrentRotation(t *testing.T) {
+	user := types.User{Id: 1, Name: "admin"}
+	SetJWTSecret(RandomJWTKey())
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				token, err := SignJWT(user, "jti", time.Now().Add(time.Hour))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ParseJWT(token)
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		previous := RandomJWTKey()
+		SetJWTSecret(RandomJWTKey())
+		SetPreviousJWTSecret(previous, time.Now().Add(time.Minute))
+	}
+	wg.Wait()
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 用于用户名不存在时做一次等价的校验，避免通过响应时间区分用户是否存在
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("nav-dummy-password"), bcrypt.DefaultCost)
+
+// 使用 bcrypt 生成密码哈希
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// 判断存储的密码是否已经是 bcrypt 哈希
+func IsPasswordHashed(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") || strings.HasPrefix(stored, "$2b$") || strings.HasPrefix(stored, "$2y$")
+}
+
+// 校验密码，兼容尚未迁移的明文密码（使用常量时间比较）
+func VerifyPassword(stored string, password string) bool {
+	if IsPasswordHashed(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+}
+
+// 用户不存在时调用，消耗与真实校验相同的时间
+func VerifyDummyPassword(password string) {
+	bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
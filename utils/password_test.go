@@ -0,0 +1,51 @@
+package utils
+
+import "testing"
+
+func TestVerifyPassword(t *testing.T) {
+	hash, err := HashPassword("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		stored   string
+		password string
+		want     bool
+	}{
+		{"哈希正确", hash, "s3cret", true},
+		{"哈希错误", hash, "S3cret", false},
+		{"哈希空密码", hash, "", false},
+		{"明文正确", "s3cret", "s3cret", true},
+		{"明文错误", "s3cret", "s3cre", false},
+		{"明文为空", "", "", true},
+		{"明文为空时不接受非空密码", "", "x", false},
+		{"不能用哈希本身当密码", hash, hash, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPassword(tt.stored, tt.password); got != tt.want {
+				t.Errorf("VerifyPassword(%q, %q) = %v, want %v", tt.stored, tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPasswordHashed(t *testing.T) {
+	tests := []struct {
+		stored string
+		want   bool
+	}{
+		{"$2a$10$abcdefghijklmnopqrstuv", true},
+		{"$2b$10$abcdefghijklmnopqrstuv", true},
+		{"$2y$10$abcdefghijklmnopqrstuv", true},
+		{"admin", false},
+		{"", false},
+		{"$1$md5crypt", false},
+	}
+	for _, tt := range tests {
+		if got := IsPasswordHashed(tt.stored); got != tt.want {
+			t.Errorf("IsPasswordHashed(%q) = %v, want %v", tt.stored, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+// 不带声调的拼音，多音字取最常用的读音
+var pinyinArgs = pinyin.NewArgs()
+
+// 单个汉字的拼音，不是汉字时返回空
+func runePinyin(r rune) string {
+	if !unicode.Is(unicode.Han, r) {
+		return ""
+	}
+	if pys := pinyin.SinglePinyin(r, pinyinArgs); len(pys) > 0 {
+		return pys[0]
+	}
+	return ""
+}
+
+// 文本的全拼和拼音首字母，用于建立搜索索引。汉字转为拼音，其余字符转为小写保留，去掉空白，
+// 例如「工号 System」为 gonghaosystem 和 ghsystem。不包含汉字时返回空
+func Pinyin(text string) (full string, initials string) {
+	var f, i strings.Builder
+	hasHan := false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if py := runePinyin(r); py != "" {
+			hasHan = true
+			f.WriteString(py)
+			i.WriteString(py[:1])
+			continue
+		}
+		r = unicode.ToLower(r)
+		f.WriteRune(r)
+		i.WriteRune(r)
+	}
+	if !hasHan {
+		return "", ""
+	}
+	return f.String(), i.String()
+}
+
+// 在 text 中查找能与 term 对应的一段连续文字，返回第一段的位置（按 rune 计算的 [start, end)）。
+// 每个汉字可以用它本身、全拼或首字母匹配，term 末尾的汉字还可以只写拼音的开头，其他字符不区分大小写原样匹配，
+// 因此「gh」「gonghao」「工号xt」「gonghx」「gonghaoxi」都能匹配「工号系统」。匹配过程中可以跳过 text 中的空白
+func PinyinMatch(text string, term string) (int, int, bool) {
+	runes := []rune(text)
+	needle := []rune(strings.ToLower(term))
+	if len(needle) == 0 {
+		return 0, 0, false
+	}
+	pys := make([]string, len(runes))
+	for i, r := range runes {
+		pys[i] = runePinyin(r)
+	}
+	// 记录匹配失败的状态，避免重复搜索
+	failed := make(map[[2]int]bool)
+	var match func(i, j int) (int, bool)
+	match = func(i, j int) (int, bool) {
+		if j == len(needle) {
+			return i, true
+		}
+		if i == len(runes) || failed[[2]int{i, j}] {
+			return 0, false
+		}
+		r := runes[i]
+		if unicode.ToLower(r) == needle[j] {
+			if end, ok := match(i+1, j+1); ok {
+				return end, true
+			}
+		}
+		if py := pys[i]; py != "" {
+			// 优先尝试更长的拼音。拼音的一部分只能是首字母，或者在 term 的末尾
+			for k := len(py); k >= 1; k-- {
+				if k != len(py) && k != 1 && j+k != len(needle) {
+					continue
+				}
+				if j+k <= len(needle) && string(needle[j:j+k]) == py[:k] {
+					if end, ok := match(i+1, j+k); ok {
+						return end, true
+					}
+				}
+			}
+		}
+		if j > 0 && unicode.IsSpace(r) {
+			if end, ok := match(i+1, j); ok {
+				return end, true
+			}
+		}
+		failed[[2]int{i, j}] = true
+		return 0, false
+	}
+	for start := range runes {
+		if unicode.IsSpace(runes[start]) {
+			continue
+		}
+		if end, ok := match(start, 0); ok {
+			return start, end, true
+		}
+	}
+	return 0, 0, false
+}
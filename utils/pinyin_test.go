@@ -0,0 +1,60 @@
+package utils
+
+import "testing"
+
+func TestPinyin(t *testing.T) {
+	tests := []struct {
+		text     string
+		full     string
+		initials string
+	}{
+		{"工号系统", "gonghaoxitong", "ghxt"},
+		{"工号 System", "gonghaosystem", "ghsystem"},
+		{"GitHub", "", ""},
+		{"", "", ""},
+		{"Van 导航 2", "vandaohang2", "vandh2"},
+	}
+	for _, tt := range tests {
+		full, initials := Pinyin(tt.text)
+		if full != tt.full || initials != tt.initials {
+			t.Errorf("Pinyin(%q) = %q, %q, want %q, %q", tt.text, full, initials, tt.full, tt.initials)
+		}
+	}
+}
+
+func TestPinyinMatch(t *testing.T) {
+	tests := []struct {
+		text  string
+		term  string
+		start int
+		end   int
+		ok    bool
+	}{
+		{"工号系统", "gh", 0, 2, true},
+		{"工号系统", "gonghao", 0, 2, true},
+		{"工号系统", "GongHao", 0, 2, true},
+		{"工号系统", "ghxt", 0, 4, true},
+		{"工号系统", "gonghaoxitong", 0, 4, true},
+		{"工号系统", "工号xt", 0, 4, true},
+		{"工号系统", "gonghx", 0, 3, true},
+		{"工号系统", "gonghaoxi", 0, 3, true},
+		{"工号系统", "xt", 2, 4, true},
+		{"工号系统", "系统", 2, 4, true},
+		// 拼音的一部分只能是首字母或者在末尾
+		{"工号系统", "gonhao", 0, 0, false},
+		{"工号系统", "hx", 1, 3, true},
+		{"工号系统", "gx", 0, 0, false},
+		{"员工工号", "gonghao", 2, 4, true},
+		{"工号 System", "ghs", 0, 4, true},
+		{"工号 System", "system", 3, 9, true},
+		{"GitHub", "hub", 3, 6, true},
+		{"GitHub", "gh", 0, 0, false},
+		{"工号系统", "", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, end, ok := PinyinMatch(tt.text, tt.term)
+		if ok != tt.ok || (ok && (start != tt.start || end != tt.end)) {
+			t.Errorf("PinyinMatch(%q, %q) = %d, %d, %v, want %d, %d, %v", tt.text, tt.term, start, end, ok, tt.start, tt.end, tt.ok)
+		}
+	}
+}
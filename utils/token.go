@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// API Token 的固定前缀，方便在日志或代码仓库中识别
+const ApiTokenPrefix = "nav_"
+
+// 列表中展示的 token 前缀长度（含 nav_）
+const apiTokenDisplayLength = len(ApiTokenPrefix) + 8
+
+// 生成一个随机的 API Token，返回完整 token、用于展示的前缀以及需要入库的哈希
+func GenerateApiToken() (token string, prefix string, hash string, err error) {
+	bytes := make([]byte, 32)
+	if _, err = rand.Read(bytes); err != nil {
+		return "", "", "", err
+	}
+	token = ApiTokenPrefix + hex.EncodeToString(bytes)
+	return token, token[:apiTokenDisplayLength], HashApiToken(token), nil
+}
+
+// API Token 只以 SHA-256 哈希形式保存
+func HashApiToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// 旧版 JWT 形式的 token 没有可识别的前缀，只展示末尾几位
+func LegacyApiTokenPrefix(token string) string {
+	if len(token) <= 6 {
+		return "…"
+	}
+	return "…" + token[len(token)-6:]
+}
+
+// 生成访客邀请链接的 token，返回完整 token、用于展示的前缀以及需要入库的哈希
+func GenerateGuestLinkToken() (token string, prefix string, hash string, err error) {
+	bytes := make([]byte, 16)
+	if _, err = rand.Read(bytes); err != nil {
+		return "", "", "", err
+	}
+	token = hex.EncodeToString(bytes)
+	return token, token[:6], HashApiToken(token), nil
+}
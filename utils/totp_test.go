@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA-1 测试密钥 "12345678901234567890"
+const rfcTotpSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestVerifyTotpRFCVectors(t *testing.T) {
+	// RFC 中的 8 位验证码取后 6 位
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		step, ok := VerifyTotp(rfcTotpSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("%d: 验证码 %s 没有通过校验", tt.unix, tt.code)
+			continue
+		}
+		if step != tt.unix/totpPeriod {
+			t.Errorf("%d: step = %d, want %d", tt.unix, step, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestVerifyTotpStep(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfcTotpSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	current := now.Unix() / totpPeriod
+	tests := []struct {
+		name string
+		code string
+		want bool
+		step int64
+	}{
+		{"当前时间步", totpCode(key, current), true, current},
+		{"前一个时间步", totpCode(key, current-1), true, current - 1},
+		{"后一个时间步", totpCode(key, current+1), true, current + 1},
+		{"超出允许的偏差", totpCode(key, current-2), false, 0},
+		{"超出允许的偏差（未来）", totpCode(key, current+2), false, 0},
+		{"前后空白", " " + totpCode(key, current) + " ", true, current},
+		{"位数不对", totpCode(key, current)[:5], false, 0},
+		{"空验证码", "", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := VerifyTotp(rfcTotpSecret, tt.code, now)
+			if ok != tt.want || step != tt.step {
+				t.Errorf("VerifyTotp(%q) = %d, %v, want %d, %v", tt.code, step, ok, tt.step, tt.want)
+			}
+		})
+	}
+	if _, ok := VerifyTotp("not base32!", totpCode(key, current), now); ok {
+		t.Error("无效的密钥通过了校验")
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("恢复码格式不对: %q", code)
+		}
+		if seen[code] {
+			t.Errorf("恢复码重复: %q", code)
+		}
+		seen[code] = true
+	}
+	// 输入时大小写、连字符和空格不影响匹配
+	code := codes[0]
+	for _, input := range []string{strings.ToUpper(code), code[:5] + code[6:], " " + code[:5] + " " + code[6:]} {
+		if HashRecoveryCode(input) != HashRecoveryCode(code) {
+			t.Errorf("HashRecoveryCode(%q) 与 %q 不一致", input, code)
+		}
+	}
+}
@@ -88,7 +88,7 @@ func PathExistsOrCreate(path string) {
 	if err == nil {
 		return
 	}
-	os.Mkdir(path, os.ModePerm)
+	os.MkdirAll(path, os.ModePerm)
 }
 
 func GenerateId() int {
@@ -97,30 +97,136 @@ func GenerateId() int {
 	return id
 }
 
+// 过滤掉隐藏的工具，以及隐藏的分类（包括上级分类被隐藏的子分类）下的工具
 func FilterHideTools(tools []types.Tool, cates []types.Catelog) []types.Tool {
 	result := make([]types.Tool, 0)
-	var hideCates []string
-	// 提取出需要隐藏的分类
-	for _, cate := range cates {
-		if cate.Hide {
-			hideCates = append(hideCates, cate.Name)
-		}
-	}
+	hideCates := hiddenCates(cates)
 	// 过滤工具
 	for _, tool := range tools {
-		if !tool.Hide && !In(tool.Catelog, hideCates) {
+		if !tool.Hide && !hideCates[tool.CatelogId] {
 			result = append(result, tool)
 		}
 	}
 	return result
 }
 
+// 过滤掉隐藏的分类，上级分类隐藏时子分类也一起隐藏
 func FilterHideCates(cates []types.Catelog) []types.Catelog {
 	result := make([]types.Catelog, 0)
+	hideCates := hiddenCates(cates)
 	for _, cate := range cates {
-		if !cate.Hide {
+		if !hideCates[cate.Id] {
 			result = append(result, cate)
 		}
 	}
 	return result
 }
+
+// 需要隐藏的分类：自己或任意一级上级分类设置了隐藏
+func hiddenCates(cates []types.Catelog) map[int]bool {
+	byId := make(map[int]types.Catelog)
+	for _, cate := range cates {
+		byId[cate.Id] = cate
+	}
+	hidden := make(map[int]bool)
+	for _, cate := range cates {
+		// 限制层数，避免数据中的环导致死循环
+		for current, depth := cate, 0; depth <= len(cates); depth++ {
+			if current.Hide {
+				hidden[cate.Id] = true
+				break
+			}
+			parent, ok := byId[current.ParentId]
+			if !ok {
+				break
+			}
+			current = parent
+		}
+	}
+	return hidden
+}
+
+// 按树的深度优先顺序排列分类：上级分类后面紧跟它的子分类，同级分类保持原来的顺序。
+// 上级分类不在列表中的分类作为顶级分类
+func SortCatelogTree(cates []types.Catelog) []types.Catelog {
+	result := make([]types.Catelog, 0, len(cates))
+	var walk func(nodes []types.CatelogNode)
+	walk = func(nodes []types.CatelogNode) {
+		for _, node := range nodes {
+			result = append(result, node.Catelog)
+			walk(node.Children)
+		}
+	}
+	walk(BuildCatelogTree(cates))
+	return result
+}
+
+// 把分类列表组装成树，同级分类保持原来的顺序。上级分类不在列表中的分类作为顶级分类
+func BuildCatelogTree(cates []types.Catelog) []types.CatelogNode {
+	exists := make(map[int]bool)
+	for _, cate := range cates {
+		exists[cate.Id] = true
+	}
+	children := make(map[int][]types.Catelog)
+	for _, cate := range cates {
+		parentId := cate.ParentId
+		if !exists[parentId] || parentId == cate.Id {
+			parentId = 0
+		}
+		children[parentId] = append(children[parentId], cate)
+	}
+	visited := make(map[int]bool)
+	var build func(parentId int) []types.CatelogNode
+	build = func(parentId int) []types.CatelogNode {
+		nodes := make([]types.CatelogNode, 0)
+		for _, cate := range children[parentId] {
+			if visited[cate.Id] {
+				continue
+			}
+			visited[cate.Id] = true
+			nodes = append(nodes, types.CatelogNode{Catelog: cate, Children: build(cate.Id)})
+		}
+		return nodes
+	}
+	return build(0)
+}
+
+// 按标签过滤工具，matchAll 为 true 时要求包含全部标签，否则包含任意一个即可
+func FilterTags(tools []types.Tool, tags []string, matchAll bool) []types.Tool {
+	result := make([]types.Tool, 0)
+	for _, tool := range tools {
+		matched := 0
+		for _, tag := range tags {
+			if In(tag, tool.Tags) {
+				matched++
+			}
+		}
+		if (matchAll && matched == len(tags)) || (!matchAll && matched > 0) {
+			result = append(result, tool)
+		}
+	}
+	return result
+}
+
+// 只保留指定分类及其子分类和其下的工具，用于限制了分类的访客链接
+func FilterAllowedCates(tools []types.Tool, cates []types.Catelog, allowedIds []int) ([]types.Tool, []types.Catelog) {
+	resultCates := make([]types.Catelog, 0)
+	allowed := make(map[int]bool)
+	for _, id := range allowedIds {
+		allowed[id] = true
+	}
+	// 按树的顺序处理，上级分类总是先于子分类
+	for _, cate := range SortCatelogTree(cates) {
+		if allowed[cate.Id] || allowed[cate.ParentId] {
+			resultCates = append(resultCates, cate)
+			allowed[cate.Id] = true
+		}
+	}
+	resultTools := make([]types.Tool, 0)
+	for _, tool := range tools {
+		if allowed[tool.CatelogId] {
+			resultTools = append(resultTools, tool)
+		}
+	}
+	return resultTools, resultCates
+}